@@ -0,0 +1,298 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/sort"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultInterval    = time.Minute
+	DefaultGracePeriod = 5 * time.Minute
+	// max number of overdue schedules reported per scheduler
+	DefaultMax     = 1000
+	WebhookTimeout = 5 * time.Second
+)
+
+// Overdue is a schedule of the cold DB with an epoch older than the grace period and no history entry
+type Overdue struct {
+	Scheduler string `json:"scheduler"`
+	ID        string `json:"id"`
+	Epoch     int64  `json:"epoch"`
+	// number of seconds since the epoch at the time of the check
+	Late int64 `json:"late-seconds"`
+}
+
+func (o Overdue) key() string {
+	return fmt.Sprintf("%v|%v|%v", o.Scheduler, o.ID, o.Epoch)
+}
+
+// Report is the result of a check, the schedules are limited to Max per scheduler, the totals count them up to Max+1
+// (more than Max overdue schedules)
+type Report struct {
+	Checked time.Time `json:"checked"`
+	Total   int       `json:"total"`
+	// by scheduler name
+	Totals    map[string]int `json:"totals"`
+	Schedules []Overdue      `json:"schedules"`
+}
+
+// Filter returns the report of the schedulers accepted by the function
+func (r Report) Filter(accept func(schedulerName string) bool) Report {
+	result := Report{
+		Checked:   r.Checked,
+		Totals:    make(map[string]int),
+		Schedules: []Overdue{},
+	}
+	for name, total := range r.Totals {
+		if accept(name) {
+			result.Totals[name] = total
+			result.Total += total
+		}
+	}
+	for _, o := range r.Schedules {
+		if accept(o.Scheduler) {
+			result.Schedules = append(result.Schedules, o)
+		}
+	}
+	return result
+}
+
+// Config contains the dependencies of the checker
+// Tracker computes the outcome of the schedules of the cold DB from the history
+// GracePeriod is the delay after the epoch from which a schedule not triggered is reported
+// WebhookURL is optional, when set the new overdue schedules of each check are posted to it
+// Ready returns true when the history is loaded, the periodic checks are skipped until then,
+// the schedules would be reported as overdue before their history entries are loaded. Nil means ready.
+type Config struct {
+	ColdDB      db.DB
+	Tracker     *outcome.Tracker
+	Resolver    schedulers.Resolver
+	Interval    time.Duration
+	GracePeriod time.Duration
+	WebhookURL  string
+	Max         int
+	Ready       func() bool
+}
+
+// Checker periodically looks for the overdue schedules of the schedulers, it exports their number
+// per scheduler and posts the new ones to the webhook. The first check runs after the first interval
+// where the history is loaded.
+type Checker struct {
+	Config
+	mutex *sync.RWMutex
+	last  Report
+	// the checks are serialized
+	checking *sync.Mutex
+	// keys of the overdue schedules already posted to the webhook
+	notified map[string]bool
+	stopChan chan bool
+	wg       *sync.WaitGroup
+}
+
+func NewChecker(cfg Config) *Checker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = DefaultGracePeriod
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = DefaultMax
+	}
+
+	return &Checker{
+		Config:   cfg,
+		mutex:    &sync.RWMutex{},
+		last:     Report{Totals: map[string]int{}, Schedules: []Overdue{}},
+		checking: &sync.Mutex{},
+		notified: make(map[string]bool),
+		stopChan: make(chan bool),
+		wg:       &sync.WaitGroup{},
+	}
+}
+
+// Start runs the checks every interval until the checker is closed
+func (c *Checker) Start() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if c.Ready != nil && !c.Ready() {
+					log.Printf("history not loaded, overdue check skipped")
+					continue
+				}
+				c.Check()
+			case <-c.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the checks and waits for the running one
+func (c *Checker) Close() {
+	close(c.stopChan)
+	c.wg.Wait()
+	log.Printf("overdue checker closed")
+}
+
+// Last returns the report of the last check, empty before the first one
+func (c *Checker) Last() Report {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.last
+}
+
+// Check looks for the overdue schedules of all the schedulers, updates the gauge and posts the new ones to the webhook
+func (c *Checker) Check() Report {
+	c.checking.Lock()
+	defer c.checking.Unlock()
+
+	now := time.Now()
+
+	report := Report{
+		Checked:   now,
+		Totals:    make(map[string]int),
+		Schedules: []Overdue{},
+	}
+
+	schs, err := c.Resolver.List()
+	if err != nil {
+		log.Errorf("cannot list schedulers: %v", err)
+		// the resolved schedulers are checked
+	}
+
+	metrics.OverdueSchedules.Reset()
+	for _, sch := range schs {
+		total, overdue, err := c.check(sch.Name(), now)
+		if err != nil {
+			log.Errorf("cannot check overdue schedules of %v: %v", sch.Name(), err)
+			continue
+		}
+		metrics.OverdueSchedules.WithLabelValues(sch.Name()).Set(float64(total))
+		report.Total += total
+		report.Totals[sch.Name()] = total
+		report.Schedules = append(report.Schedules, overdue...)
+	}
+
+	c.notify(report)
+
+	c.mutex.Lock()
+	c.last = report
+	c.mutex.Unlock()
+
+	return report
+}
+
+// check returns the schedules not triggered (pending or overdue) with an epoch older than the grace period
+func (c *Checker) check(schedulerName string, now time.Time) (int, []Overdue, error) {
+	total, list, err := c.Tracker.Search(c.ColdDB, db.SearchQuery{
+		Filter: db.Filter{
+			SchedulerName: schedulerName,
+			EpochRange: db.EpochRange{
+				To: now.Add(-c.GracePeriod).Unix(),
+			},
+		},
+		Limit: db.Limit{
+			Max: c.Max,
+		},
+		SortBy: sort.By{Field: sort.Epoch, Order: sort.Asc},
+	}, outcome.Pending, outcome.Overdue)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result := []Overdue{}
+	for s := range list {
+		result = append(result, Overdue{
+			Scheduler: schedulerName,
+			ID:        s.ID(),
+			Epoch:     s.Epoch(),
+			Late:      now.Unix() - s.Epoch(),
+		})
+	}
+
+	return total, result, nil
+}
+
+// notify posts the overdue schedules which were not posted by the previous checks,
+// they are posted again by the next check when the webhook fails
+func (c *Checker) notify(report Report) {
+	if c.WebhookURL == "" {
+		return
+	}
+
+	notified := make(map[string]bool, len(report.Schedules))
+	news := []Overdue{}
+	for _, o := range report.Schedules {
+		if c.notified[o.key()] {
+			notified[o.key()] = true
+		} else {
+			news = append(news, o)
+		}
+	}
+
+	if len(news) != 0 {
+		// the report of the check with only the new schedules
+		err := c.post(Report{Checked: report.Checked, Total: report.Total, Totals: report.Totals, Schedules: news})
+		if err != nil {
+			log.Errorf("cannot post overdue schedules to the webhook: %v", err)
+		} else {
+			for _, o := range news {
+				notified[o.key()] = true
+			}
+		}
+	}
+
+	// the schedules not overdue anymore are forgotten
+	c.notified = notified
+}
+
+func (c *Checker) post(report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), WebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook failed: %v %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
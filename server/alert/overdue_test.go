@@ -0,0 +1,243 @@
+package alert_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/alert"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	simple_schedule "github.com/etf1/kafka-message-scheduler/schedule/simple"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// webhook is a local http stub recording the posted reports, it fails while failing is true
+type webhook struct {
+	mutex   sync.Mutex
+	reports []alert.Report
+	failing bool
+}
+
+func (wh *webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wh.mutex.Lock()
+	defer wh.mutex.Unlock()
+
+	if wh.failing {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var report alert.Report
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	wh.reports = append(wh.reports, report)
+}
+
+func (wh *webhook) posted() []string {
+	wh.mutex.Lock()
+	defer wh.mutex.Unlock()
+
+	result := []string{}
+	for _, r := range wh.reports {
+		ids := []string{}
+		for _, o := range r.Schedules {
+			ids = append(ids, o.ID)
+		}
+		result = append(result, strings.Join(ids, ","))
+	}
+	return result
+}
+
+func ids(report alert.Report) []string {
+	result := []string{}
+	for _, o := range report.Schedules {
+		result = append(result, o.ID)
+	}
+	return result
+}
+
+// Rule #1: the schedules not triggered with an epoch older than the grace period should be reported,
+// exported in the gauge and posted once to the webhook
+func TestChecker_Check(t *testing.T) {
+	now := time.Now().Unix()
+
+	cold := hmap.NewStore()
+	history := hmap.NewStore()
+
+	cold.Add("scheduler-1",
+		simple_schedule.NewSchedule("schedule-1", now-7200),
+		simple_schedule.NewSchedule("schedule-2", now-3600),
+		// triggered
+		simple_schedule.NewSchedule("schedule-3", now-3600),
+		// in the grace period
+		simple_schedule.NewSchedule("schedule-4", now-60),
+		simple_schedule.NewSchedule("schedule-5", now+3600),
+	)
+	cold.Add("scheduler-2", simple_schedule.NewSchedule("schedule-6", now-3600))
+	history.Add("scheduler-1", simple_schedule.NewSchedule("schedule-3", now-3600))
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     hmap.NewStore(),
+		History:        history,
+		CancelledStore: hmap.NewStore(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolver := slice.NewResolver()
+	resolver.Add(slice.Scheduler{SchedulerName: "scheduler-1"}, slice.Scheduler{SchedulerName: "scheduler-2"})
+
+	wh := &webhook{failing: true}
+	srv := httptest.NewServer(wh)
+	defer srv.Close()
+
+	checker := alert.NewChecker(alert.Config{
+		ColdDB:      simple.DB{Store: cold},
+		Tracker:     tracker,
+		Resolver:    resolver,
+		GracePeriod: 5 * time.Minute,
+		WebhookURL:  srv.URL,
+	})
+
+	if report := checker.Last(); report.Total != 0 || len(report.Schedules) != 0 {
+		t.Errorf("unexpected report before the first check: %+v", report)
+	}
+
+	report := checker.Check()
+	if !reflect.DeepEqual(ids(report), []string{"schedule-1", "schedule-2", "schedule-6"}) {
+		t.Errorf("unexpected overdue schedules: %v", ids(report))
+	}
+	if report.Total != 3 || !reflect.DeepEqual(report.Totals, map[string]int{"scheduler-1": 2, "scheduler-2": 1}) {
+		t.Errorf("unexpected totals: %v %v", report.Total, report.Totals)
+	}
+	if report.Schedules[0].Scheduler != "scheduler-1" || report.Schedules[0].Epoch != now-7200 || report.Schedules[0].Late < 7200 {
+		t.Errorf("unexpected overdue schedule: %+v", report.Schedules[0])
+	}
+	if !reflect.DeepEqual(checker.Last(), report) {
+		t.Errorf("unexpected last report: %+v", checker.Last())
+	}
+
+	expected := `
+# HELP kafka_message_scheduler_admin_overdue_schedules Number of schedules not triggered with an epoch older than the grace period.
+# TYPE kafka_message_scheduler_admin_overdue_schedules gauge
+kafka_message_scheduler_admin_overdue_schedules{scheduler="scheduler-1"} 2
+kafka_message_scheduler_admin_overdue_schedules{scheduler="scheduler-2"} 1
+`
+	if err := testutil.CollectAndCompare(metrics.OverdueSchedules, strings.NewReader(expected)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the webhook failed, the schedules are posted by the next check
+	if posted := wh.posted(); len(posted) != 0 {
+		t.Errorf("unexpected posted reports: %v", posted)
+	}
+	wh.mutex.Lock()
+	wh.failing = false
+	wh.mutex.Unlock()
+
+	tests := []struct {
+		before   func()
+		expected []string
+	}{
+		{func() {}, []string{"schedule-1,schedule-2,schedule-6"}},
+		// only the new overdue schedules are posted
+		{func() {}, []string{"schedule-1,schedule-2,schedule-6"}},
+		{func() {
+			cold.Add("scheduler-2", simple_schedule.NewSchedule("schedule-7", now-3600))
+		}, []string{"schedule-1,schedule-2,schedule-6", "schedule-7"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			tt.before()
+			checker.Check()
+			if posted := wh.posted(); !reflect.DeepEqual(posted, tt.expected) {
+				t.Errorf("unexpected posted reports: %v", posted)
+			}
+		})
+	}
+}
+
+// Rule #2: the report should be filtered by scheduler
+func TestReport_Filter(t *testing.T) {
+	report := alert.Report{
+		Total:  3,
+		Totals: map[string]int{"scheduler-1": 2, "scheduler-2": 1},
+		Schedules: []alert.Overdue{
+			{Scheduler: "scheduler-1", ID: "schedule-1"},
+			{Scheduler: "scheduler-1", ID: "schedule-2"},
+			{Scheduler: "scheduler-2", ID: "schedule-3"},
+		},
+	}
+
+	result := report.Filter(func(name string) bool {
+		return name == "scheduler-2"
+	})
+
+	if result.Total != 1 || !reflect.DeepEqual(result.Totals, map[string]int{"scheduler-2": 1}) || !reflect.DeepEqual(ids(result), []string{"schedule-3"}) {
+		t.Errorf("unexpected report: %+v", result)
+	}
+}
+
+// Rule #3: the periodic checks should be skipped until the history is loaded
+func TestChecker_Start(t *testing.T) {
+	cold := hmap.NewStore()
+	cold.Add("scheduler-1", simple_schedule.NewSchedule("schedule-1", time.Now().Unix()-3600))
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     hmap.NewStore(),
+		History:        hmap.NewStore(),
+		CancelledStore: hmap.NewStore(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolver := slice.NewResolver()
+	resolver.Add(slice.Scheduler{SchedulerName: "scheduler-1"})
+
+	var ready int32
+	checker := alert.NewChecker(alert.Config{
+		ColdDB:   simple.DB{Store: cold},
+		Tracker:  tracker,
+		Resolver: resolver,
+		Interval: 10 * time.Millisecond,
+		Ready: func() bool {
+			return atomic.LoadInt32(&ready) == 1
+		},
+	})
+	checker.Start()
+	defer checker.Close()
+
+	time.Sleep(100 * time.Millisecond)
+	if report := checker.Last(); !report.Checked.IsZero() {
+		t.Errorf("unexpected check before the history is loaded: %+v", report)
+	}
+
+	atomic.StoreInt32(&ready, 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for checker.Last().Checked.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatalf("no check after 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if report := checker.Last(); report.Total != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
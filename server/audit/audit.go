@@ -0,0 +1,290 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	FileMode   = 0666
+	DefaultMax = 300
+	MaxFound   = 1000
+)
+
+var (
+	// not a valid scheduler name, the bucket can be stored next to the buckets of the schedules
+	bucketName = []byte("_audit")
+	// ErrTampered is returned by Verify when an entry has been modified or removed
+	ErrTampered = errors.New("audit log has been tampered")
+)
+
+type Action string
+
+const (
+	CreateSchedule Action = "create"
+	UpdateSchedule Action = "update"
+	CancelSchedule Action = "cancel"
+	StartBulkJob   Action = "bulk-start"
+	AbortBulkJob   Action = "bulk-abort"
+)
+
+// Entry is an administrative action, entries are chained by their hash: the hash of an entry
+// is computed with the hash of the previous entry, so a modified or removed entry is detected
+type Entry struct {
+	Sequence   uint64    `json:"sequence"`
+	Timestamp  time.Time `json:"timestamp"`
+	User       string    `json:"user"`
+	RemoteAddr string    `json:"remote-addr,omitempty"`
+	Action     Action    `json:"action"`
+	Scheduler  string    `json:"scheduler"`
+	ScheduleID string    `json:"schedule-id,omitempty"`
+	// id of the bulk job which has processed the schedule
+	Job string `json:"job,omitempty"`
+	// payload before and after the action (schedule, bulk job), empty when there is none
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+	// error of the action, empty when it succeeded
+	Error        string `json:"error,omitempty"`
+	PreviousHash string `json:"previous-hash"`
+	Hash         string `json:"hash"`
+}
+
+// Payload returns the json representation of v for the before and after fields, nil when v is nil
+func Payload(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Errorf("cannot marshal audit payload %+v: %v", v, err)
+		return nil
+	}
+	return data
+}
+
+func (e Entry) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Mirror receives a copy of the recorded entries (ie: a kafka topic)
+type Mirror interface {
+	Publish(e Entry) error
+}
+
+type Filter struct {
+	From time.Time
+	To   time.Time
+	User string
+	// scheduler names, empty means all the schedulers
+	Schedulers []string
+	Max        int
+}
+
+func (f Filter) matches(e Entry) bool {
+	if !f.To.IsZero() && e.Timestamp.After(f.To) {
+		return false
+	}
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+	if len(f.Schedulers) == 0 {
+		return true
+	}
+	for _, name := range f.Schedulers {
+		if e.Scheduler == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Log is the audit log persisted in a bbolt bucket
+type Log struct {
+	db     *bolt.DB
+	mirror Mirror
+	mutex  *sync.Mutex
+	// the database is closed with the log when it has been opened by the log
+	owned bool
+}
+
+// Open opens or creates the audit log in its own file, mirror is optional
+func Open(path string, mirror Mirror) (*Log, error) {
+	db, err := bolt.Open(path, FileMode, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := New(db, mirror)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	l.owned = true
+
+	return l, nil
+}
+
+// New creates the audit log in a bucket of an existing database (ie: the schedules store), mirror is optional.
+// The database is not closed by the log.
+func New(db *bolt.DB, mirror Mirror) (*Log, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create audit bucket: %w", err)
+	}
+
+	return &Log{
+		db:     db,
+		mirror: mirror,
+		mutex:  &sync.Mutex{},
+	}, nil
+}
+
+func (l *Log) Close() {
+	if !l.owned {
+		return
+	}
+	if err := l.db.Close(); err != nil {
+		log.Errorf("cannot close audit log: %v", err)
+	}
+}
+
+func key(sequence uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, sequence)
+	return b
+}
+
+// Record appends the entry to the log, the sequence, the timestamp and the hashes are set by the log
+func (l *Log) Record(e Entry) (Entry, error) {
+	// entries are recorded one at a time to keep the mirror in the same order as the log
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+
+		e.PreviousHash = ""
+		if _, last := b.Cursor().Last(); last != nil {
+			var previous Entry
+			if err := json.Unmarshal(last, &previous); err != nil {
+				return err
+			}
+			e.PreviousHash = previous.Hash
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		e.Sequence = seq
+		e.Timestamp = time.Now().UTC()
+
+		e.Hash, err = e.hash()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(key(seq), data)
+	})
+	if err != nil {
+		return Entry{}, fmt.Errorf("cannot record audit entry: %w", err)
+	}
+
+	if l.mirror != nil {
+		if err := l.mirror.Publish(e); err != nil {
+			log.Errorf("cannot mirror audit entry %v: %v", e.Sequence, err)
+		}
+	}
+
+	return e, nil
+}
+
+// Search returns the entries matching the filter, the most recent first
+func (l *Log) Search(f Filter) ([]Entry, error) {
+	max := DefaultMax
+	if f.Max > 0 && f.Max < MaxFound {
+		max = f.Max
+	}
+
+	result := []Entry{}
+
+	err := l.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		for k, v := c.Last(); k != nil && len(result) < max; k, v = c.Prev() {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			// entries are ordered by time
+			if !f.From.IsZero() && e.Timestamp.Before(f.From) {
+				break
+			}
+			if f.matches(e) {
+				result = append(result, e)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Verify checks the hash chain of the log and returns the number of entries
+func (l *Log) Verify() (int, error) {
+	count := 0
+
+	err := l.db.View(func(tx *bolt.Tx) error {
+		previousHash := ""
+		var expectedSeq uint64 = 1
+
+		return tx.Bucket(bucketName).ForEach(func(k, v []byte) error {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("%w: cannot unmarshal entry %x: %v", ErrTampered, k, err)
+			}
+			if e.Sequence != expectedSeq || binary.BigEndian.Uint64(k) != e.Sequence {
+				return fmt.Errorf("%w: missing entry %v", ErrTampered, expectedSeq)
+			}
+			hash, err := e.hash()
+			if err != nil {
+				return err
+			}
+			if e.PreviousHash != previousHash || e.Hash != hash {
+				return fmt.Errorf("%w: invalid hash for entry %v", ErrTampered, e.Sequence)
+			}
+
+			previousHash = e.Hash
+			expectedSeq++
+			count++
+			return nil
+		})
+	})
+
+	return count, err
+}
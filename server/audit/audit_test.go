@@ -0,0 +1,233 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	bolt "go.etcd.io/bbolt"
+)
+
+type mirror struct {
+	entries []audit.Entry
+}
+
+func (m *mirror) Publish(e audit.Entry) error {
+	m.entries = append(m.entries, e)
+	return nil
+}
+
+func record(t *testing.T, l *audit.Log, user, scheduler, scheduleID string) audit.Entry {
+	e, err := l.Record(audit.Entry{
+		User:       user,
+		Action:     audit.UpdateSchedule,
+		Scheduler:  scheduler,
+		ScheduleID: scheduleID,
+		Before:     audit.Payload(map[string]int64{"epoch": 1}),
+		After:      audit.Payload(map[string]int64{"epoch": 2}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return e
+}
+
+func ids(entries []audit.Entry) string {
+	result := []string{}
+	for _, e := range entries {
+		result = append(result, e.ScheduleID)
+	}
+	return fmt.Sprint(result)
+}
+
+// Rule #1: entries should be chained, persisted and mirrored
+func TestLog_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.bbolt")
+	m := &mirror{}
+
+	l, err := audit.Open(path, m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e1 := record(t, l, "user1", "scheduler-1", "schedule-1")
+	e2 := record(t, l, "user2", "scheduler-2", "schedule-2")
+	l.Close()
+
+	if e1.Sequence != 1 || e1.PreviousHash != "" || e1.Hash == "" {
+		t.Errorf("unexpected first entry: %+v", e1)
+	}
+	if e2.Sequence != 2 || e2.PreviousHash != e1.Hash {
+		t.Errorf("unexpected second entry: %+v", e2)
+	}
+	if len(m.entries) != 2 || m.entries[1].Hash != e2.Hash {
+		t.Errorf("unexpected mirrored entries: %+v", m.entries)
+	}
+
+	// reopen the log, the chain continues
+	l, err = audit.Open(path, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	e3 := record(t, l, "user1", "scheduler-1", "schedule-3")
+	if e3.Sequence != 3 || e3.PreviousHash != e2.Hash {
+		t.Errorf("unexpected third entry: %+v", e3)
+	}
+
+	count, err := l.Verify()
+	if err != nil || count != 3 {
+		t.Errorf("unexpected verification: %v %v", count, err)
+	}
+}
+
+// Rule #2: search should return the entries matching the filter, most recent first
+func TestLog_Search(t *testing.T) {
+	l, err := audit.Open(filepath.Join(t.TempDir(), "audit.bbolt"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	before := time.Now().Add(-time.Second)
+	record(t, l, "user1", "scheduler-1", "schedule-1")
+	record(t, l, "user2", "scheduler-1", "schedule-2")
+	record(t, l, "user1", "scheduler-2", "schedule-3")
+	after := time.Now().Add(time.Second)
+
+	tests := []struct {
+		filter   audit.Filter
+		expected string
+	}{
+		{audit.Filter{}, "[schedule-3 schedule-2 schedule-1]"},
+		{audit.Filter{User: "user1"}, "[schedule-3 schedule-1]"},
+		{audit.Filter{Schedulers: []string{"scheduler-1"}}, "[schedule-2 schedule-1]"},
+		{audit.Filter{User: "user1", Schedulers: []string{"scheduler-1"}}, "[schedule-1]"},
+		{audit.Filter{Max: 1}, "[schedule-3]"},
+		{audit.Filter{From: before, To: after}, "[schedule-3 schedule-2 schedule-1]"},
+		{audit.Filter{From: after}, "[]"},
+		{audit.Filter{To: before}, "[]"},
+	}
+
+	for i, tt := range tests {
+		entries, err := l.Search(tt.filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual := ids(entries); actual != tt.expected {
+			t.Errorf("case #%v: unexpected entries: %v, expected %v", i+1, actual, tt.expected)
+		}
+	}
+}
+
+// Rule #3: verify should detect modified and removed entries
+func TestLog_Verify(t *testing.T) {
+	tests := []struct {
+		tamper func(b *bolt.Bucket) error
+	}{
+		// modified entry
+		{func(b *bolt.Bucket) error {
+			k, v := b.Cursor().First()
+			var e audit.Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			e.User = "someone-else"
+			data, _ := json.Marshal(e)
+			return b.Put(k, data)
+		}},
+		// removed entry
+		{func(b *bolt.Bucket) error {
+			k, _ := b.Cursor().First()
+			return b.Delete(k)
+		}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.bbolt")
+			l, err := audit.Open(path, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			record(t, l, "user1", "scheduler-1", "schedule-1")
+			record(t, l, "user1", "scheduler-1", "schedule-2")
+			l.Close()
+
+			db, err := bolt.Open(path, audit.FileMode, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err = db.Update(func(tx *bolt.Tx) error {
+				return tt.tamper(tx.Bucket([]byte("_audit")))
+			})
+			db.Close()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			l, err = audit.Open(path, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer l.Close()
+
+			_, err = l.Verify()
+			if !errors.Is(err, audit.ErrTampered) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// Rule #4: a log created in an existing database should be stored in its own bucket and should not close the database
+func TestLog_New(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "schedules.bbolt"), audit.FileMode, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte("scheduler-1"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	l, err := audit.New(db, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	record(t, l, "user1", "scheduler-1", "schedule-1")
+	l.Close()
+
+	// the database is still open
+	buckets := []string{}
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			buckets = append(buckets, string(name))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(buckets) != "[_audit scheduler-1]" {
+		t.Errorf("unexpected buckets: %v", buckets)
+	}
+
+	l, err = audit.New(db, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count, err := l.Verify(); err != nil || count != 1 {
+		t.Errorf("unexpected verification: %v %v", count, err)
+	}
+}
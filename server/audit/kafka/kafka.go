@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BaseNumber   = 10
+	FlushTimeout = 5000
+)
+
+var (
+	DeliveryTimeout = 10 * time.Second
+)
+
+// Mirror publishes the audit entries to a kafka topic, the key of the messages is the sequence of the entry
+type Mirror struct {
+	producer *confluent.Producer
+	topic    string
+}
+
+// NewMirror returns a mirror producing to the topic, with the client properties (security protocol, SASL, TLS ...)
+func NewMirror(bootstrapServers, topic string, properties kafka.Properties) (*Mirror, error) {
+	cm := &confluent.ConfigMap{
+		"bootstrap.servers": bootstrapServers,
+	}
+	if err := properties.Apply(cm); err != nil {
+		return nil, err
+	}
+
+	kp, err := confluent.NewProducer(cm)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create kafka producer for %v: %w", bootstrapServers, err)
+	}
+
+	// delivery reports are sent to the delivery channel, only errors are left here
+	go func() {
+		for e := range kp.Events() {
+			if kerr, ok := e.(confluent.Error); ok {
+				log.Errorf("received kafka audit producer error: %v", kerr)
+			}
+		}
+	}()
+
+	return &Mirror{
+		producer: kp,
+		topic:    topic,
+	}, nil
+}
+
+func (m *Mirror) Close() {
+	if remaining := m.producer.Flush(FlushTimeout); remaining > 0 {
+		log.Warnf("kafka audit producer closed with %v unflushed messages", remaining)
+	}
+	m.producer.Close()
+}
+
+func (m *Mirror) Publish(e audit.Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	deliveryChan := make(chan confluent.Event, 1)
+
+	err = m.producer.Produce(&confluent.Message{
+		TopicPartition: confluent.TopicPartition{Topic: &m.topic, Partition: confluent.PartitionAny},
+		Key:            []byte(strconv.FormatUint(e.Sequence, BaseNumber)),
+		Value:          data,
+	}, deliveryChan)
+	if err != nil {
+		return fmt.Errorf("cannot produce audit entry to topic %v: %w", m.topic, err)
+	}
+
+	timeout := time.NewTimer(DeliveryTimeout)
+	defer timeout.Stop()
+
+	select {
+	case evt := <-deliveryChan:
+		msg, ok := evt.(*confluent.Message)
+		if !ok {
+			return fmt.Errorf("unexpected delivery event: %v", evt)
+		}
+		if msg.TopicPartition.Error != nil {
+			return fmt.Errorf("delivery failed for audit entry %v: %w", e.Sequence, msg.TopicPartition.Error)
+		}
+	case <-timeout.C:
+		return fmt.Errorf("delivery timeout for audit entry %v to topic %v", e.Sequence, m.topic)
+	}
+
+	return nil
+}
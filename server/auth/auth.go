@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// AllSchedulers is the scheduler name of a role granted on all the schedulers
+const AllSchedulers = "*"
+
+var (
+	// ErrNoCredentials is returned when the request doesn't contain credentials handled by the authenticator
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned when the credentials of the request are not valid
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidRole        = errors.New("invalid role")
+)
+
+// Role is the level of access on a scheduler, each role includes the previous ones
+type Role int
+
+const (
+	NoRole Role = iota
+	// Viewer can search and read the schedules
+	Viewer
+	// Operator can create, update and cancel schedules
+	Operator
+	// Admin can run bulk operations
+	Admin
+)
+
+func (r Role) String() string {
+	switch r {
+	case Viewer:
+		return "viewer"
+	case Operator:
+		return "operator"
+	case Admin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+func ParseRole(s string) (Role, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "viewer":
+		return Viewer, nil
+	case "operator":
+		return Operator, nil
+	case "admin":
+		return Admin, nil
+	default:
+		return NoRole, fmt.Errorf("%w: %q", ErrInvalidRole, s)
+	}
+}
+
+// Roles are the roles of a principal per scheduler name, AllSchedulers is used for the roles on all schedulers
+type Roles map[string]Role
+
+// ParseRoles parses a comma separated list of roles with the format role@scheduler,
+// the scheduler can be omitted for a role on all the schedulers, ie: "operator@scheduler-1,viewer"
+func ParseRoles(s string) (Roles, error) {
+	result := Roles{}
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		if err := result.add(item); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (r Roles) add(item string) error {
+	schedulerName := AllSchedulers
+	if i := strings.Index(item, "@"); i != -1 {
+		item, schedulerName = item[:i], item[i+1:]
+	}
+
+	role, err := ParseRole(item)
+	if err != nil {
+		return err
+	}
+
+	if role > r[schedulerName] {
+		r[schedulerName] = role
+	}
+
+	return nil
+}
+
+// Merge returns the roles with the highest role of r and other for each scheduler
+func (r Roles) Merge(other Roles) Roles {
+	result := Roles{}
+	for _, roles := range []Roles{r, other} {
+		for name, role := range roles {
+			if role > result[name] {
+				result[name] = role
+			}
+		}
+	}
+	return result
+}
+
+// Allows tells if the role is granted on the scheduler, an empty scheduler name
+// means any scheduler (used for the routes which are not related to a scheduler)
+func (r Roles) Allows(role Role, schedulerName string) bool {
+	if schedulerName == "" {
+		for _, granted := range r {
+			if granted >= role {
+				return true
+			}
+		}
+		return false
+	}
+
+	return r[AllSchedulers] >= role || r[schedulerName] >= role
+}
+
+// Principal is the authenticated user of a request
+type Principal struct {
+	Name  string
+	Roles Roles
+}
+
+func (p Principal) Allows(role Role, schedulerName string) bool {
+	return p.Roles.Allows(role, schedulerName)
+}
+
+// Authenticator returns the principal of the request
+type Authenticator interface {
+	// Authenticate returns ErrNoCredentials when the request doesn't contain credentials handled by the authenticator
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Chain tries the authenticators in order, until one of them finds credentials in the request
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) (Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+
+	return Principal{}, ErrNoCredentials
+}
+
+type principalKey struct{}
+
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// RoleBindings are the roles granted to the users, loaded from a file
+type RoleBindings map[string]Roles
+
+// LoadRoleBindings loads a file with a line per user with the format user:role@scheduler,role@scheduler,
+// empty lines and lines starting with # are ignored
+func LoadRoleBindings(path string) (RoleBindings, error) {
+	result := RoleBindings{}
+
+	err := readEntries(path, func(user, value string) error {
+		roles, err := ParseRoles(value)
+		if err != nil {
+			return err
+		}
+		result[user] = result[user].Merge(roles)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Roles returns the roles of the user, empty when the user has no role
+func (rb RoleBindings) Roles(user string) Roles {
+	return Roles{}.Merge(rb[user])
+}
+
+// readEntries reads the lines of a file with the format key:value
+func readEntries(path string, fn func(key, value string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		i := strings.Index(text, ":")
+		if i <= 0 {
+			return fmt.Errorf("%v:%v: invalid entry, expected format is key:value", path, line)
+		}
+
+		err := fn(strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]))
+		if err != nil {
+			return fmt.Errorf("%v:%v: %w", path, line, err)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,302 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // used by the {SHA} format of htpasswd
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func newRequest(header, value string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "/schedulers", http.NoBody)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	return req
+}
+
+// Rule #1: roles should be granted per scheduler, a role includes the lower roles
+func TestRoles_Allows(t *testing.T) {
+	roles, err := auth.ParseRoles("operator@scheduler-1, viewer, admin@scheduler-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		role          auth.Role
+		schedulerName string
+		expected      bool
+	}{
+		{auth.Viewer, "scheduler-1", true},
+		{auth.Operator, "scheduler-1", true},
+		{auth.Admin, "scheduler-1", false},
+		{auth.Viewer, "scheduler-3", true},
+		{auth.Operator, "scheduler-3", false},
+		{auth.Admin, "scheduler-2", true},
+		// any scheduler
+		{auth.Admin, "", true},
+	}
+
+	for i, tt := range tests {
+		if actual := roles.Allows(tt.role, tt.schedulerName); actual != tt.expected {
+			t.Errorf("case #%v: unexpected result for %v@%v: %v", i+1, tt.role, tt.schedulerName, actual)
+		}
+	}
+
+	_, err = auth.ParseRoles("superuser@scheduler-1")
+	if !errors.Is(err, auth.ErrInvalidRole) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// Rule #2: static tokens should authenticate the bearer tokens of the file
+func TestTokens(t *testing.T) {
+	bindings, err := auth.LoadRoleBindings(writeFile(t, "roles", "# comment\nuser1:viewer@scheduler-1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := auth.LoadTokens(writeFile(t, "tokens", "user1:token1\nuser2:token2\n"), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p, err := tokens.Authenticate(newRequest("Authorization", "Bearer token1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "user1" || !reflect.DeepEqual(p.Roles, auth.Roles{"scheduler-1": auth.Viewer}) {
+		t.Errorf("unexpected principal: %+v", p)
+	}
+
+	p, err = tokens.Authenticate(newRequest("Authorization", "Bearer token2"))
+	if err != nil || p.Name != "user2" || len(p.Roles) != 0 {
+		t.Errorf("unexpected principal: %+v %v", p, err)
+	}
+
+	for _, req := range []*http.Request{newRequest("", ""), newRequest("Authorization", "Bearer unknown")} {
+		_, err = tokens.Authenticate(req)
+		if !errors.Is(err, auth.ErrNoCredentials) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+// Rule #3: basic authentication should verify the bcrypt and SHA1 passwords of the htpasswd file
+func TestBasic(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sum := sha1.Sum([]byte("password2")) //nolint:gosec // used by the {SHA} format of htpasswd
+	shaHash := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+
+	basic, err := auth.LoadHtpasswd(writeFile(t, "htpasswd", fmt.Sprintf("user1:%s\nuser2:%s\n", bcryptHash, shaHash)), auth.RoleBindings{
+		"user2": auth.Roles{auth.AllSchedulers: auth.Admin},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		user          string
+		password      string
+		expectedError error
+	}{
+		{"user1", "password1", nil},
+		{"user2", "password2", nil},
+		{"user1", "password2", auth.ErrInvalidCredentials},
+		{"user3", "password1", auth.ErrInvalidCredentials},
+	}
+
+	for i, tt := range tests {
+		req := newRequest("", "")
+		req.SetBasicAuth(tt.user, tt.password)
+		p, err := basic.Authenticate(req)
+		if !errors.Is(err, tt.expectedError) {
+			t.Errorf("case #%v: unexpected error: %v", i+1, err)
+		}
+		if err == nil && p.Name != tt.user {
+			t.Errorf("case #%v: unexpected principal: %+v", i+1, p)
+		}
+	}
+
+	_, err = basic.Authenticate(newRequest("", ""))
+	if !errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = auth.LoadHtpasswd(writeFile(t, "htpasswd", "user1:$apr1$salt$hash\n"), nil)
+	if err == nil {
+		t.Errorf("unsupported hash format should return an error")
+	}
+}
+
+func encodeSegment(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func signRS256(key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": "ES256", "kid": kid}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Rule #4: JWT authenticator should verify the signature and the claims of the bearer token
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "alg": "RS256", "n": encodeBigInt(rsaKey.N), "e": encodeBigInt(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encodeBigInt(ecKey.X), "y": encodeBigInt(ecKey.Y)},
+		},
+	}
+	b, _ := json.Marshal(jwks)
+
+	jwt, err := auth.NewJWT(auth.JWTConfig{
+		JWKSPath: writeFile(t, "jwks.json", string(b)),
+		Issuer:   "https://issuer",
+		Audience: "admin",
+	}, auth.RoleBindings{
+		"user1": auth.Roles{"scheduler-2": auth.Viewer},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		result := map[string]interface{}{
+			"sub":   "user1",
+			"iss":   "https://issuer",
+			"aud":   []string{"admin", "other"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": []string{"operator@scheduler-1", "unrelated-role"},
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(result, k)
+			} else {
+				result[k] = v
+			}
+		}
+		return result
+	}
+
+	tests := []struct {
+		token         string
+		expectedError error
+	}{
+		{signRS256(rsaKey, "rsa", claims(nil)), nil},
+		{signES256(ecKey, "ec", claims(nil)), nil},
+		{signRS256(rsaKey, "", claims(nil)), nil},
+		{signRS256(otherKey, "rsa", claims(nil)), auth.ErrInvalidCredentials},
+		{signRS256(rsaKey, "ec", claims(nil)), auth.ErrInvalidCredentials},
+		{signRS256(rsaKey, "rsa", claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})), auth.ErrInvalidCredentials},
+		{signRS256(rsaKey, "rsa", claims(map[string]interface{}{"exp": nil})), auth.ErrInvalidCredentials},
+		{signRS256(rsaKey, "rsa", claims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})), auth.ErrInvalidCredentials},
+		{signRS256(rsaKey, "rsa", claims(map[string]interface{}{"iss": "https://other"})), auth.ErrInvalidCredentials},
+		{signRS256(rsaKey, "rsa", claims(map[string]interface{}{"aud": "other"})), auth.ErrInvalidCredentials},
+		{signRS256(rsaKey, "rsa", claims(map[string]interface{}{"sub": nil})), auth.ErrInvalidCredentials},
+		{encodeSegment(map[string]string{"alg": "none"}) + "." + encodeSegment(claims(nil)) + ".", auth.ErrInvalidCredentials},
+		{"not-a-jwt", auth.ErrInvalidCredentials},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			p, err := jwt.Authenticate(newRequest("Authorization", "Bearer "+tt.token))
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			expected := auth.Roles{"scheduler-1": auth.Operator, "scheduler-2": auth.Viewer}
+			if p.Name != "user1" || !reflect.DeepEqual(p.Roles, expected) {
+				t.Errorf("unexpected principal: %+v", p)
+			}
+		})
+	}
+}
+
+// Rule #5: chain should use the first authenticator which finds credentials in the request
+func TestChain(t *testing.T) {
+	tokens, err := auth.LoadTokens(writeFile(t, "tokens", "user1:token1\n"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	basic, err := auth.LoadHtpasswd(writeFile(t, "htpasswd", "user2:{SHA}invalid\n"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chain := auth.Chain{tokens, basic}
+
+	p, err := chain.Authenticate(newRequest("Authorization", "Bearer token1"))
+	if err != nil || p.Name != "user1" {
+		t.Errorf("unexpected result: %+v %v", p, err)
+	}
+
+	req := newRequest("", "")
+	req.SetBasicAuth("user2", "password")
+	_, err = chain.Authenticate(req)
+	if !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = chain.Authenticate(newRequest("", ""))
+	if !errors.Is(err, auth.ErrNoCredentials) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
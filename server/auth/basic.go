@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"crypto/sha1" //nolint:gosec // used by the {SHA} format of htpasswd
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Basic authenticates the requests with http basic authentication against a htpasswd file
+type Basic struct {
+	hashes   map[string]string
+	bindings RoleBindings
+}
+
+// LoadHtpasswd loads a htpasswd file, only bcrypt (htpasswd -B) and SHA1 (htpasswd -s) hashes are supported,
+// the roles of the users are defined by the role bindings
+func LoadHtpasswd(path string, bindings RoleBindings) (Basic, error) {
+	result := Basic{
+		hashes:   map[string]string{},
+		bindings: bindings,
+	}
+
+	err := readEntries(path, func(user, hash string) error {
+		if !isBcrypt(hash) && !strings.HasPrefix(hash, "{SHA}") {
+			return fmt.Errorf("unsupported hash format for user %v, only bcrypt and SHA1 are supported", user)
+		}
+		result.hashes[user] = hash
+		return nil
+	})
+	if err != nil {
+		return Basic{}, err
+	}
+
+	return result, nil
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2y$") || strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$")
+}
+
+func (b Basic) Authenticate(r *http.Request) (Principal, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	hash, found := b.hashes[user]
+	if !found || !verifyPassword(hash, password) {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	return Principal{
+		Name:  user,
+		Roles: b.bindings.Roles(user),
+	}, nil
+}
+
+func verifyPassword(hash, password string) bool {
+	if isBcrypt(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	sum := sha1.Sum([]byte(password)) //nolint:gosec // used by the {SHA} format of htpasswd
+	expected := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(expected)) == 1
+}
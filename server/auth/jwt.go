@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultUserClaim  = "sub"
+	DefaultRolesClaim = "roles"
+	// tolerated clock difference with the issuer
+	clockSkew = time.Minute
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// rsa
+	N string `json:"n"`
+	E string `json:"e"`
+	// ecdsa
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type key struct {
+	kid       string
+	alg       string
+	publicKey crypto.PublicKey
+}
+
+// JWTConfig is the configuration of the JWT/OIDC bearer authenticator
+// JWKSPath is the path of the JSON Web Key Set with the public keys of the issuer
+// Issuer and Audience are verified when not empty
+// UserClaim is the claim of the user name, default is sub
+// RolesClaim is the claim with the list of roles (same format as the role bindings: role@scheduler), default is roles
+type JWTConfig struct {
+	JWKSPath   string
+	Issuer     string
+	Audience   string
+	UserClaim  string
+	RolesClaim string
+}
+
+// JWT authenticates the requests with JWT bearer tokens signed with RS256, RS384, RS512, ES256, ES384 or ES512,
+// the roles of the claim are merged with the roles of the role bindings
+type JWT struct {
+	cfg      JWTConfig
+	keys     []key
+	bindings RoleBindings
+}
+
+func NewJWT(cfg JWTConfig, bindings RoleBindings) (JWT, error) {
+	if cfg.UserClaim == "" {
+		cfg.UserClaim = DefaultUserClaim
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = DefaultRolesClaim
+	}
+
+	keys, err := loadJWKS(cfg.JWKSPath)
+	if err != nil {
+		return JWT{}, fmt.Errorf("cannot load JWKS %v: %w", cfg.JWKSPath, err)
+	}
+
+	return JWT{
+		cfg:      cfg,
+		keys:     keys,
+		bindings: bindings,
+	}, nil
+}
+
+func loadJWKS(path string) ([]key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.Unmarshal(data, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []key{}
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		publicKey, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", k.Kid, err)
+		}
+		result = append(result, key{k.Kid, k.Alg, publicKey})
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no signing key found")
+	}
+
+	return result, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %v", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %v", k.Kty)
+	}
+}
+
+func hashFunc(alg string) (crypto.Hash, bool) {
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, true
+	case "384":
+		return crypto.SHA384, true
+	case "512":
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// verify checks the signature of the signed part of the token with the key
+func (k key) verify(alg, signed string, signature []byte) error {
+	if len(alg) != 5 || (k.alg != "" && k.alg != alg) {
+		return fmt.Errorf("unexpected algorithm: %v", alg)
+	}
+	hash, ok := hashFunc(alg)
+	if !ok {
+		return fmt.Errorf("unsupported algorithm: %v", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch publicKey := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("unexpected algorithm for RSA key: %v", alg)
+		}
+		return rsa.VerifyPKCS1v15(publicKey, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("unexpected algorithm for EC key: %v", alg)
+		}
+		// signature is the concatenation of r and s
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type: %T", k.publicKey)
+	}
+}
+
+func (j JWT) Authenticate(r *http.Request) (Principal, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return Principal{}, ErrNoCredentials
+	}
+
+	claims, err := j.verify(token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	user, _ := claims[j.cfg.UserClaim].(string)
+	if user == "" {
+		return Principal{}, fmt.Errorf("%w: missing claim %v", ErrInvalidCredentials, j.cfg.UserClaim)
+	}
+
+	roles := Roles{}
+	for _, item := range stringList(claims[j.cfg.RolesClaim]) {
+		// roles not related to the application are ignored
+		_ = roles.add(item)
+	}
+
+	return Principal{
+		Name:  user,
+		Roles: roles.Merge(j.bindings.Roles(user)),
+	}, nil
+}
+
+// verify checks the signature and the registered claims of the token, and returns its claims
+func (j JWT) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	verified := false
+	for _, k := range j.keys {
+		if header.Kid != "" && k.kid != "" && header.Kid != k.kid {
+			continue
+		}
+		if k.verify(header.Alg, parts[0]+"."+parts[1], signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("missing expiration time")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token not valid yet")
+	}
+	if iss, _ := claims["iss"].(string); j.cfg.Issuer != "" && iss != j.cfg.Issuer {
+		return nil, fmt.Errorf("unexpected issuer: %v", iss)
+	}
+	if j.cfg.Audience != "" && !contains(stringList(claims["aud"]), j.cfg.Audience) {
+		return nil, fmt.Errorf("unexpected audience: %v", claims["aud"])
+	}
+
+	return claims, nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// stringList returns the values of a claim which is a string or a list of strings
+func stringList(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(strings.ReplaceAll(c, ",", " "))
+	case []interface{}:
+		result := []string{}
+		for _, item := range c {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+func contains(arr []string, s string) bool {
+	for _, item := range arr {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
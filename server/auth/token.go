@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// bearerToken returns the bearer token of the Authorization header, or of the access_token
+// query parameter for the clients which cannot set headers (ie: EventSource for the events stream)
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(header[len("Bearer "):])
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+type token struct {
+	user  string
+	value []byte
+}
+
+// Tokens authenticates the requests with static API tokens sent as bearer tokens
+type Tokens struct {
+	tokens   []token
+	bindings RoleBindings
+}
+
+// LoadTokens loads a file with a line per token with the format user:token,
+// the roles of the users are defined by the role bindings
+func LoadTokens(path string, bindings RoleBindings) (Tokens, error) {
+	result := Tokens{
+		bindings: bindings,
+	}
+
+	err := readEntries(path, func(user, value string) error {
+		result.tokens = append(result.tokens, token{user, []byte(value)})
+		return nil
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return result, nil
+}
+
+// Authenticate returns ErrNoCredentials for an unknown token, so it can be verified by another authenticator (ie: JWT)
+func (t Tokens) Authenticate(r *http.Request) (Principal, error) {
+	value := []byte(bearerToken(r))
+	if len(value) == 0 {
+		return Principal{}, ErrNoCredentials
+	}
+
+	for _, tok := range t.tokens {
+		if subtle.ConstantTimeCompare(tok.value, value) == 1 {
+			return Principal{
+				Name:  tok.user,
+				Roles: t.bindings.Roles(tok.user),
+			}, nil
+		}
+	}
+
+	return Principal{}, ErrNoCredentials
+}
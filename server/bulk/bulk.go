@@ -0,0 +1,402 @@
+package bulk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// max number of error messages kept in a job
+	MaxErrors = 100
+	// max number of jobs kept in memory, oldest finished jobs are removed first
+	MaxJobs = 100
+)
+
+var (
+	ErrJobNotFound        = errors.New("bulk: job not found")
+	ErrInvalidAction      = errors.New("bulk: invalid action, should be cancel or reschedule")
+	ErrInvalidOffset      = errors.New("bulk: offset is mandatory for reschedule")
+	ErrRescheduleDisabled = errors.New("bulk: reschedule is not available when the message body decoder is enabled")
+)
+
+type Action string
+
+const (
+	CancelAction     Action = "cancel"
+	RescheduleAction Action = "reschedule"
+)
+
+type Status string
+
+const (
+	PendingStatus Status = "pending"
+	RunningStatus Status = "running"
+	DoneStatus    Status = "done"
+	FailedStatus  Status = "failed"
+	AbortedStatus Status = "aborted"
+)
+
+type Request struct {
+	Action Action
+	// the scheduler name of the filter is mandatory
+	Filter db.Filter
+	// number of seconds added to the epoch of the schedules when rescheduling, can be negative
+	Offset int64
+	// when true, only returns the list of the schedules which would be affected
+	DryRun bool
+	// optional, called for each processed schedule with the schedule before the action and after it (nil when cancelled)
+	Processed func(jobID string, before producer.Schedule, after *producer.Schedule, err error)
+}
+
+func (r Request) validate() error {
+	switch r.Action {
+	case CancelAction:
+	case RescheduleAction:
+		if r.Offset == 0 {
+			return ErrInvalidOffset
+		}
+	default:
+		return ErrInvalidAction
+	}
+	return nil
+}
+
+// Target is a schedule affected by a job
+type Target struct {
+	ID       string `json:"id"`
+	Epoch    int64  `json:"epoch"`
+	NewEpoch int64  `json:"new-epoch,omitempty"`
+}
+
+type Job struct {
+	ID        string     `json:"id"`
+	Scheduler string     `json:"scheduler"`
+	Action    Action     `json:"action"`
+	Offset    int64      `json:"offset,omitempty"`
+	DryRun    bool       `json:"dry-run"`
+	Status    Status     `json:"status"`
+	Total     int        `json:"total"`
+	Processed int        `json:"processed"`
+	Failed    int        `json:"failed"`
+	Errors    []string   `json:"errors,omitempty"`
+	Created   time.Time  `json:"created"`
+	Started   *time.Time `json:"started,omitempty"`
+	Ended     *time.Time `json:"ended,omitempty"`
+	// only filled for dry run
+	Targets []Target `json:"schedules,omitempty"`
+}
+
+func (j Job) finished() bool {
+	return j.Status == DoneStatus || j.Status == FailedStatus || j.Status == AbortedStatus
+}
+
+type job struct {
+	mutex *sync.RWMutex
+	Job
+	stopChan chan bool
+}
+
+func (j *job) snapshot() Job {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	result := j.Job
+	result.Errors = append([]string{}, j.Errors...)
+	return result
+}
+
+func (j *job) update(f func(j *Job)) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	f(&j.Job)
+}
+
+type Config struct {
+	ColdDB   db.DB
+	Producer producer.Producer
+	// tells that the values stored in the cold DB are decoded, so they cannot be produced again
+	DecodedValues bool
+}
+
+// Manager runs the bulk jobs asynchronously, one goroutine per job
+type Manager struct {
+	Config
+	mutex *sync.RWMutex
+	jobs  map[string]*job
+	seq   int
+	wg    *sync.WaitGroup
+}
+
+func NewManager(cfg Config) *Manager {
+	return &Manager{
+		Config: cfg,
+		mutex:  &sync.RWMutex{},
+		jobs:   make(map[string]*job),
+		wg:     &sync.WaitGroup{},
+	}
+}
+
+// Close aborts the running jobs and waits for them to exit
+func (m *Manager) Close() {
+	for _, j := range m.List() {
+		if !j.finished() {
+			_, err := m.Abort(j.ID)
+			if err != nil {
+				log.Errorf("cannot abort bulk job %v: %v", j.ID, err)
+			}
+		}
+	}
+	m.wg.Wait()
+	log.Printf("bulk manager closed")
+}
+
+func (m *Manager) Start(req Request) (Job, error) {
+	if err := req.validate(); err != nil {
+		return Job{}, err
+	}
+	if req.Action == RescheduleAction && m.DecodedValues && !req.DryRun {
+		return Job{}, ErrRescheduleDisabled
+	}
+
+	j := m.newJob(req)
+
+	if req.DryRun {
+		m.dryRun(j, req)
+		return j.snapshot(), nil
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.run(j, req)
+	}()
+
+	return j.snapshot(), nil
+}
+
+func (m *Manager) Get(id string) (Job, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	j, found := m.jobs[id]
+	if !found {
+		return Job{}, fmt.Errorf("%w: %v", ErrJobNotFound, id)
+	}
+	return j.snapshot(), nil
+}
+
+// List returns the jobs, most recent first
+func (m *Manager) List() []Job {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	result := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		result = append(result, j.snapshot())
+	}
+
+	sortJobs(result)
+
+	return result
+}
+
+func (m *Manager) Abort(id string) (Job, error) {
+	m.mutex.RLock()
+	j, found := m.jobs[id]
+	m.mutex.RUnlock()
+
+	if !found {
+		return Job{}, fmt.Errorf("%w: %v", ErrJobNotFound, id)
+	}
+
+	// non blocking, the job may already be stopping
+	select {
+	case j.stopChan <- true:
+	default:
+	}
+
+	return j.snapshot(), nil
+}
+
+func sortJobs(jobs []Job) {
+	sort.Slice(jobs, func(i, k int) bool {
+		if jobs[i].Created.Equal(jobs[k].Created) {
+			return jobs[i].ID > jobs[k].ID
+		}
+		return jobs[i].Created.After(jobs[k].Created)
+	})
+}
+
+func (m *Manager) newJob(req Request) *job {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.seq++
+	now := time.Now()
+
+	j := &job{
+		mutex: &sync.RWMutex{},
+		Job: Job{
+			ID:        fmt.Sprintf("job-%v-%v", now.Unix(), m.seq),
+			Scheduler: req.Filter.SchedulerName,
+			Action:    req.Action,
+			Offset:    req.Offset,
+			DryRun:    req.DryRun,
+			Status:    PendingStatus,
+			Created:   now,
+		},
+		stopChan: make(chan bool, 1),
+	}
+
+	m.jobs[j.ID] = j
+	m.evict()
+
+	return j
+}
+
+// removes the oldest finished jobs when there are too many
+func (m *Manager) evict() {
+	if len(m.jobs) <= MaxJobs {
+		return
+	}
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.snapshot())
+	}
+	sortJobs(jobs)
+
+	for i := len(jobs) - 1; i >= 0 && len(m.jobs) > MaxJobs; i-- {
+		if jobs[i].finished() {
+			delete(m.jobs, jobs[i].ID)
+		}
+	}
+}
+
+// search returns all the schedules matching the filter of the request
+func (m *Manager) search(req Request) ([]producer.Schedule, error) {
+	_, list, err := m.ColdDB.Search(db.SearchQuery{
+		Filter: req.Filter,
+		Limit: db.Limit{
+			Max: -1,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []producer.Schedule{}
+	for sch := range list {
+		s, err := producer.FromSchedule(sch)
+		if err != nil {
+			log.Errorf("cannot convert schedule %v: %v", sch.ID(), err)
+			continue
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+func (m *Manager) dryRun(j *job, req Request) {
+	schs, err := m.search(req)
+
+	j.update(func(job *Job) {
+		now := time.Now()
+		job.Started = &now
+		job.Ended = &now
+
+		if err != nil {
+			job.Status = FailedStatus
+			job.Errors = append(job.Errors, err.Error())
+			return
+		}
+
+		job.Status = DoneStatus
+		job.Total = len(schs)
+		job.Targets = make([]Target, len(schs))
+		for i, s := range schs {
+			job.Targets[i] = Target{
+				ID:    s.ID,
+				Epoch: s.Epoch,
+			}
+			if req.Action == RescheduleAction {
+				job.Targets[i].NewEpoch = s.Epoch + req.Offset
+			}
+		}
+	})
+}
+
+func (m *Manager) run(j *job, req Request) {
+	j.update(func(job *Job) {
+		now := time.Now()
+		job.Started = &now
+		job.Status = RunningStatus
+	})
+
+	end := func(status Status) {
+		j.update(func(job *Job) {
+			now := time.Now()
+			job.Ended = &now
+			job.Status = status
+		})
+		log.Printf("bulk job %v ended: %+v", j.ID, j.snapshot())
+	}
+
+	schs, err := m.search(req)
+	if err != nil {
+		j.update(func(job *Job) {
+			job.Errors = append(job.Errors, err.Error())
+		})
+		end(FailedStatus)
+		return
+	}
+
+	j.update(func(job *Job) {
+		job.Total = len(schs)
+	})
+
+	for _, s := range schs {
+		select {
+		case <-j.stopChan:
+			end(AbortedStatus)
+			return
+		default:
+		}
+
+		var err error
+		var after *producer.Schedule
+		switch req.Action {
+		case CancelAction:
+			_, err = m.Producer.Cancel(req.Filter.SchedulerName, s.ID)
+		case RescheduleAction:
+			rescheduled := s
+			rescheduled.Epoch += req.Offset
+			after = &rescheduled
+			_, err = m.Producer.Produce(req.Filter.SchedulerName, rescheduled)
+		}
+		if req.Processed != nil {
+			req.Processed(j.ID, s, after, err)
+		}
+
+		j.update(func(job *Job) {
+			job.Processed++
+			if err != nil {
+				job.Failed++
+				if len(job.Errors) < MaxErrors {
+					job.Errors = append(job.Errors, fmt.Sprintf("%v: %v", s.ID, err))
+				}
+			}
+		})
+	}
+
+	end(DoneStatus)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,6 +39,53 @@ func getStrings(name string, defaultValue []string) []string {
 	return defaultValue
 }
 
+func getInt(name string, defaultValue int) int {
+	value, set := os.LookupEnv(name)
+	if !set {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		log.Warnf("%v: invalid value ignored: %v", name, value)
+		return defaultValue
+	}
+	return i
+}
+
+func getDuration(name string, defaultValue time.Duration) time.Duration {
+	value, set := os.LookupEnv(name)
+	if !set {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		log.Warnf("%v: invalid value ignored: %v", name, value)
+		return defaultValue
+	}
+	return d
+}
+
+func getChains(name string) map[string][]string {
+	result := make(map[string][]string)
+	for _, chain := range getStrings(name, nil) {
+		if chain == "" {
+			continue
+		}
+		i := strings.Index(chain, ":")
+		if i < 0 {
+			log.Warnf("%v: invalid value ignored: %v", name, chain)
+			continue
+		}
+		key := strings.TrimSpace(chain[:i])
+		for _, v := range strings.Split(chain[i+1:], "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				result[key] = append(result[key], v)
+			}
+		}
+	}
+	return result
+}
+
 func LogLevel() log.Level {
 	lvl, err := log.ParseLevel(getString("LOG_LEVEL", "info"))
 	if err != nil {
@@ -71,6 +119,36 @@ func SchedulersAddr() []string {
 	return getStrings("SCHEDULERS_ADDR", []string{"localhost:8000"})
 }
 
+// comma separated list of the resolvers of the schedulers: dns (SCHEDULERS_ADDR), file (SCHEDULERS_FILE) and/or kubernetes
+func SchedulersResolvers() []string {
+	return getStrings("SCHEDULERS_RESOLVERS", []string{"dns"})
+}
+
+// YAML or JSON file with the definitions of the schedulers, used by the file resolver
+func SchedulersFile() string {
+	return getString("SCHEDULERS_FILE", "")
+}
+
+// namespace of the schedulers services for the kubernetes resolver, default is the namespace of the pod
+func SchedulersK8SNamespace() string {
+	return getString("SCHEDULERS_K8S_NAMESPACE", "")
+}
+
+// label selector of the schedulers services for the kubernetes resolver
+func SchedulersK8SLabelSelector() string {
+	return getString("SCHEDULERS_K8S_LABEL_SELECTOR", "app=kafka-message-scheduler")
+}
+
+// name of the http port of the schedulers services for the kubernetes resolver
+func SchedulersK8SPortName() string {
+	return getString("SCHEDULERS_K8S_PORT_NAME", "http")
+}
+
+// when true, the kubernetes resolver watches the Endpoints instead of the EndpointSlices (kubernetes < 1.21)
+func SchedulersK8SEndpoints() bool {
+	return getBool("SCHEDULERS_K8S_ENDPOINTS", false)
+}
+
 func StaticFilesDir() string {
 	dir := getString("STATIC_FILES_DIR", "../client/build")
 	return dir
@@ -80,15 +158,86 @@ func APIServerOnly() bool {
 	return getBool("API_SERVER_ONLY", false)
 }
 
-// URL for the http decoder can start with http:// or not
+// URL of the decoder service, http:// (default) or https:// for the http decoder, grpc:// or grpcs:// (TLS) for the gRPC decoder
 func KafkaMessageBodyDecoder() string {
 	u := strings.ToLower(getString("KAFKA_MESSAGE_BODY_DECODER", ""))
-	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
-		return u
+	for _, scheme := range []string{"http://", "https://", "grpc://", "grpcs://"} {
+		if strings.HasPrefix(u, scheme) {
+			return u
+		}
 	}
 	return "http://" + u
 }
 
+// true when KAFKA_MESSAGE_BODY_DECODER is set, KafkaMessageBodyDecoder returns http:// otherwise
+func KafkaMessageBodyDecoderSet() bool {
+	return getString("KAFKA_MESSAGE_BODY_DECODER", "") != ""
+}
+
+// deadline of the requests to the gRPC decoder service
+func KafkaMessageBodyDecoderTimeout() time.Duration {
+	return getDuration("KAFKA_MESSAGE_BODY_DECODER_TIMEOUT", time.Second)
+}
+
+// comma separated list of the decoders of the kafka message bodies, tried in order: avro, protobuf, jsonschema, lua and/or http
+// (the decoder service KAFKA_MESSAGE_BODY_DECODER, http or gRPC), default is http when KAFKA_MESSAGE_BODY_DECODER is set
+func Decoders() []string {
+	var defaultValue []string
+	if KafkaMessageBodyDecoderSet() {
+		defaultValue = []string{"http"}
+	}
+	return getStrings("DECODERS", defaultValue)
+}
+
+// decoders by scheduler, with the format scheduler:decoder|decoder,scheduler:decoder
+func DecodersByScheduler() map[string][]string {
+	return getChains("DECODERS_BY_SCHEDULER")
+}
+
+// decoders by target topic, with the format topic:decoder|decoder,topic:decoder
+func DecodersByTopic() map[string][]string {
+	return getChains("DECODERS_BY_TOPIC")
+}
+
+// URL of the schema registry used by the avro, protobuf and jsonschema decoders
+func SchemaRegistryURL() string {
+	return getString("SCHEMA_REGISTRY_URL", "")
+}
+
+// directory of the local schema files, named with the schema id: 1.avsc, 2.proto or 3.json
+func SchemaRegistryDir() string {
+	return getString("SCHEMA_REGISTRY_DIR", "")
+}
+
+// directory of the scripts of the lua decoder, scheduler-1.lua for the scheduler-1, default.lua for the other schedulers
+func LuaScriptsDir() string {
+	return getString("LUA_SCRIPTS_DIR", "")
+}
+
+// maximum duration of a call of a lua script
+func LuaScriptTimeout() time.Duration {
+	return getDuration("LUA_SCRIPT_TIMEOUT", 100*time.Millisecond)
+}
+
+// number of goroutines decoding the kafka messages, the messages of a schedule are decoded in order
+func DecoderWorkers() int {
+	return getInt("DECODER_WORKERS", 8)
+}
+
+// number of decoded message bodies kept in memory, 0 disables the cache
+func DecoderCacheSize() int {
+	return getInt("DECODER_CACHE_SIZE", 10000)
+}
+
+// number of consecutive failures of a decoder before it is not called anymore during DECODER_BREAKER_COOLDOWN
+func DecoderBreakerThreshold() int {
+	return getInt("DECODER_BREAKER_THRESHOLD", 5)
+}
+
+func DecoderBreakerCooldown() time.Duration {
+	return getDuration("DECODER_BREAKER_COOLDOWN", 30*time.Second)
+}
+
 func DataRootDir() string {
 	dir := getString("DATA_ROOT_DIR", "./.db")
 	if !strings.HasSuffix(dir, "/") {
@@ -96,3 +245,103 @@ func DataRootDir() string {
 	}
 	return dir
 }
+
+// comma separated list of the origins allowed for the cross-origin requests, empty means same origin only
+func CORSAllowedOrigins() []string {
+	return getStrings("CORS_ALLOWED_ORIGINS", nil)
+}
+
+// file with the roles of the users, a line per user with the format user:role@scheduler,role@scheduler
+func AuthRolesFile() string {
+	return getString("AUTH_ROLES_FILE", "")
+}
+
+// file with the static API tokens, a line per token with the format user:token
+func AuthTokensFile() string {
+	return getString("AUTH_TOKENS_FILE", "")
+}
+
+// htpasswd file for the http basic authentication (bcrypt or SHA1)
+func AuthHtpasswdFile() string {
+	return getString("AUTH_HTPASSWD_FILE", "")
+}
+
+// JWKS file with the public keys used to verify the JWT bearer tokens
+func AuthJWKSFile() string {
+	return getString("AUTH_JWKS_FILE", "")
+}
+
+func AuthJWTIssuer() string {
+	return getString("AUTH_JWT_ISSUER", "")
+}
+
+func AuthJWTAudience() string {
+	return getString("AUTH_JWT_AUDIENCE", "")
+}
+
+func AuthJWTUserClaim() string {
+	return getString("AUTH_JWT_USER_CLAIM", "sub")
+}
+
+func AuthJWTRolesClaim() string {
+	return getString("AUTH_JWT_ROLES_CLAIM", "roles")
+}
+
+// YAML or JSON file with the masking rules of the schedules returned by the API, empty means no masking
+func MaskingRulesFile() string {
+	return getString("MASKING_RULES_FILE", "")
+}
+
+// delay after the epoch from which a triggered schedule is late and a schedule not triggered is overdue
+func ScheduleLateThreshold() time.Duration {
+	return getDuration("SCHEDULE_LATE_THRESHOLD", 10*time.Second)
+}
+
+// retention of the history topic, the tombstones of the cancelled schedules older than it are purged, 0 means no purge
+func HistoryRetention() time.Duration {
+	return getDuration("HISTORY_RETENTION", 7*24*time.Hour)
+}
+
+// interval between the checks of the overdue schedules
+func OverdueCheckInterval() time.Duration {
+	return getDuration("OVERDUE_CHECK_INTERVAL", time.Minute)
+}
+
+// delay after the epoch from which a schedule not triggered is reported by the overdue checker
+func OverdueGracePeriod() time.Duration {
+	return getDuration("OVERDUE_GRACE_PERIOD", 5*time.Minute)
+}
+
+// url where the new overdue schedules are posted, empty means no webhook
+func OverdueWebhookURL() string {
+	return getString("OVERDUE_WEBHOOK_URL", "")
+}
+
+// interval between the commits of the offsets consumed by the cold and history DBs
+func OffsetsCommitInterval() time.Duration {
+	return getDuration("OFFSETS_COMMIT_INTERVAL", 5*time.Second)
+}
+
+// delay for the cold and history DBs to store the consumed events on shutdown, the pending events are discarded after it
+func ShutdownTimeout() time.Duration {
+	return getDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+}
+
+// properties file of the kafka consumers of the cold and history DBs (security.protocol, sasl.mechanisms, ssl.ca.location ...)
+func KafkaPropertiesFile() string {
+	return getString("KAFKA_PROPERTIES_FILE", "")
+}
+
+// properties files of the kafka consumers by scheduler, with the format scheduler:file|file, they override KAFKA_PROPERTIES_FILE
+func KafkaPropertiesFilesByScheduler() map[string][]string {
+	return getChains("KAFKA_PROPERTIES_FILES_BY_SCHEDULER")
+}
+
+// kafka topic where the audit entries are mirrored, empty means no mirror
+func AuditKafkaTopic() string {
+	return getString("AUDIT_KAFKA_TOPIC", "")
+}
+
+func AuditKafkaBootstrapServers() string {
+	return getString("AUDIT_KAFKA_BOOTSTRAP_SERVERS", "localhost:9092")
+}
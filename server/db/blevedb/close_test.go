@@ -0,0 +1,190 @@
+package blevedb_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/blevedb"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	"github.com/etf1/kafka-message-scheduler/schedule/simple"
+)
+
+// source is a watchable store fed and closed by the test
+type source chan store.Event
+
+func (s source) Watch() (chan store.Event, error) {
+	return s, nil
+}
+
+// Rule #8: close should store and acknowledge the events received before, whether the source store is closed or not
+func TestBleveDB_Close(t *testing.T) {
+	count := 5000
+
+	tests := []struct {
+		closeSource bool
+	}{
+		{true},
+		{false},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			dir := t.TempDir()
+
+			bs, err := bbolt.NewStore(filepath.Join(dir, "schedules.bbolt"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer bs.Close()
+
+			src := source(make(chan store.Event, count))
+			bdb, err := blevedb.NewDB(blevedb.Config{
+				SourceStore:   src,
+				InternalStore: bs,
+				Path:          filepath.Join(dir, "schedules.bleve"),
+				Name:          "cold",
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			acked := int32(0)
+			now := time.Now().Unix()
+			for j := 0; j < count; j++ {
+				src <- store.Event{
+					EventType: store.UpsertType,
+					Schedule: store.Schedule{
+						SchedulerName: "scheduler-1",
+						Schedule:      simple.NewSchedule(fmt.Sprintf("schedule-%v", j), now),
+					},
+					Ack: func() {
+						atomic.AddInt32(&acked, 1)
+					},
+				}
+			}
+			if tt.closeSource {
+				close(src)
+			}
+
+			if err := bdb.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			lst, err := bs.List("scheduler-1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			stored := 0
+			for range lst {
+				stored++
+			}
+			if stored != count {
+				t.Errorf("unexpected number of schedules: %v", stored)
+			}
+			if n := atomic.LoadInt32(&acked); int(n) != count {
+				t.Errorf("unexpected number of acknowledged events: %v", n)
+			}
+		})
+	}
+}
+
+// Rule #9: the index of a closed DB should be opened again on the same path
+func TestBleveDB_reopen(t *testing.T) {
+	dir := t.TempDir()
+
+	bs, err := bbolt.NewStore(filepath.Join(dir, "schedules.bbolt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer bs.Close()
+
+	for i := 0; i < 2; i++ {
+		src := source(make(chan store.Event))
+		bdb, err := blevedb.NewDB(blevedb.Config{
+			SourceStore:   src,
+			InternalStore: bs,
+			Path:          filepath.Join(dir, "schedules.bleve"),
+			Name:          "cold",
+		})
+		if err != nil {
+			t.Fatalf("run #%v: unexpected error: %v", i+1, err)
+		}
+		close(src)
+		if err := bdb.Close(); err != nil {
+			t.Fatalf("run #%v: unexpected error: %v", i+1, err)
+		}
+	}
+}
+
+// Rule #10: the index with an outdated mapping should be rebuilt on open
+func TestBleveDB_outdated_mapping(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedules.bleve")
+
+	bs, err := bbolt.NewStore(filepath.Join(dir, "schedules.bbolt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer bs.Close()
+
+	open := func() {
+		src := source(make(chan store.Event))
+		bdb, err := blevedb.NewDB(blevedb.Config{
+			SourceStore:   src,
+			InternalStore: bs,
+			Path:          path,
+			Name:          "cold",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(src)
+		if err := bdb.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	outdated := func() bool {
+		result, err := blevedb.Outdated(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	// no index
+	if outdated() {
+		t.Errorf("unexpected outdated index")
+	}
+
+	open()
+	if outdated() {
+		t.Errorf("unexpected outdated index")
+	}
+
+	// another version, then no version: created before the versioning of the mapping
+	for _, remove := range []bool{false, true} {
+		versionFile := filepath.Join(path, "mapping.version")
+		err = os.WriteFile(versionFile, []byte(strconv.Itoa(blevedb.MappingVersion+1)), 0600)
+		if remove {
+			err = os.Remove(versionFile)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !outdated() {
+			t.Errorf("expected outdated index")
+		}
+
+		open()
+		if outdated() {
+			t.Errorf("unexpected outdated index")
+		}
+	}
+}
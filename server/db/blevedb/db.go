@@ -1,15 +1,19 @@
 package blevedb
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/search/query"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
 	"github.com/etf1/kafka-message-scheduler-admin/server/sort"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
 	"github.com/etf1/kafka-message-scheduler/schedule"
 	log "github.com/sirupsen/logrus"
 )
@@ -25,32 +29,56 @@ type DB struct {
 	sourceStore store.Watchable
 	idxr        *indexer
 	updtr       updater
+	name        string
+	// stops the watch of the source store
+	stop context.CancelFunc
+	// receives the error of the flush of the internal store and the index, when the watch exits
+	done chan error
 }
 
 type Config struct {
 	InternalStore store.BatchableStore
 	SourceStore   store.Watchable
 	Path          string
+	// name of the db in the metrics (cold, history)
+	Name string
+	// optional, the watch of the source store stops when the context is done
+	Context context.Context
 }
 
 func NewDB(cfg Config) (DB, error) {
-	idxr, err := newIndexer(cfg.Path)
+	idxr, err := newIndexer(cfg.Path, cfg.Name)
 	if err != nil {
 		return DB{}, err
 	}
+	// watch before starting the goroutines, so no event is missed
+	watchChan, err := cfg.SourceStore.Watch()
+	if err != nil {
+		return DB{}, fmt.Errorf("cannot get watch channel: %w", err)
+	}
+
 	go idxr.start()
 
 	updtr := newUpdater(cfg.InternalStore)
 	go updtr.start()
 
+	parent := cfg.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, stop := context.WithCancel(parent)
+
 	d := DB{
 		cfg.InternalStore,
 		cfg.SourceStore,
 		idxr,
 		updtr,
+		cfg.Name,
+		stop,
+		make(chan error, 1),
 	}
 
-	go d.watch()
+	go d.watch(ctx, watchChan)
 
 	return d, nil
 }
@@ -62,16 +90,37 @@ func toDocument(sch schedule.Schedule) document {
 		return document{}
 	}
 
-	// TODO: manage kafka.Schedule
+	fields := db.GetFields(s)
+
 	return document{
-		ID:        s.ID(),
-		SortID:    s.ID(),
-		Scheduler: s.SchedulerName,
-		Epoch:     s.Epoch(),
-		Timestamp: s.Timestamp(),
+		ID:          s.ID(),
+		SortID:      s.ID(),
+		Scheduler:   s.SchedulerName,
+		Epoch:       s.Epoch(),
+		Timestamp:   s.Timestamp(),
+		Topic:       fields.Topic,
+		TargetTopic: fields.TargetTopic,
+		TargetKey:   fields.TargetKey,
+		Value:       fields.Value,
+		Fields:      fields.Extra,
+		Partition:   fields.Partition,
+		Offset:      fields.Offset,
+		Headers:     headers(fields.Headers),
 	}
 }
 
+// headers returns the values of the headers by name, the last header wins when a name is repeated
+func headers(hs []bbolt.Header) map[string]string {
+	if len(hs) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(hs))
+	for _, h := range hs {
+		result[h.Key] = h.Value
+	}
+	return result
+}
+
 // create an id for bleve, we are using a composite key with scheduler name
 // in case of there will be a same schedule id for two different scheduler
 func bleveID(sch schedule.Schedule) string {
@@ -83,67 +132,129 @@ func bleveID(sch schedule.Schedule) string {
 	}
 }
 
-func (d DB) upsert(sch store.Schedule) {
+// acknowledgement returns the acknowledgement of the event by the updater and the indexer,
+// the event is acknowledged once both of them persisted it
+func acknowledgement(evt store.Event) func() {
+	if evt.Ack == nil {
+		return nil
+	}
+	remaining := int32(2)
+	return func() {
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			evt.Ack()
+		}
+	}
+}
+
+func (d DB) upsert(sch store.Schedule, ack func()) {
 	// upsert in bbolt store
-	d.updtr.upsert(sch.ID(), sch)
+	d.updtr.upsert(sch.ID(), sch, ack)
 
 	// upsert in bleve index
-	d.idxr.upsert(bleveID(sch), toDocument(sch))
+	d.idxr.upsert(bleveID(sch), toDocument(sch), ack)
 }
 
-func (d DB) delete(sch schedule.Schedule) {
+func (d DB) delete(sch schedule.Schedule, ack func()) {
 	// delete in bbolt store
-	d.updtr.delete(sch.ID(), sch)
+	d.updtr.delete(sch.ID(), sch, ack)
 
 	// delete in bleve index
-	d.idxr.delete(bleveID(sch))
+	d.idxr.delete(bleveID(sch), ack)
 }
 
-func (d DB) watch() {
-	defer log.Printf("watcher closed")
+// watch applies the events of the source store until it is closed or the context is done, then the pending
+// batches of the internal store and the index are flushed
+func (d DB) watch(ctx context.Context, watchChan chan store.Event) {
+	defer func() {
+		d.done <- d.flush()
+		close(d.done)
+		log.Printf("watcher closed")
+	}()
 
-	watchChan, err := d.sourceStore.Watch()
-	if err != nil {
-		log.Errorf("cannot get watch channel: %v", err)
-	}
-
-	for evt := range watchChan {
-		log.Printf("received watch event from store: %+v", evt)
-
-		switch evt.EventType {
-		case store.UpsertType:
-			log.Printf("received upsert watch event from store: %+v", evt)
-			d.upsert(evt.Schedule)
-		case store.DeletedType:
-			log.Printf("received delete watch event from store: %+v", evt)
-			d.delete(evt.Schedule)
-		// the store has been reset need to delete all data
-		case store.StoreResetType:
-			schedulerName := evt.Schedule.SchedulerName
-			log.Printf("received store reset watch event from store for %v: %+v", schedulerName, evt)
-			// search all schedules for the reseted scheduler
-			_, list, err := d.Search(db.SearchQuery{
-				Filter: db.Filter{
-					SchedulerName: schedulerName,
-				},
-				Limit: db.Limit{
-					Max: -1,
-				},
-			})
-			if err != nil {
-				log.Errorf("cannot find all schedules for %v: %v", schedulerName, err)
-				break
+	for {
+		select {
+		case evt, ok := <-watchChan:
+			if !ok {
+				return
 			}
-			// delete all schedules for the specified scheduler
-			for sch := range list {
-				d.delete(sch)
+			d.apply(evt)
+		case <-ctx.Done():
+			// the events already received are applied
+			for {
+				select {
+				case evt, ok := <-watchChan:
+					if !ok {
+						return
+					}
+					d.apply(evt)
+				default:
+					return
+				}
 			}
 		}
 	}
 }
 
-func (d DB) Close() {
-	d.idxr.close()
+// flush closes the updater and the indexer and waits for their last batches
+func (d DB) flush() error {
+	close(d.updtr.input)
+	close(d.idxr.input)
+
+	updtrErr := <-d.updtr.done
+	idxrErr := <-d.idxr.done
+
+	switch {
+	case updtrErr != nil && idxrErr != nil:
+		return fmt.Errorf("cannot flush %v db: %v, %w", d.name, updtrErr, idxrErr)
+	case updtrErr != nil:
+		return fmt.Errorf("cannot flush %v db: %w", d.name, updtrErr)
+	case idxrErr != nil:
+		return fmt.Errorf("cannot flush %v db: %w", d.name, idxrErr)
+	}
+	return nil
+}
+
+// apply stores and indexes the event of the source store
+func (d DB) apply(evt store.Event) {
+	log.Printf("received watch event from store: %+v", evt)
+	metrics.WatchEvents.WithLabelValues(d.name, evt.SchedulerName, evt.EventType.String()).Inc()
+
+	switch evt.EventType {
+	case store.UpsertType:
+		log.Printf("received upsert watch event from store: %+v", evt)
+		d.upsert(evt.Schedule, acknowledgement(evt))
+	case store.DeletedType:
+		log.Printf("received delete watch event from store: %+v", evt)
+		d.delete(evt.Schedule, acknowledgement(evt))
+	// the store has been reset need to delete all data
+	case store.StoreResetType:
+		schedulerName := evt.Schedule.SchedulerName
+		log.Printf("received store reset watch event from store for %v: %+v", schedulerName, evt)
+		// search all schedules for the reseted scheduler
+		_, list, err := d.Search(db.SearchQuery{
+			Filter: db.Filter{
+				SchedulerName: schedulerName,
+			},
+			Limit: db.Limit{
+				Max: -1,
+			},
+		})
+		if err != nil {
+			log.Errorf("cannot find all schedules for %v: %v", schedulerName, err)
+			break
+		}
+		// delete all schedules for the specified scheduler
+		for sch := range list {
+			d.delete(sch, nil)
+		}
+	}
+}
+
+// Close stops the watch of the source store and waits for the events received before to be stored and indexed,
+// it returns the errors of the last batches
+func (d DB) Close() error {
+	d.stop()
+	return <-d.done
 }
 
 func toBleveSort(s sort.By) []string {
@@ -220,9 +331,51 @@ func toBleveStringQuery(q db.SearchQuery) string {
 		squery = appendQuery(squery, "epoch", false, "+"+fmt.Sprintf("<=%v", q.Filter.EpochRange.To))
 	}
 
+	if q.Filter.TargetTopic != "" {
+		squery = appendQuery(squery, "target-topic", true, "+"+q.Filter.TargetTopic)
+	}
+
+	if q.Filter.TargetKey != "" {
+		squery = appendQuery(squery, "target-key", true, "+"+q.Filter.TargetKey)
+	}
+
+	if q.Filter.Value != "" {
+		squery = appendQuery(squery, "value", true, q.Filter.Value)
+	}
+
+	if q.Filter.MessagePartition != nil {
+		squery = appendQuery(squery, "partition", false, fmt.Sprintf("+>=%v +<=%v", *q.Filter.MessagePartition, *q.Filter.MessagePartition))
+	}
+
+	if q.Filter.MessageOffset != nil {
+		squery = appendQuery(squery, "offset", false, fmt.Sprintf("+>=%v +<=%v", *q.Filter.MessageOffset, *q.Filter.MessageOffset))
+	}
+
 	return squery
 }
 
+// toBleveKeywordQueries returns a term query on the whole value for each field and header of the filter,
+// the values are keywords: they are not split on whitespaces like the terms of the string query
+func toBleveKeywordQueries(q db.SearchQuery) []query.Query {
+	result := []query.Query{}
+
+	appendTerm := func(field, value string) {
+		tq := bleve.NewTermQuery(value)
+		tq.SetField(field)
+		result = append(result, tq)
+	}
+
+	for _, name := range q.Filter.FieldNames() {
+		appendTerm("fields."+name, q.Filter.Fields[name])
+	}
+
+	for _, name := range q.Filter.HeaderNames() {
+		appendTerm("headers."+name, q.Filter.Headers[name])
+	}
+
+	return result
+}
+
 func (d DB) Search(q db.SearchQuery) (total int, result chan schedule.Schedule, err error) {
 	result = make(chan schedule.Schedule, ChanSize)
 
@@ -241,6 +394,8 @@ func (d DB) Search(q db.SearchQuery) (total int, result chan schedule.Schedule,
 	sortBy := toBleveSort(q.SortBy)
 	queryString := toBleveStringQuery(q)
 
+	keywordQueries := toBleveKeywordQueries(q)
+
 	// bleve search
 	var searchQuery query.Query
 	if queryString == "" {
@@ -248,13 +403,19 @@ func (d DB) Search(q db.SearchQuery) (total int, result chan schedule.Schedule,
 	} else {
 		searchQuery = bleve.NewQueryStringQuery(queryString)
 	}
+	if len(keywordQueries) > 0 {
+		searchQuery = bleve.NewConjunctionQuery(append([]query.Query{searchQuery}, keywordQueries...)...)
+	}
 
 	search := bleve.NewSearchRequest(searchQuery)
 	search.SortBy(sortBy)
 	search.Size = max
 	search.Fields = fields
+	if q.Limit.Offset > 0 {
+		search.From = q.Limit.Offset
+	}
 
-	log.Warnf("search query='%v' max=%v sort=%v", queryString, max, sortBy)
+	log.Warnf("search query='%v' max=%v offset=%v sort=%v", queryString, max, q.Limit.Offset, sortBy)
 	start := time.Now()
 	searchResults, err := d.idxr.Search(search)
 	if err != nil {
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db/blevedb"
 	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
@@ -487,3 +489,113 @@ func TestBleveDBSearch_delete(t *testing.T) {
 		})
 	}
 }
+
+// Rule #6: search by target topic, target key and value should filter the result list
+func TestBleveDBSearch_by_target_and_value(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	data, bdb, clean := initDB(t)
+	defer clean()
+
+	now := time.Now()
+	data.Add("scheduler-1", helper.NewKafkaSchedule("schedules", "schedule-1", `{"title":"Hello World"}`, now.Unix(), "videos", "video-1"))
+	data.Add("scheduler-1", helper.NewKafkaSchedule("schedules", "schedule-2", `{"title":"Goodbye World"}`, now.Add(1*time.Second).Unix(), "videos", "video-2"))
+	data.Add("scheduler-1", helper.NewKafkaSchedule("schedules", "schedule-3", `{"title":"Hello"}`, now.Add(2*time.Second).Unix(), "programs", "video-1"))
+
+	// wait for goroutines to be scheduled
+	time.Sleep(1 * time.Second)
+
+	tests := []struct {
+		filter      db.Filter
+		expectedIDs []string
+	}{
+		{db.Filter{TargetTopic: "videos"}, []string{"schedule-1", "schedule-2"}},
+		{db.Filter{TargetKey: "video-1"}, []string{"schedule-1", "schedule-3"}},
+		{db.Filter{TargetTopic: "videos", TargetKey: "video-1"}, []string{"schedule-1"}},
+		{db.Filter{TargetTopic: "vid*"}, []string{"schedule-1", "schedule-2"}},
+		{db.Filter{TargetTopic: "unknown"}, []string{}},
+		{db.Filter{Value: "hello"}, []string{"schedule-1", "schedule-3"}},
+		{db.Filter{Value: "hello world"}, []string{"schedule-1"}},
+		{db.Filter{Value: "world -goodbye"}, []string{"schedule-1"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			_, lst, err := bdb.Search(db.SearchQuery{
+				Filter: tt.filter,
+				SortBy: sort.By{Field: sort.ID, Order: sort.Asc},
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			index := 0
+			for s := range lst {
+				if index >= len(tt.expectedIDs) {
+					t.Fatalf("unexpected result length > %v", len(tt.expectedIDs))
+				}
+				if tt.expectedIDs[index] != s.ID() {
+					t.Errorf("unexpected id: %v", s.ID())
+				}
+				index++
+			}
+
+			if index != len(tt.expectedIDs) {
+				t.Errorf("unexpected result length: %v", index)
+			}
+		})
+	}
+}
+
+// Rule #7: search by partition, offset and headers of the kafka message should filter the result list
+func TestBleveDBSearch_by_message_metadata(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	data, bdb, clean := initDB(t)
+	defer clean()
+
+	now := time.Now()
+	for i, partition := range []int32{0, 1, 1} {
+		sch := helper.NewKafkaSchedule("schedules", fmt.Sprintf("schedule-%v", i+1), "value", now.Add(time.Duration(i)*time.Second).Unix(), "videos", fmt.Sprintf("video-%v", i+1))
+		sch.TopicPartition.Partition = partition
+		sch.TopicPartition.Offset = confluent.Offset(10 + i)
+		data.Add("scheduler-1", sch)
+	}
+
+	// wait for goroutines to be scheduled
+	time.Sleep(1 * time.Second)
+
+	partition := func(p int32) *int32 { return &p }
+	offset := func(o int64) *int64 { return &o }
+
+	tests := []struct {
+		filter      db.Filter
+		expectedIDs []string
+	}{
+		{db.Filter{MessagePartition: partition(1)}, []string{"schedule-2", "schedule-3"}},
+		{db.Filter{MessagePartition: partition(1), MessageOffset: offset(12)}, []string{"schedule-3"}},
+		{db.Filter{MessageOffset: offset(13)}, []string{}},
+		{db.Filter{Headers: map[string]string{"scheduler-target-key": "video-2"}}, []string{"schedule-2"}},
+		{db.Filter{Headers: map[string]string{"scheduler-target-topic": "videos"}}, []string{"schedule-1", "schedule-2", "schedule-3"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			_, lst, err := bdb.Search(db.SearchQuery{
+				Filter: tt.filter,
+				SortBy: sort.By{Field: sort.ID, Order: sort.Asc},
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			ids := []string{}
+			for s := range lst {
+				ids = append(ids, s.ID())
+			}
+			if !reflect.DeepEqual(ids, tt.expectedIDs) {
+				t.Errorf("unexpected ids: %v", ids)
+			}
+		})
+	}
+}
@@ -1,17 +1,29 @@
 package blevedb
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
 	"github.com/blevesearch/bleve/v2/analysis/analyzer/simple"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
 	batchSize = 1000
+	// MappingVersion is the version of the mapping of the indexes, to be incremented on every change of the mapping:
+	// the indexes created with another version are rebuilt
+	MappingVersion = 1
+	// file of the index containing the version of its mapping
+	mappingVersionFile = "mapping.version"
 )
 
 type eventType int
@@ -30,20 +42,32 @@ type document struct {
 	Topic       string `json:"topic"`
 	TargetTopic string `json:"target-topic"`
 	TargetKey   string `json:"target-key"`
+	Value       string `json:"value"`
+	// fields extracted by the decoder
+	Fields map[string]string `json:"fields,omitempty"`
+	// metadata of the kafka message
+	Partition *int32            `json:"partition,omitempty"`
+	Offset    *int64            `json:"offset,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
 }
 
 type event struct {
 	eventType
 	id   string
 	data interface{}
+	// acknowledgement of the event once persisted, nil when not needed
+	ack func()
 }
 
 type indexer struct {
 	input chan event
+	// receives the error of the last batch and of the close of the index, when the input is closed
+	done chan error
+	name string
 	bleve.Index
 }
 
-func newIndexer(path string) (*indexer, error) {
+func newIndexer(path, name string) (*indexer, error) {
 	// a generic reusable mapping for keyword text
 	keywordFieldMapping := bleve.NewTextFieldMapping()
 	keywordFieldMapping.Analyzer = keyword.Name
@@ -60,23 +84,92 @@ func newIndexer(path string) (*indexer, error) {
 	mapping.DefaultMapping.AddFieldMappingsAt("sort-id", keywordFieldMapping)
 	mapping.DefaultMapping.AddFieldMappingsAt("epoch", bleve.NewNumericFieldMapping())
 	mapping.DefaultMapping.AddFieldMappingsAt("timestamp", bleve.NewNumericFieldMapping())
+	mapping.DefaultMapping.AddFieldMappingsAt("topic", keywordFieldMapping)
+	mapping.DefaultMapping.AddFieldMappingsAt("target-topic", keywordFieldMapping)
+	mapping.DefaultMapping.AddFieldMappingsAt("target-key", keywordFieldMapping)
+	mapping.DefaultMapping.AddFieldMappingsAt("value", simpleFieldMapping)
 
-	index, err := bleve.New(path, mapping)
+	// the fields extracted by the decoder are keywords, whatever their names
+	fieldsMapping := bleve.NewDocumentMapping()
+	fieldsMapping.DefaultAnalyzer = keyword.Name
+	mapping.DefaultMapping.AddSubDocumentMapping("fields", fieldsMapping)
+
+	mapping.DefaultMapping.AddFieldMappingsAt("partition", bleve.NewNumericFieldMapping())
+	mapping.DefaultMapping.AddFieldMappingsAt("offset", bleve.NewNumericFieldMapping())
+
+	// the headers of the kafka message are keywords, whatever their names
+	headersMapping := bleve.NewDocumentMapping()
+	headersMapping.DefaultAnalyzer = keyword.Name
+	mapping.DefaultMapping.AddSubDocumentMapping("headers", headersMapping)
+
+	// the index of a previous run is opened, the consumers resume from its offsets,
+	// unless it has an outdated mapping: it is rebuilt, its offsets are invalidated by the runner
+	outdated, err := Outdated(path)
+	if err != nil {
+		return nil, err
+	}
+	if outdated {
+		log.Warnf("the mapping of %v is outdated, the index is rebuilt", path)
+		if err := os.RemoveAll(path); err != nil {
+			return nil, err
+		}
+	}
+
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = newIndex(path, mapping)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return &indexer{
 		make(chan event, MaxChanSize),
+		make(chan error, 1),
+		name,
 		index,
 	}, nil
 }
 
-func (i *indexer) close() {
-	close(i.input)
-	i.input = nil
+// newIndex creates the index with the version of its mapping
+func newIndex(path string, m mapping.IndexMapping) (bleve.Index, error) {
+	index, err := bleve.New(path, m)
+	if err != nil || path == "" {
+		return index, err
+	}
+
+	err = os.WriteFile(filepath.Join(path, mappingVersionFile), []byte(strconv.Itoa(MappingVersion)), 0600)
+	if err != nil {
+		index.Close()
+		return nil, fmt.Errorf("cannot write the mapping version of %v: %w", path, err)
+	}
+
+	return index, nil
+}
+
+// Outdated returns true when the index of the path was created with another version of the mapping,
+// or before the versioning of the mappings, false when there is no index
+func Outdated(path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, mappingVersionFile))
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return strings.TrimSpace(string(data)) != strconv.Itoa(MappingVersion), nil
 }
 
+// start indexes the events of the input by batches, the last batch is indexed and the index is closed
+// when the input is closed
 func (i indexer) start() {
 	defer log.Printf("indexer closed")
 
@@ -87,13 +180,25 @@ func (i indexer) start() {
 	counter := 0
 	batch := i.NewBatch()
 
+	// acknowledgements of the events of the batch, called once it is indexed
+	var acks []func()
+	// error of the last batch indexed
+	var batchErr error
+
 	indexBatch := func() {
 		log.Printf("batch indexing %v documents", counter)
-		err := i.Batch(batch)
-		if err != nil {
-			log.Printf("batch indexing failed : %v", err)
+		start := time.Now()
+		batchErr = i.Batch(batch)
+		metrics.IndexingBatchDuration.WithLabelValues(i.name).Observe(time.Since(start).Seconds())
+		if batchErr != nil {
+			log.Printf("batch indexing failed : %v", batchErr)
+		} else {
+			for _, ack := range acks {
+				ack()
+			}
 		}
 		batch = i.NewBatch()
+		acks = nil
 	}
 
 loop:
@@ -105,6 +210,12 @@ loop:
 			if !ok {
 				log.Printf("input channel closed")
 				indexBatch()
+				err := i.Close()
+				if batchErr != nil {
+					err = batchErr
+				}
+				i.done <- err
+				close(i.done)
 				break loop
 			}
 
@@ -132,9 +243,15 @@ loop:
 					log.Errorf("index batch failed: %v", err)
 					break
 				}
+				if evt.ack != nil {
+					acks = append(acks, evt.ack)
+				}
 			case deleteType:
 				log.Printf("batch delete with id: %v", evt.id)
 				batch.Delete(evt.id)
+				if evt.ack != nil {
+					acks = append(acks, evt.ack)
+				}
 			}
 			counter++
 			if counter%batchSize == 0 {
@@ -151,24 +268,20 @@ loop:
 	}
 }
 
-func (i indexer) upsert(id string, data document) {
-	if i.input == nil {
-		return
-	}
+func (i indexer) upsert(id string, data document, ack func()) {
 	i.input <- event{
 		upsertType,
 		id,
 		data,
+		ack,
 	}
 }
 
-func (i indexer) delete(id string) {
-	if i.input == nil {
-		return
-	}
+func (i indexer) delete(id string, ack func()) {
 	i.input <- event{
 		eventType: deleteType,
 		id:        id,
+		ack:       ack,
 	}
 }
 
@@ -1,6 +1,9 @@
 package blevedb
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler/schedule"
 	log "github.com/sirupsen/logrus"
@@ -13,32 +16,46 @@ const (
 
 type updater struct {
 	input chan event
+	// receives the first error of the batches of the internal store, when the input is closed
+	done chan error
 	store.BatchableStore
 }
 
 func newUpdater(bs store.BatchableStore) updater {
 	return updater{
 		make(chan event, MaxChanSize),
+		make(chan error, 1),
 		bs,
 	}
 }
 
+// start sends the events of the input to the batch of the internal store, it waits for the last batch
+// when the input is closed
 func (u updater) start() {
 	defer log.Printf("updater closed")
 
 	batchChan := make(chan store.Event, MaxBatchChanSize)
-	defer close(batchChan)
-
 	errChan := u.Batch(batchChan)
 
+	var firstErr error
+	errCount := 0
+	received := func(err error) {
+		log.Errorf("received error from batch: %v", err)
+		if firstErr == nil {
+			firstErr = err
+		}
+		errCount++
+	}
+
 loop:
 	for {
 		select {
 		case err, ok := <-errChan:
 			if !ok {
+				received(errors.New("batch exited"))
 				break loop
 			}
-			log.Errorf("received error from batch: %v", err)
+			received(err)
 		case evt, ok := <-u.input:
 			if !ok {
 				log.Printf("input channel closed")
@@ -54,36 +71,49 @@ loop:
 				batchChan <- store.Event{
 					EventType: store.UpsertType,
 					Schedule:  sch,
+					Ack:       evt.ack,
 				}
 			case deleteType:
 				log.Debugf("batch delete: %T %v", sch, sch)
 				batchChan <- store.Event{
 					EventType: store.DeletedType,
 					Schedule:  sch,
+					Ack:       evt.ack,
 				}
 			}
 		}
 	}
-}
 
-func (u updater) upsert(id string, s schedule.Schedule) {
-	if u.input == nil {
-		return
+	// the last batch is processed when its input is closed
+	close(batchChan)
+	for err := range errChan {
+		received(err)
 	}
+	// the events are discarded when the batch exited before the input was closed
+	for range u.input {
+	}
+
+	if errCount > 1 {
+		firstErr = fmt.Errorf("%w (%v errors)", firstErr, errCount)
+	}
+	u.done <- firstErr
+	close(u.done)
+}
+
+func (u updater) upsert(id string, s schedule.Schedule, ack func()) {
 	u.input <- event{
 		eventType: upsertType,
 		id:        id,
 		data:      s,
+		ack:       ack,
 	}
 }
 
-func (u updater) delete(id string, s schedule.Schedule) {
-	if u.input == nil {
-		return
-	}
+func (u updater) delete(id string, s schedule.Schedule, ack func()) {
 	u.input <- event{
 		eventType: deleteType,
 		id:        id,
 		data:      s,
+		ack:       ack,
 	}
 }
@@ -1,6 +1,8 @@
 package db
 
 import (
+	stdsort "sort"
+
 	"github.com/etf1/kafka-message-scheduler-admin/server/sort"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler/schedule"
@@ -21,7 +23,38 @@ type Filter struct {
 	SchedulerName string
 	ScheduleID    string
 	EpochRange
+	TargetTopic string
+	TargetKey   string
+	// words contained in the message body
+	Value string
+	// values of the fields extracted by the decoder, by field name
+	Fields map[string]string
+	// partition and offset of the kafka message, ignored when nil
+	MessagePartition *int32
+	MessageOffset    *int64
+	// values of the headers of the kafka message, by header name
+	Headers map[string]string
+}
+
+// FieldNames returns the names of the fields of the filter, sorted
+func (f Filter) FieldNames() []string {
+	return sortedKeys(f.Fields)
+}
+
+// HeaderNames returns the names of the headers of the filter, sorted
+func (f Filter) HeaderNames() []string {
+	return sortedKeys(f.Headers)
 }
+
+func sortedKeys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	stdsort.Strings(result)
+	return result
+}
+
 type EpochRange struct {
 	From int64
 	To   int64
@@ -29,4 +62,25 @@ type EpochRange struct {
 
 type Limit struct {
 	Max int
+	// number of schedules to skip, used for pagination
+	Offset int
+}
+
+// Count returns the number of schedules of the scheduler
+func Count(d DB, schedulerName string) (int, error) {
+	total, result, err := d.Search(SearchQuery{
+		Filter: Filter{
+			SchedulerName: schedulerName,
+		},
+		Limit: Limit{
+			Max: 1,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	// drain the results
+	for range result {
+	}
+	return total, nil
 }
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"unicode/utf8"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+)
+
+// Fields contains the fields of a schedule which are not exposed by the schedule interface
+type Fields struct {
+	Topic       string
+	TargetTopic string
+	TargetKey   string
+	// message body, decoded if a decoder is configured, empty when not valid UTF-8 (binary payload)
+	Value string
+	// raw message body
+	RawValue []byte
+	// partition and offset of the kafka message, nil when unknown
+	Partition *int32
+	Offset    *int64
+	// fields extracted from the message body by the decoder
+	Extra map[string]string
+	// headers and timestamp type of the kafka message, empty when unknown
+	Headers       []bbolt.Header
+	TimestampType string
+}
+
+// Header returns the value of the last header with the name, false when not found
+func (f Fields) Header(name string) (string, bool) {
+	for i := len(f.Headers) - 1; i >= 0; i-- {
+		if f.Headers[i].Key == name {
+			return f.Headers[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// GetFields extracts the searchable fields from a kafka, bbolt or rest schedule, decoded or not,
+// other types of schedules return empty fields
+func GetFields(sch schedule.Schedule) Fields {
+	if s, ok := sch.(store.Schedule); ok {
+		sch = s.Schedule
+	}
+
+	sch, _, extra := decoder.Unwrap(sch)
+
+	if s, ok := sch.(*kafka.Schedule); ok {
+		if s == nil {
+			return Fields{}
+		}
+		sch = *s
+	}
+
+	var result Fields
+
+	switch s := sch.(type) {
+	case kafka.Schedule:
+		if s.Message == nil {
+			return result
+		}
+		result = Fields{
+			Topic:         s.Topic(),
+			TargetTopic:   s.TargetTopic(),
+			TargetKey:     s.TargetKey(),
+			RawValue:      s.Value,
+			Headers:       bbolt.MessageHeaders(s.Message),
+			TimestampType: bbolt.MessageTimestampType(s.Message),
+		}
+		// negative offsets are the special offsets of the kafka client (not consumed message)
+		if s.TopicPartition.Offset >= 0 {
+			partition := s.TopicPartition.Partition
+			offset := int64(s.TopicPartition.Offset)
+			result.Partition, result.Offset = &partition, &offset
+		}
+	case bbolt.Schedule:
+		result = Fields{
+			Topic:         s.Topic,
+			TargetTopic:   s.TargetTopic,
+			TargetKey:     s.TargetKey,
+			RawValue:      s.Value,
+			Partition:     s.Partition,
+			Offset:        s.Offset,
+			Extra:         s.Fields,
+			Headers:       s.Headers,
+			TimestampType: s.TimestampType,
+		}
+	case rest.Schedule:
+		result = Fields{
+			Topic:       s.MessageTopic,
+			TargetTopic: s.MessageTargetTopic,
+			TargetKey:   s.MessageTargetKey,
+			RawValue:    s.MessageValue,
+		}
+	}
+
+	if extra != nil {
+		result.Extra = extra
+	}
+
+	if utf8.Valid(result.RawValue) {
+		result.Value = string(result.RawValue)
+	}
+
+	return result
+}
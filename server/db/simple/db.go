@@ -37,6 +37,35 @@ func (d DB) Search(q db.SearchQuery) (total int, result chan schedule.Schedule,
 			epoch := sch.Epoch()
 			match = match && epoch <= q.EpochRange.To
 		}
+
+		if q.Filter.TargetTopic != "" || q.Filter.TargetKey != "" || q.Filter.Value != "" || len(q.Filter.Fields) != 0 ||
+			q.Filter.MessagePartition != nil || q.Filter.MessageOffset != nil || len(q.Filter.Headers) != 0 {
+			fields := db.GetFields(sch)
+			if q.Filter.TargetTopic != "" {
+				match = match && fields.TargetTopic == q.Filter.TargetTopic
+			}
+			if q.Filter.TargetKey != "" {
+				match = match && fields.TargetKey == q.Filter.TargetKey
+			}
+			// all the words should be contained in the value (case insensitive)
+			value := strings.ToLower(fields.Value)
+			for _, word := range strings.Fields(strings.ToLower(q.Filter.Value)) {
+				match = match && strings.Contains(value, word)
+			}
+			for name, v := range q.Filter.Fields {
+				match = match && fields.Extra[name] == v
+			}
+			if q.Filter.MessagePartition != nil {
+				match = match && fields.Partition != nil && *fields.Partition == *q.Filter.MessagePartition
+			}
+			if q.Filter.MessageOffset != nil {
+				match = match && fields.Offset != nil && *fields.Offset == *q.Filter.MessageOffset
+			}
+			for name, v := range q.Filter.Headers {
+				header, found := fields.Header(name)
+				match = match && found && header == v
+			}
+		}
 		return match
 	}
 
@@ -58,14 +87,25 @@ func (d DB) Search(q db.SearchQuery) (total int, result chan schedule.Schedule,
 	result = make(chan schedule.Schedule, ChanSize)
 
 	max := DefaultMax
-	if q.Max > 0 {
+	if q.Max == -1 {
+		max = len(arr)
+	} else if q.Max > 0 {
 		max = q.Max
 	}
 
+	page := arr
+	if q.Offset > 0 {
+		if q.Offset >= len(arr) {
+			page = nil
+		} else {
+			page = arr[q.Offset:]
+		}
+	}
+
 	go func() {
 		defer close(result)
 
-		for _, sch := range arr {
+		for _, sch := range page {
 			if found >= max {
 				break
 			}
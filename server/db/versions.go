@@ -0,0 +1,92 @@
+package db
+
+import (
+	"bytes"
+	stdsort "sort"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+// Change is the modification of a field between two consecutive versions of a schedule
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Version is a version of a schedule with the kafka message it comes from (when known)
+type Version struct {
+	Schedule  schedule.Schedule `json:"schedule"`
+	Timestamp int64             `json:"timestamp"`
+	Topic     string            `json:"topic,omitempty"`
+	Partition *int32            `json:"partition,omitempty"`
+	Offset    *int64            `json:"offset,omitempty"`
+	// changes compared to the previous version, empty for the first version
+	Changes []Change `json:"changes"`
+}
+
+// Versions returns the versions of a schedule from the oldest to the newest, with the changes
+// between consecutive versions. Versions are sorted by timestamp then by offset when known.
+func Versions(schs []store.Schedule) []Version {
+	result := make([]Version, len(schs))
+	fields := make([]Fields, len(schs))
+
+	for i, sch := range schs {
+		fields[i] = GetFields(sch)
+		result[i] = Version{
+			Schedule:  sch.Schedule,
+			Timestamp: sch.Timestamp(),
+			Topic:     fields[i].Topic,
+			Partition: fields[i].Partition,
+			Offset:    fields[i].Offset,
+		}
+	}
+
+	// sort the indexes to keep versions and fields in sync
+	indexes := make([]int, len(schs))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	stdsort.SliceStable(indexes, func(i, j int) bool {
+		vi, vj := result[indexes[i]], result[indexes[j]]
+		if vi.Timestamp != vj.Timestamp {
+			return vi.Timestamp < vj.Timestamp
+		}
+		if vi.Offset != nil && vj.Offset != nil {
+			return *vi.Offset < *vj.Offset
+		}
+		return false
+	})
+
+	sorted := make([]Version, len(schs))
+	for i, index := range indexes {
+		sorted[i] = result[index]
+		sorted[i].Changes = []Change{}
+		if i > 0 {
+			previous := indexes[i-1]
+			sorted[i].Changes = diff(schs[previous], fields[previous], schs[index], fields[index])
+		}
+	}
+
+	return sorted
+}
+
+func diff(from schedule.Schedule, fromFields Fields, to schedule.Schedule, toFields Fields) []Change {
+	result := []Change{}
+
+	if from.Epoch() != to.Epoch() {
+		result = append(result, Change{"epoch", from.Epoch(), to.Epoch()})
+	}
+	if fromFields.TargetTopic != toFields.TargetTopic {
+		result = append(result, Change{"target-topic", fromFields.TargetTopic, toFields.TargetTopic})
+	}
+	if fromFields.TargetKey != toFields.TargetKey {
+		result = append(result, Change{"target-key", fromFields.TargetKey, toFields.TargetKey})
+	}
+	if !bytes.Equal(fromFields.RawValue, toFields.RawValue) {
+		result = append(result, Change{"value", fromFields.RawValue, toFields.RawValue})
+	}
+
+	return result
+}
@@ -0,0 +1,133 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultThreshold = 5
+	DefaultCooldown  = 30 * time.Second
+)
+
+var (
+	// ErrOpen is returned without calling the decoder when the circuit is open
+	ErrOpen = errors.New("decoder circuit open")
+)
+
+type state int
+
+const (
+	closed state = iota
+	open
+	// the cooldown is over, one decoding is allowed to test the decoder
+	halfOpen
+)
+
+// Breaker stops calling a failing decoder: the circuit opens after threshold consecutive failures,
+// the decoder is called again after the cooldown, the circuit closes on the first success.
+// The formats not supported by the decoder are not failures.
+type Breaker struct {
+	name      string
+	dec       decoder.Decoder
+	threshold int
+	cooldown  time.Duration
+	mutex     *sync.Mutex
+	state     state
+	failures  int
+	openedAt  time.Time
+}
+
+// New returns a circuit breaker of the decoder, the name is the name of the decoder in the logs and the metrics
+func New(name string, dec decoder.Decoder, threshold int, cooldown time.Duration) *Breaker {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultCooldown
+	}
+	metrics.DecoderCircuitOpen.WithLabelValues(name).Set(0)
+
+	return &Breaker{
+		name:      name,
+		dec:       dec,
+		threshold: threshold,
+		cooldown:  cooldown,
+		mutex:     &sync.Mutex{},
+	}
+}
+
+func (b *Breaker) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	return b.DecodeFor("", s)
+}
+
+func (b *Breaker) DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	if !b.allow() {
+		return s, fmt.Errorf("%w: %v", ErrOpen, b.name)
+	}
+
+	result, err := decoder.Decode(b.dec, schedulerName, s)
+	b.record(err == nil || errors.Is(err, decoder.ErrNotSupported))
+
+	return result, err
+}
+
+// Open returns true when the decoder is not called
+func (b *Breaker) Open() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.state == open && time.Since(b.openedAt) < b.cooldown
+}
+
+func (b *Breaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case open:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		log.Printf("decoder %v circuit half-open, trying the decoder", b.name)
+		b.state = halfOpen
+		return true
+	case halfOpen:
+		// a decoding is already testing the decoder
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *Breaker) record(success bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if success {
+		if b.state != closed {
+			log.Printf("decoder %v circuit closed", b.name)
+			metrics.DecoderCircuitOpen.WithLabelValues(b.name).Set(0)
+		}
+		b.state = closed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == halfOpen || b.failures >= b.threshold {
+		if b.state != open {
+			log.Warnf("decoder %v circuit open after %v consecutive failures, retrying in %v", b.name, b.failures, b.cooldown)
+			metrics.DecoderCircuitOpen.WithLabelValues(b.name).Set(1)
+		}
+		b.state = open
+		b.openedAt = time.Now()
+	}
+}
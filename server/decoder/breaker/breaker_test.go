@@ -0,0 +1,103 @@
+package breaker_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/breaker"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+// switchDecoder returns its error and counts its calls
+type switchDecoder struct {
+	err   error
+	calls int
+}
+
+func (d *switchDecoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	d.calls++
+	return s, d.err
+}
+
+// Rule #1: the decoder should not be called after threshold consecutive failures, until the cooldown is over
+func TestBreaker(t *testing.T) {
+	cooldown := 50 * time.Millisecond
+	dec := &switchDecoder{err: errors.New("decoder unavailable")}
+	b := breaker.New("test", dec, 3, cooldown)
+
+	decode := func() error {
+		_, err := b.Decode(rest.Schedule{MessageValue: []byte("value")})
+		return err
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := decode(); err == nil || errors.Is(err, breaker.ErrOpen) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if !b.Open() {
+		t.Errorf("circuit should be open")
+	}
+
+	if err := decode(); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if dec.calls != 3 {
+		t.Errorf("unexpected decoder calls: %v", dec.calls)
+	}
+
+	// the trial after the cooldown fails, the circuit is open again
+	time.Sleep(cooldown)
+	if err := decode(); err == nil || errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := decode(); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if dec.calls != 4 {
+		t.Errorf("unexpected decoder calls: %v", dec.calls)
+	}
+
+	// the trial after the cooldown succeeds, the circuit is closed
+	time.Sleep(cooldown)
+	dec.err = nil
+	for i := 0; i < 3; i++ {
+		if err := decode(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if b.Open() {
+		t.Errorf("circuit should be closed")
+	}
+	if dec.calls != 7 {
+		t.Errorf("unexpected decoder calls: %v", dec.calls)
+	}
+}
+
+// Rule #2: the messages not supported by the decoder should not open the circuit
+func TestBreaker_NotSupported(t *testing.T) {
+	tests := []struct {
+		err          error
+		expectedOpen bool
+	}{
+		{fmt.Errorf("%w: no schema id", decoder.ErrNotSupported), false},
+		{errors.New("invalid message"), true},
+		{nil, false},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			b := breaker.New("test", &switchDecoder{err: tt.err}, 2, time.Minute)
+			for j := 0; j < 2; j++ {
+				_, _ = b.Decode(rest.Schedule{})
+			}
+			if b.Open() != tt.expectedOpen {
+				t.Errorf("unexpected open: %v", b.Open())
+			}
+		})
+	}
+}
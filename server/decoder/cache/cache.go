@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"strconv"
+	"sync"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/payload"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+const (
+	DefaultSize = 10000
+)
+
+type key [sha256.Size]byte
+
+type entry struct {
+	key    key
+	value  []byte
+	fields map[string]string
+}
+
+// Decoder caches the values decoded by a decoder, the cache is keyed by the hash of the value and of all
+// the inputs of the decoders: the scheduler, the id, the epoch, the topics and the target key of the schedule
+// (the scripts and the decoder services receive them).
+// The least recently used values are evicted when the cache is full, the failures are not cached.
+// The fields extracted by the decoder are cached with the value.
+type Decoder struct {
+	dec     decoder.Decoder
+	size    int
+	mutex   *sync.Mutex
+	entries map[key]*list.Element
+	// most recently used first
+	lru *list.List
+}
+
+// New returns a cache of the values decoded by the decoder, with at most size values
+func New(dec decoder.Decoder, size int) *Decoder {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Decoder{
+		dec:     dec,
+		size:    size,
+		mutex:   &sync.Mutex{},
+		entries: make(map[key]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (d *Decoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	return d.DecodeFor("", s)
+}
+
+func (d *Decoder) DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	value, err := payload.Value(s)
+	if err != nil || len(value) == 0 {
+		return decoder.Decode(d.dec, schedulerName, s)
+	}
+
+	k := hash(schedulerName, s, value)
+
+	if e, found := d.get(k); found {
+		metrics.DecoderCacheRequests.WithLabelValues("hit").Inc()
+		result, err := payload.WithValue(s, e.value)
+		if err != nil || e.fields == nil {
+			return result, err
+		}
+		return decoder.WithFields{Schedule: result, Fields: e.fields}, nil
+	}
+	metrics.DecoderCacheRequests.WithLabelValues("miss").Inc()
+
+	result, err := decoder.Decode(d.dec, schedulerName, s)
+	if err != nil {
+		return result, err
+	}
+
+	decoded, _, fields := decoder.Unwrap(result)
+	value, err = payload.Value(decoded)
+	if err != nil {
+		return result, nil
+	}
+	d.put(entry{k, value, fields})
+
+	return result, nil
+}
+
+// Len returns the number of values in the cache
+func (d *Decoder) Len() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.lru.Len()
+}
+
+func (d *Decoder) get(k key) (entry, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	elt, found := d.entries[k]
+	if !found {
+		return entry{}, false
+	}
+	d.lru.MoveToFront(elt)
+
+	return elt.Value.(entry), true
+}
+
+func (d *Decoder) put(e entry) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if elt, found := d.entries[e.key]; found {
+		elt.Value = e
+		d.lru.MoveToFront(elt)
+		return
+	}
+
+	d.entries[e.key] = d.lru.PushFront(e)
+
+	for d.lru.Len() > d.size {
+		oldest := d.lru.Back()
+		d.lru.Remove(oldest)
+		delete(d.entries, oldest.Value.(entry).key)
+	}
+}
+
+func hash(schedulerName string, s schedule.Schedule, value []byte) key {
+	h := sha256.New()
+	for _, input := range []string{
+		schedulerName,
+		s.ID(),
+		strconv.FormatInt(s.Epoch(), 10),
+		payload.Topic(s),
+		payload.TargetTopic(s),
+		payload.TargetKey(s),
+	} {
+		h.Write([]byte(input))
+		h.Write([]byte{0})
+	}
+	h.Write(value)
+
+	var result key
+	copy(result[:], h.Sum(nil))
+	return result
+}
@@ -0,0 +1,135 @@
+package cache_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/cache"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+// countingDecoder upper cases the values of the rest schedules and counts its calls,
+// the values starting with "invalid" cannot be decoded
+type countingDecoder struct {
+	calls int
+}
+
+func (d *countingDecoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	return d.DecodeFor("", s)
+}
+
+func (d *countingDecoder) DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	d.calls++
+	sch := s.(rest.Schedule)
+	if strings.HasPrefix(string(sch.MessageValue), "invalid") {
+		return s, errors.New("invalid value")
+	}
+	sch.MessageValue = []byte(schedulerName + ":" + strings.ToUpper(string(sch.MessageValue)))
+	return sch, nil
+}
+
+func newSchedule(id, topic, value string) rest.Schedule {
+	return rest.Schedule{
+		ScheduleID:         id,
+		MessageTargetTopic: topic,
+		MessageValue:       []byte(value),
+	}
+}
+
+func withEpoch(s rest.Schedule, epoch int64) rest.Schedule {
+	s.ScheduleEpoch = epoch
+	return s
+}
+
+func withTargetKey(s rest.Schedule, key string) rest.Schedule {
+	s.MessageTargetKey = key
+	return s
+}
+
+// Rule #1: the decoded values should be cached by scheduler, value and schedule metadata (id, epoch, topics, target key)
+func TestDecoder_Cache(t *testing.T) {
+	dec := &countingDecoder{}
+	c := cache.New(dec, 10)
+
+	tests := []struct {
+		scheduler     string
+		schedule      rest.Schedule
+		expectedValue string
+		expectedErr   bool
+		expectedCalls int
+	}{
+		{"scheduler-1", newSchedule("1", "topic-1", "value"), "scheduler-1:VALUE", false, 1},
+		// same schedule
+		{"scheduler-1", newSchedule("1", "topic-1", "value"), "scheduler-1:VALUE", false, 1},
+		// same value, another schedule, epoch or target key: the decoders receive them
+		{"scheduler-1", newSchedule("2", "topic-1", "value"), "scheduler-1:VALUE", false, 2},
+		{"scheduler-1", withEpoch(newSchedule("1", "topic-1", "value"), 10), "scheduler-1:VALUE", false, 3},
+		{"scheduler-1", withTargetKey(newSchedule("1", "topic-1", "value"), "key-1"), "scheduler-1:VALUE", false, 4},
+		{"scheduler-2", newSchedule("1", "topic-1", "value"), "scheduler-2:VALUE", false, 5},
+		{"scheduler-1", newSchedule("1", "topic-2", "value"), "scheduler-1:VALUE", false, 6},
+		{"scheduler-1", newSchedule("1", "topic-1", "other"), "scheduler-1:OTHER", false, 7},
+		// failures are not cached
+		{"scheduler-1", newSchedule("1", "topic-1", "invalid"), "invalid", true, 8},
+		{"scheduler-1", newSchedule("1", "topic-1", "invalid"), "invalid", true, 9},
+		// empty values are not cached
+		{"scheduler-1", newSchedule("1", "topic-1", ""), "scheduler-1:", false, 10},
+		{"scheduler-1", newSchedule("1", "topic-1", ""), "scheduler-1:", false, 11},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result, err := c.DecodeFor(tt.scheduler, tt.schedule)
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			sch := result.(rest.Schedule)
+			if string(sch.MessageValue) != tt.expectedValue {
+				t.Errorf("unexpected value: %s", sch.MessageValue)
+			}
+			if sch.ScheduleID != tt.schedule.ScheduleID {
+				t.Errorf("unexpected id: %v", sch.ScheduleID)
+			}
+			if dec.calls != tt.expectedCalls {
+				t.Errorf("unexpected decoder calls: %v", dec.calls)
+			}
+		})
+	}
+}
+
+// Rule #2: the least recently used values should be evicted when the cache is full
+func TestDecoder_Eviction(t *testing.T) {
+	dec := &countingDecoder{}
+	c := cache.New(dec, 2)
+
+	decode := func(value string) {
+		if _, err := c.Decode(newSchedule("1", "topic", value)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	decode("a")
+	decode("b")
+	// a is used, b is the least recently used
+	decode("a")
+	decode("c")
+
+	if c.Len() != 2 {
+		t.Errorf("unexpected cache length: %v", c.Len())
+	}
+	if dec.calls != 3 {
+		t.Errorf("unexpected decoder calls: %v", dec.calls)
+	}
+
+	decode("a")
+	if dec.calls != 3 {
+		t.Errorf("a should be in the cache, decoder calls: %v", dec.calls)
+	}
+
+	decode("b")
+	if dec.calls != 4 {
+		t.Errorf("b should have been evicted, decoder calls: %v", dec.calls)
+	}
+}
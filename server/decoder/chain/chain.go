@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"errors"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/payload"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	log "github.com/sirupsen/logrus"
+)
+
+// Chain tries the decoders in order, the schedule is decoded by the first decoder which succeeds.
+// When no decoder supports the format of the message, the schedule is returned unchanged.
+type Chain []decoder.Decoder
+
+func (c Chain) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	return c.DecodeFor("", s)
+}
+
+// DecodeFor passes the scheduler to the decoders of the chain which depend on it
+func (c Chain) DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	var lastErr error
+
+	for _, d := range c {
+		result, err := decoder.Decode(d, schedulerName, s)
+		if err == nil {
+			return result, nil
+		}
+		if !errors.Is(err, decoder.ErrNotSupported) {
+			log.Debugf("decoder %T failed on schedule %v: %v", d, s.ID(), err)
+			lastErr = err
+		}
+	}
+
+	return s, lastErr
+}
+
+// Router selects the decoder of a schedule by its target topic, then by its scheduler,
+// the default decoder is used when none is configured for the target topic and the scheduler
+type Router struct {
+	Default    decoder.Decoder
+	Schedulers map[string]decoder.Decoder
+	Topics     map[string]decoder.Decoder
+}
+
+func (r Router) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	return r.DecodeFor("", s)
+}
+
+func (r Router) DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	d, found := r.Topics[payload.TargetTopic(s)]
+	if !found {
+		d, found = r.Schedulers[schedulerName]
+	}
+	if !found {
+		d = r.Default
+	}
+
+	if d == nil {
+		return s, nil
+	}
+	return decoder.Decode(d, schedulerName, s)
+}
@@ -0,0 +1,111 @@
+package chain_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/chain"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+)
+
+// fakeDecoder replaces the value of the rest schedules with its name, or returns its error
+type fakeDecoder struct {
+	name string
+	err  error
+}
+
+func (d fakeDecoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	if d.err != nil {
+		return s, d.err
+	}
+	sch := s.(rest.Schedule)
+	sch.MessageValue = []byte(d.name)
+	return sch, nil
+}
+
+var (
+	notSupported = fakeDecoder{err: fmt.Errorf("%w: no schema id", decoder.ErrNotSupported)}
+	failed       = fakeDecoder{err: errors.New("invalid message")}
+)
+
+func value(s schedule.Schedule) string {
+	return string(s.(rest.Schedule).MessageValue)
+}
+
+// Rule #1: chain should return the schedule of the first decoder which succeeds
+func TestChain(t *testing.T) {
+	tests := []struct {
+		chain         chain.Chain
+		expectedValue string
+		expectedErr   bool
+	}{
+		{chain.Chain{fakeDecoder{name: "avro"}, fakeDecoder{name: "http"}}, "avro", false},
+		{chain.Chain{notSupported, fakeDecoder{name: "http"}}, "http", false},
+		{chain.Chain{failed, notSupported, fakeDecoder{name: "http"}}, "http", false},
+		// no decoder supports the message, the raw value is kept
+		{chain.Chain{notSupported, notSupported}, "raw", false},
+		{chain.Chain{notSupported, failed}, "raw", true},
+		{chain.Chain{}, "raw", false},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result, err := tt.chain.Decode(rest.Schedule{MessageValue: []byte("raw")})
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if v := value(result); v != tt.expectedValue {
+				t.Errorf("unexpected value: %v, expected %v", v, tt.expectedValue)
+			}
+		})
+	}
+}
+
+// Rule #2: router should select the decoder by target topic, then by scheduler, then the default one
+func TestRouter(t *testing.T) {
+	router := chain.Router{
+		Default: fakeDecoder{name: "default"},
+		Schedulers: map[string]decoder.Decoder{
+			"scheduler-1": fakeDecoder{name: "scheduler-1"},
+		},
+		Topics: map[string]decoder.Decoder{
+			"videos": fakeDecoder{name: "videos"},
+		},
+	}
+
+	tests := []struct {
+		schedulerName string
+		targetTopic   string
+		expectedValue string
+	}{
+		{"scheduler-1", "videos", "videos"},
+		{"scheduler-1", "other", "scheduler-1"},
+		{"scheduler-2", "other", "default"},
+		{"", "", "default"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result, err := decoder.Decode(router, tt.schedulerName, rest.Schedule{MessageTargetTopic: tt.targetTopic})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v := value(result); v != tt.expectedValue {
+				t.Errorf("unexpected value: %v, expected %v", v, tt.expectedValue)
+			}
+		})
+	}
+
+	// without default decoder, the schedules are not decoded
+	router.Default = nil
+	sch := &kafka.Schedule{Message: &confluent.Message{Value: []byte("raw")}}
+	result, err := router.DecodeFor("scheduler-2", sch)
+	if err != nil || result != sch {
+		t.Errorf("unexpected result: %v %v", result, err)
+	}
+}
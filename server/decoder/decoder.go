@@ -1,7 +1,82 @@
 package decoder
 
-import "github.com/etf1/kafka-message-scheduler/schedule"
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+var (
+	// ErrNotSupported is returned by a decoder when the format of the message is not its format,
+	// the next decoder of a chain is tried
+	ErrNotSupported = errors.New("message format not supported by the decoder")
+)
 
 type Decoder interface {
 	Decode(s schedule.Schedule) (schedule.Schedule, error)
 }
+
+// SchedulerDecoder is implemented by the decoders which depend on the scheduler of the schedule
+type SchedulerDecoder interface {
+	DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error)
+}
+
+// Decode decodes a schedule of the scheduler, with the scheduler when the decoder is a SchedulerDecoder
+func Decode(d Decoder, schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	if sd, ok := d.(SchedulerDecoder); ok {
+		return sd.DecodeFor(schedulerName, s)
+	}
+	return d.Decode(s)
+}
+
+// Failed is a schedule which cannot be decoded, with the error of the decoder,
+// the value of the schedule is the raw value
+type Failed struct {
+	schedule.Schedule
+	Err string
+}
+
+// MarshalJSON adds the error to the JSON object of the schedule
+func (f Failed) MarshalJSON() ([]byte, error) {
+	return marshalWith(f.Schedule, "decode-error", f.Err)
+}
+
+// WithFields is a decoded schedule with extra fields extracted from its value by the decoder,
+// the fields are indexed with the schedule
+type WithFields struct {
+	schedule.Schedule
+	Fields map[string]string
+}
+
+// MarshalJSON adds the fields to the JSON object of the schedule
+func (f WithFields) MarshalJSON() ([]byte, error) {
+	return marshalWith(f.Schedule, "fields", f.Fields)
+}
+
+// Unwrap returns the schedule wrapped by Failed or WithFields, with the error and the fields of the decoder
+func Unwrap(s schedule.Schedule) (sch schedule.Schedule, decodeErr string, fields map[string]string) {
+	switch w := s.(type) {
+	case Failed:
+		return w.Schedule, w.Err, nil
+	case WithFields:
+		return w.Schedule, "", w.Fields
+	default:
+		return s, "", nil
+	}
+}
+
+func marshalWith(s schedule.Schedule, key string, value interface{}) ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields[key] = value
+
+	return json.Marshal(fields)
+}
@@ -0,0 +1,244 @@
+package grpcdecoder
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/grpcdecoder/decoderpb"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/payload"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// schemes of the URL of a gRPC decoder
+const (
+	Scheme    = "grpc://"
+	TLSScheme = "grpcs://"
+)
+
+const (
+	// DefaultTimeout is the deadline of a batch
+	DefaultTimeout = 1 * time.Second
+	// DefaultBatchSize is the maximum number of schedules in a batch
+	DefaultBatchSize = 100
+	// DefaultLinger is the time to wait for other schedules before sending a batch
+	DefaultLinger = 5 * time.Millisecond
+)
+
+var (
+	ErrClosed = errors.New("grpc decoder closed")
+)
+
+// IsURL returns true when the URL is the URL of a gRPC decoder (grpc:// or grpcs:// scheme)
+func IsURL(u string) bool {
+	return strings.HasPrefix(u, Scheme) || strings.HasPrefix(u, TLSScheme)
+}
+
+type result struct {
+	schedule schedule.Schedule
+	err      error
+}
+
+type request struct {
+	schedule schedule.Schedule
+	pb       *decoderpb.Schedule
+	result   chan result
+}
+
+// Decoder decodes the message bodies with a gRPC decoder service (see decoderpb/decoder.proto),
+// the schedules decoded concurrently are sent in batches.
+type Decoder struct {
+	conn      *grpc.ClientConn
+	client    decoderpb.DecoderClient
+	timeout   time.Duration
+	batchSize int
+	linger    time.Duration
+	requests  chan request
+	stopChan  chan bool
+	closeOnce *sync.Once
+}
+
+// New returns a decoder calling the service of the URL grpc://host:port, or grpcs://host:port for TLS,
+// each batch has the timeout as deadline
+func New(u string, timeout time.Duration) (*Decoder, error) {
+	var target string
+	var creds credentials.TransportCredentials
+
+	switch {
+	case strings.HasPrefix(u, TLSScheme):
+		target = strings.TrimPrefix(u, TLSScheme)
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	case strings.HasPrefix(u, Scheme):
+		target = strings.TrimPrefix(u, Scheme)
+		creds = insecure.NewCredentials()
+	default:
+		return nil, fmt.Errorf("invalid grpc decoder url %v: the scheme should be %v or %v", u, Scheme, TLSScheme)
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	conn, err := grpc.Dial(strings.TrimSuffix(target, "/"), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to grpc decoder %v: %w", u, err)
+	}
+
+	d := &Decoder{
+		conn:      conn,
+		client:    decoderpb.NewDecoderClient(conn),
+		timeout:   timeout,
+		batchSize: DefaultBatchSize,
+		linger:    DefaultLinger,
+		requests:  make(chan request),
+		stopChan:  make(chan bool),
+		closeOnce: &sync.Once{},
+	}
+	go d.batch()
+
+	return d, nil
+}
+
+// Close stops the decoder and closes the connection with the service, the pending schedules fail with ErrClosed
+func (d *Decoder) Close() error {
+	var err error
+	d.closeOnce.Do(func() {
+		close(d.stopChan)
+		err = d.conn.Close()
+	})
+	return err
+}
+
+func (d *Decoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	return d.DecodeFor("", s)
+}
+
+// DecodeFor returns a copy of the input schedule, its value replaced by the value decoded by the service
+func (d *Decoder) DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	result, err := d.decode(schedulerName, s)
+	if err != nil && !errors.Is(err, decoder.ErrNotSupported) {
+		metrics.DecoderFailures.WithLabelValues("grpc").Inc()
+	}
+	return result, err
+}
+
+func (d *Decoder) decode(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	value, err := payload.Value(s)
+	if err != nil {
+		return s, err
+	}
+	if len(value) == 0 {
+		return s, nil
+	}
+
+	req := request{
+		schedule: s,
+		pb: &decoderpb.Schedule{
+			Id:          s.ID(),
+			Epoch:       s.Epoch(),
+			Scheduler:   schedulerName,
+			Topic:       payload.Topic(s),
+			TargetTopic: payload.TargetTopic(s),
+			TargetKey:   payload.TargetKey(s),
+			Value:       value,
+		},
+		result: make(chan result, 1),
+	}
+
+	select {
+	case d.requests <- req:
+	case <-d.stopChan:
+		return s, ErrClosed
+	}
+
+	res := <-req.result
+	return res.schedule, res.err
+}
+
+// batch collects the requests until the batch is full or the linger is over, and sends the batches
+func (d *Decoder) batch() {
+	for {
+		var reqs []request
+
+		select {
+		case req := <-d.requests:
+			reqs = append(reqs, req)
+		case <-d.stopChan:
+			return
+		}
+
+		timer := time.NewTimer(d.linger)
+	loop:
+		for len(reqs) < d.batchSize {
+			select {
+			case req := <-d.requests:
+				reqs = append(reqs, req)
+			case <-timer.C:
+				break loop
+			case <-d.stopChan:
+				timer.Stop()
+				for _, req := range reqs {
+					req.result <- result{req.schedule, ErrClosed}
+				}
+				return
+			}
+		}
+		timer.Stop()
+
+		go d.send(reqs)
+	}
+}
+
+func (d *Decoder) send(reqs []request) {
+	schedules := make([]*decoderpb.Schedule, len(reqs))
+	for i, req := range reqs {
+		schedules[i] = req.pb
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	resp, err := d.client.Decode(ctx, &decoderpb.DecodeRequest{Schedules: schedules})
+	if err == nil && len(resp.Results) != len(reqs) {
+		err = fmt.Errorf("unexpected number of results: %v instead of %v", len(resp.Results), len(reqs))
+	}
+	if err != nil {
+		log.Warnf("grpc decoder failed on a batch of %v schedules: %v", len(reqs), err)
+		for _, req := range reqs {
+			req.result <- result{req.schedule, err}
+		}
+		return
+	}
+
+	for i, req := range reqs {
+		req.result <- toResult(req.schedule, resp.Results[i])
+	}
+}
+
+func toResult(s schedule.Schedule, r *decoderpb.Result) result {
+	switch res := r.GetResult().(type) {
+	case *decoderpb.Result_Value:
+		sch, err := payload.WithValue(s, res.Value)
+		return result{sch, err}
+	case *decoderpb.Result_Error:
+		return result{s, errors.New(res.Error)}
+	case *decoderpb.Result_NotSupported:
+		if res.NotSupported {
+			return result{s, fmt.Errorf("%w: rejected by the grpc decoder", decoder.ErrNotSupported)}
+		}
+		return result{s, nil}
+	default:
+		// no result, the value is unchanged
+		return result{s, nil}
+	}
+}
@@ -0,0 +1,262 @@
+package grpcdecoder_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/chain"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/grpcdecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/grpcdecoder/decoderpb"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeServer upper cases the values, the values "invalid" cannot be decoded and the values "binary" are not supported,
+// the value "slow" is decoded after the deadline of the client
+type fakeServer struct {
+	decoderpb.UnimplementedDecoderServer
+	mutex   sync.Mutex
+	batches [][]*decoderpb.Schedule
+}
+
+func (f *fakeServer) Decode(ctx context.Context, req *decoderpb.DecodeRequest) (*decoderpb.DecodeResponse, error) {
+	f.mutex.Lock()
+	f.batches = append(f.batches, req.Schedules)
+	f.mutex.Unlock()
+
+	resp := &decoderpb.DecodeResponse{}
+	for _, s := range req.Schedules {
+		var result decoderpb.Result
+		switch string(s.Value) {
+		case "invalid":
+			result.Result = &decoderpb.Result_Error{Error: "invalid value"}
+		case "binary":
+			result.Result = &decoderpb.Result_NotSupported{NotSupported: true}
+		case "slow":
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return nil, status.Error(codes.DeadlineExceeded, "too slow")
+			}
+		default:
+			result.Result = &decoderpb.Result_Value{Value: []byte(fmt.Sprintf("%v/%v:%v", s.Scheduler, s.TargetTopic, strings.ToUpper(string(s.Value))))}
+		}
+		resp.Results = append(resp.Results, &result)
+	}
+	return resp, nil
+}
+
+func (f *fakeServer) batchSizes() []int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	result := make([]int, len(f.batches))
+	for i, b := range f.batches {
+		result[i] = len(b)
+	}
+	return result
+}
+
+func startServer(t *testing.T) (*fakeServer, *grpcdecoder.Decoder) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fake := &fakeServer{}
+	srv := grpc.NewServer()
+	decoderpb.RegisterDecoderServer(srv, fake)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	dec, err := grpcdecoder.New("grpc://"+lis.Addr().String(), 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = dec.Close()
+	})
+
+	return fake, dec
+}
+
+// Rule #1: the values should be decoded by the service, with the scheduler and the topic of the schedule
+func TestDecoder_Decode(t *testing.T) {
+	_, dec := startServer(t)
+
+	tests := []struct {
+		value         string
+		expectedValue string
+		expectedErr   error
+	}{
+		{"value", "scheduler-1/target-topic:VALUE", nil},
+		{"invalid", "invalid", errors.New("invalid value")},
+		{"binary", "binary", decoder.ErrNotSupported},
+		{"slow", "slow", errors.New("DeadlineExceeded")},
+		// empty values are not sent
+		{"", "", nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			sch := rest.Schedule{
+				ScheduleID:         "schedule-1",
+				MessageTargetTopic: "target-topic",
+				MessageValue:       []byte(tt.value),
+			}
+
+			result, err := dec.DecodeFor("scheduler-1", sch)
+			switch {
+			case tt.expectedErr == nil && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.expectedErr != nil && err == nil:
+				t.Errorf("expected error: %v", tt.expectedErr)
+			case errors.Is(tt.expectedErr, decoder.ErrNotSupported) && !errors.Is(err, decoder.ErrNotSupported):
+				t.Errorf("unexpected error: %v", err)
+			case tt.expectedErr != nil && !strings.Contains(err.Error(), tt.expectedErr.Error()):
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			rsch := result.(rest.Schedule)
+			if string(rsch.MessageValue) != tt.expectedValue {
+				t.Errorf("unexpected value: %s", rsch.MessageValue)
+			}
+			if rsch.ScheduleID != "schedule-1" {
+				t.Errorf("unexpected id: %v", rsch.ScheduleID)
+			}
+		})
+	}
+}
+
+// Rule #2: the schedules decoded concurrently should be sent in batches
+func TestDecoder_Batch(t *testing.T) {
+	fake, dec := startServer(t)
+
+	count := 50
+	wg := sync.WaitGroup{}
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		go func(i int) {
+			defer wg.Done()
+			value := fmt.Sprintf("value-%v", i)
+			result, err := dec.DecodeFor("scheduler-1", rest.Schedule{MessageValue: []byte(value)})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if expected := "scheduler-1/:" + strings.ToUpper(value); string(result.(rest.Schedule).MessageValue) != expected {
+				t.Errorf("unexpected value: %s", result.(rest.Schedule).MessageValue)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sizes := fake.batchSizes()
+	total := 0
+	for _, size := range sizes {
+		total += size
+	}
+	if total != count {
+		t.Errorf("unexpected total: %v", total)
+	}
+	if len(sizes) >= count {
+		t.Errorf("schedules not batched: %v", sizes)
+	}
+}
+
+// Rule #3: the decoder should not be created with an url which is not a grpc url
+func TestNew_invalidURL(t *testing.T) {
+	_, err := grpcdecoder.New("http://localhost:8080", 0)
+	if err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+// Rule #4: closing the decoder should stop the pending decodes, the next ones should fail with ErrClosed
+func TestDecoder_Close(t *testing.T) {
+	_, dec := startServer(t)
+
+	count := 20
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		wg := sync.WaitGroup{}
+		wg.Add(count)
+		for i := 0; i < count; i++ {
+			go func(i int) {
+				defer wg.Done()
+				_, _ = dec.DecodeFor("scheduler-1", rest.Schedule{MessageValue: []byte(fmt.Sprintf("value-%v", i))})
+			}(i)
+		}
+		wg.Wait()
+	}()
+
+	if err := dec.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pending decodes not stopped")
+	}
+
+	_, err := dec.DecodeFor("scheduler-1", rest.Schedule{MessageValue: []byte("value")})
+	if !errors.Is(err, grpcdecoder.ErrClosed) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// Rule #5: the scheduler should be sent to the service when the decoder is in a chain selected by the router
+func TestDecoder_Router(t *testing.T) {
+	fake, dec := startServer(t)
+
+	router := chain.Router{
+		Default: chain.Chain{dec},
+		Schedulers: map[string]decoder.Decoder{
+			"scheduler-1": chain.Chain{dec},
+		},
+	}
+
+	tests := []struct {
+		schedulerName string
+		expectedValue string
+	}{
+		{"scheduler-1", "scheduler-1/target-topic:VALUE"},
+		{"scheduler-2", "scheduler-2/target-topic:VALUE"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result, err := decoder.Decode(router, tt.schedulerName, rest.Schedule{
+				ScheduleID:         "schedule-1",
+				MessageTargetTopic: "target-topic",
+				MessageValue:       []byte("value"),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v := string(result.(rest.Schedule).MessageValue); v != tt.expectedValue {
+				t.Errorf("unexpected value: %v, expected %v", v, tt.expectedValue)
+			}
+
+			fake.mutex.Lock()
+			defer fake.mutex.Unlock()
+			last := fake.batches[len(fake.batches)-1]
+			if len(last) != 1 || last[0].Scheduler != tt.schedulerName {
+				t.Errorf("unexpected batch: %v", last)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
 	"github.com/etf1/kafka-message-scheduler/schedule"
 	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
@@ -64,6 +65,14 @@ func (h Decoder) Post(payload interface{}) ([]byte, error) {
 
 // Decode returns a copy of the input schedule, its field 'value' replaced by the response from the http request
 func (h Decoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	result, err := h.decode(s)
+	if err != nil {
+		metrics.DecoderFailures.WithLabelValues("http").Inc()
+	}
+	return result, err
+}
+
+func (h Decoder) decode(s schedule.Schedule) (schedule.Schedule, error) {
 	switch sch := s.(type) {
 	case *kafka.Schedule:
 		if len(sch.Message.Value) == 0 {
@@ -0,0 +1,140 @@
+package luadecoder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/payload"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Extension of the script files
+	Extension = ".lua"
+	// DefaultScript is the name of the script of the schedulers without script
+	DefaultScript = "default"
+)
+
+// Decoder runs the script of the scheduler of the schedule, or the default script. The function of the script
+// returns the new value of the message and the fields to index, ie:
+//
+//	function decode(msg)
+//	  local v = json.decode(gzip.decompress(base64.decode(msg.value)))
+//	  return json.encode(v), {country = v.country}
+//	end
+//
+// The schedules of the schedulers without script are not supported by the decoder (decoder.ErrNotSupported).
+type Decoder struct {
+	// scripts by scheduler name
+	scripts map[string]*Script
+}
+
+// New returns a decoder with the scripts of the directory, the script of a scheduler is named with the
+// scheduler name: scheduler-1.lua, the default script is default.lua
+func New(dir string, sb Sandbox) (*Decoder, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+Extension))
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := make(map[string]*Script, len(files))
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), Extension)
+		s, err := sb.Compile(filepath.Base(file), string(source))
+		if err != nil {
+			return nil, err
+		}
+		scripts[name] = s
+		names = append(names, name)
+	}
+	if len(scripts) == 0 {
+		return nil, fmt.Errorf("no %v script in %v", Extension, dir)
+	}
+
+	sort.Strings(names)
+	log.Printf("lua decoder scripts: %v", names)
+
+	return &Decoder{scripts}, nil
+}
+
+func (d *Decoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	return d.DecodeFor("", s)
+}
+
+// DecodeFor returns a copy of the input schedule, its value replaced by the value returned by the script,
+// with the fields returned by the script (decoder.WithFields)
+func (d *Decoder) DecodeFor(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	result, err := d.decode(schedulerName, s)
+	if err != nil && !errors.Is(err, decoder.ErrNotSupported) {
+		metrics.DecoderFailures.WithLabelValues("lua").Inc()
+	}
+	return result, err
+}
+
+func (d *Decoder) decode(schedulerName string, s schedule.Schedule) (schedule.Schedule, error) {
+	script, found := d.scripts[schedulerName]
+	if !found {
+		script, found = d.scripts[DefaultScript]
+	}
+	if !found {
+		return s, fmt.Errorf("%w: no script for scheduler %v", decoder.ErrNotSupported, schedulerName)
+	}
+
+	value, err := payload.Value(s)
+	if err != nil {
+		return s, err
+	}
+	if len(value) == 0 {
+		return s, nil
+	}
+
+	res, err := script.Run(NewMessage(schedulerName, s))
+	if err != nil {
+		return s, err
+	}
+
+	return WithResult(s, res)
+}
+
+// NewMessage returns the message of a schedule, passed to the scripts
+func NewMessage(schedulerName string, s schedule.Schedule) Message {
+	value, _ := payload.Value(s)
+	return Message{
+		ID:          s.ID(),
+		Epoch:       s.Epoch(),
+		Scheduler:   schedulerName,
+		Topic:       payload.Topic(s),
+		TargetTopic: payload.TargetTopic(s),
+		TargetKey:   payload.TargetKey(s),
+		Value:       value,
+	}
+}
+
+// WithResult returns a copy of the schedule with the value and the fields of the result of a script
+func WithResult(s schedule.Schedule, res Result) (schedule.Schedule, error) {
+	result := s
+	if res.Value != nil {
+		var err error
+		result, err = payload.WithValue(s, res.Value)
+		if err != nil {
+			return s, err
+		}
+	}
+
+	if len(res.Fields) == 0 {
+		return result, nil
+	}
+	return decoder.WithFields{Schedule: result, Fields: res.Fields}, nil
+}
@@ -0,0 +1,229 @@
+package luadecoder_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/luadecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+)
+
+func gzipBase64(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// Rule #1: the script should transform the value and extract the fields
+func TestScript_Run(t *testing.T) {
+	sb := luadecoder.Sandbox{}
+
+	tests := []struct {
+		script         string
+		value          string
+		expectedValue  string
+		expectedFields map[string]string
+		expectedErr    bool
+	}{
+		{
+			`function decode(msg) return string.upper(msg.value) end`,
+			"value", "VALUE", nil, false,
+		},
+		// value unchanged
+		{
+			`function decode(msg) return nil, {scheduler = msg.scheduler, topic = msg.target_topic} end`,
+			"value", "", map[string]string{"scheduler": "scheduler-1", "topic": "target-topic"}, false,
+		},
+		{
+			`function decode(msg)
+			   local v = json.decode(gzip.decompress(base64.decode(msg.value)))
+			   v.email = "***"
+			   return json.encode(v), {country = v.country, age = v.age}
+			 end`,
+			gzipBase64(t, `{"country":"fr","age":42,"email":"john@example.com"}`),
+			`{"age":42,"country":"fr","email":"***"}`, map[string]string{"country": "fr", "age": "42"}, false,
+		},
+		// a table referenced twice is not a cycle
+		{
+			`function decode(msg) local t = {msg.value} return json.encode({a = t, b = t}) .. string.rep("!", 2) end`,
+			"value", `{"a":["value"],"b":["value"]}!!`, nil, false,
+		},
+		{
+			`function decode(msg) error("invalid message") end`,
+			"value", "", nil, true,
+		},
+		{
+			`function decode(msg) return 42 end`,
+			"value", "", nil, true,
+		},
+		{
+			`function decode(msg) return base64.decode("!") end`,
+			"value", "", nil, true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			script, err := sb.Compile("test.lua", tt.script)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := script.Run(luadecoder.Message{
+				ID:          "schedule-1",
+				Scheduler:   "scheduler-1",
+				TargetTopic: "target-topic",
+				Value:       []byte(tt.value),
+			})
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(result.Value) != tt.expectedValue {
+				t.Errorf("unexpected value: %s", result.Value)
+			}
+			if !reflect.DeepEqual(result.Fields, tt.expectedFields) {
+				t.Errorf("unexpected fields: %v", result.Fields)
+			}
+		})
+	}
+}
+
+// Rule #2: the scripts should not access the files or load code, and should be interrupted after the timeout
+func TestSandbox(t *testing.T) {
+	sb := luadecoder.Sandbox{Timeout: 50 * time.Millisecond}
+
+	tests := []struct {
+		script      string
+		compileErr  error
+		expectedErr error
+	}{
+		{`x = 1`, luadecoder.ErrNoFunction, nil},
+		{`function decode(msg) return io.open("/etc/passwd"):read("*a") end`, nil, nil},
+		{`function decode(msg) return os.getenv("HOME") end`, nil, nil},
+		{`function decode(msg) return dofile("/etc/passwd") end`, nil, nil},
+		{`function decode(msg) return loadstring("return 1")() end`, nil, nil},
+		{`function decode(msg) return require("os") end`, nil, nil},
+		{`function decode(msg) while true do end end`, nil, luadecoder.ErrTimeout},
+		// the tables containing themselves or nested too deeply cannot be encoded
+		{`function decode(msg) local t = {} t.self = t return json.encode(t) end`, nil, nil},
+		{`function decode(msg) local t = {} for i = 1, 1000 do t = {t} end return json.encode(t) end`, nil, nil},
+		// the memory used by the strings is limited
+		{`function decode(msg) return string.rep("x", 1e12) end`, nil, nil},
+		{`function decode(msg) return ("x"):rep(2^31) end`, nil, nil},
+		// the loading of the script is interrupted too
+		{`while true do end`, luadecoder.ErrTimeout, nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			script, err := sb.Compile("test.lua", tt.script)
+			if tt.compileErr != nil {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				// the timeout of the loading is reported as a load failure
+				if !errors.Is(err, tt.compileErr) && !strings.Contains(err.Error(), "context deadline exceeded") {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			start := time.Now()
+			_, err = script.Run(luadecoder.Message{Value: []byte("value")})
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if time.Since(start) > time.Second {
+				t.Errorf("script not interrupted: %v", time.Since(start))
+			}
+
+			// the script can be run again after an error
+			_, err = script.Run(luadecoder.Message{Value: []byte("value")})
+			if err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+// Rule #3: the decoder should run the script of the scheduler, or the default script
+func TestDecoder(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"scheduler-1.lua": `function decode(msg) return "1:" .. msg.value, {id = msg.id} end`,
+		"default.lua":     `function decode(msg) return "default:" .. msg.value end`,
+	}
+	for name, source := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	dec, err := luadecoder.New(dir, luadecoder.Sandbox{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sch := rest.Schedule{ScheduleID: "schedule-1", MessageValue: []byte("value")}
+
+	result, err := dec.DecodeFor("scheduler-1", sch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf, ok := result.(decoder.WithFields)
+	if !ok {
+		t.Fatalf("unexpected type: %T", result)
+	}
+	if v := string(wf.Schedule.(rest.Schedule).MessageValue); v != "1:value" {
+		t.Errorf("unexpected value: %v", v)
+	}
+	if !reflect.DeepEqual(wf.Fields, map[string]string{"id": "schedule-1"}) {
+		t.Errorf("unexpected fields: %v", wf.Fields)
+	}
+	if string(sch.MessageValue) != "value" {
+		t.Errorf("input schedule modified: %s", sch.MessageValue)
+	}
+
+	result, err = dec.DecodeFor("scheduler-2", sch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := string(result.(rest.Schedule).MessageValue); v != "default:value" {
+		t.Errorf("unexpected value: %v", v)
+	}
+
+	// without default script, the schedules of the other schedulers are not supported
+	if err := os.Remove(filepath.Join(dir, "default.lua")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dec, err = luadecoder.New(dir, luadecoder.Sandbox{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = dec.DecodeFor("scheduler-2", sch)
+	if !errors.Is(err, decoder.ErrNotSupported) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
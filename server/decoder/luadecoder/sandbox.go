@@ -0,0 +1,390 @@
+package luadecoder
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+const (
+	// FunctionName is the name of the function of the scripts called for each message
+	FunctionName = "decode"
+	// DefaultTimeout is the maximum duration of a call of the function
+	DefaultTimeout = 100 * time.Millisecond
+	// MaxInputSize is the maximum size of the values read or built by the helpers (ie: decompressed by gzip.decompress, repeated by string.rep)
+	MaxInputSize = 10 << 20
+
+	callStackSize   = 64
+	registrySize    = 1024
+	registryMaxSize = 64 * 1024
+	// maximum nesting of the tables encoded by json.encode
+	maxJSONDepth = 64
+)
+
+var (
+	ErrNoFunction = errors.New("the script has no " + FunctionName + " function")
+	ErrTimeout    = errors.New("script timeout")
+)
+
+// functions of the base library removed by the sandbox: no access to the files, no code loaded at runtime
+var unsafeFunctions = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "getfenv", "setfenv", "collectgarbage", "print"}
+
+// Message is the message passed to the function of the scripts, as a table with the json names as keys
+type Message struct {
+	ID          string `json:"id"`
+	Epoch       int64  `json:"epoch"`
+	Scheduler   string `json:"scheduler"`
+	Topic       string `json:"topic"`
+	TargetTopic string `json:"target_topic"`
+	TargetKey   string `json:"target_key"`
+	Value       []byte `json:"value"`
+}
+
+// Result is the result of the function of a script: the new value and the fields to index.
+// The value is nil when the function returns nil, the message value is unchanged.
+type Result struct {
+	Value  []byte            `json:"value"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Sandbox compiles the scripts, the scripts are run with the base, string, table and math libraries,
+// without access to the files, the network or the environment, and with a timeout
+type Sandbox struct {
+	Timeout time.Duration
+}
+
+// Script is a compiled script, it can be run concurrently
+type Script struct {
+	name    string
+	proto   *lua.FunctionProto
+	timeout time.Duration
+	// lua states with the script loaded, a state is not safe for concurrent use
+	states *sync.Pool
+}
+
+// Compile compiles the source of a script, the name is used in the errors
+func (sb Sandbox) Compile(name, source string) (*Script, error) {
+	chunk, err := parse.Parse(strings.NewReader(source), name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script %v: %w", name, err)
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script %v: %w", name, err)
+	}
+
+	timeout := sb.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	s := &Script{
+		name:    name,
+		proto:   proto,
+		timeout: timeout,
+		states:  &sync.Pool{},
+	}
+
+	// loads the script once, so a script without function is rejected
+	L, err := s.newState()
+	if err != nil {
+		return nil, err
+	}
+	s.states.Put(L)
+
+	return s, nil
+}
+
+// Run calls the function of the script with the message
+func (s *Script) Run(msg Message) (Result, error) {
+	L, ok := s.states.Get().(*lua.LState)
+	if !ok {
+		var err error
+		L, err = s.newState()
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+
+	err := L.CallByParam(lua.P{
+		Fn:      L.GetGlobal(FunctionName),
+		NRet:    2,
+		Protect: true,
+	}, toTable(L, msg))
+	L.RemoveContext()
+
+	if err != nil {
+		// the state may be inconsistent after an error, it is not reused
+		L.Close()
+		if ctx.Err() != nil {
+			return Result{}, fmt.Errorf("%w: %v after %v", ErrTimeout, s.name, s.timeout)
+		}
+		return Result{}, fmt.Errorf("script %v failed: %w", s.name, err)
+	}
+
+	value, fields := L.Get(-2), L.Get(-1)
+	L.Pop(2)
+	s.states.Put(L)
+
+	return toResult(s.name, value, fields)
+}
+
+func (s *Script) newState() (*lua.LState, error) {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   callStackSize,
+		RegistrySize:    registrySize,
+		RegistryMaxSize: registryMaxSize,
+	})
+
+	for name, open := range map[string]lua.LGFunction{
+		lua.BaseLibName:   lua.OpenBase,
+		lua.TabLibName:    lua.OpenTable,
+		lua.StringLibName: lua.OpenString,
+		lua.MathLibName:   lua.OpenMath,
+	} {
+		L.Push(L.NewFunction(open))
+		L.Push(lua.LString(name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeFunctions {
+		L.SetGlobal(name, lua.LNil)
+	}
+	openHelpers(L)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	L.Push(L.NewFunctionFromProto(s.proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		L.Close()
+		return nil, fmt.Errorf("cannot load script %v: %w", s.name, err)
+	}
+
+	if L.GetGlobal(FunctionName).Type() != lua.LTFunction {
+		L.Close()
+		return nil, fmt.Errorf("%w: %v", ErrNoFunction, s.name)
+	}
+
+	return L, nil
+}
+
+func toTable(L *lua.LState, msg Message) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("id", lua.LString(msg.ID))
+	t.RawSetString("epoch", lua.LNumber(msg.Epoch))
+	t.RawSetString("scheduler", lua.LString(msg.Scheduler))
+	t.RawSetString("topic", lua.LString(msg.Topic))
+	t.RawSetString("target_topic", lua.LString(msg.TargetTopic))
+	t.RawSetString("target_key", lua.LString(msg.TargetKey))
+	t.RawSetString("value", lua.LString(msg.Value))
+	return t
+}
+
+func toResult(name string, value, fields lua.LValue) (Result, error) {
+	var result Result
+
+	switch v := value.(type) {
+	case *lua.LNilType:
+	case lua.LString:
+		result.Value = []byte(v)
+	default:
+		return Result{}, fmt.Errorf("script %v: the value returned by %v should be a string or nil, not a %v", name, FunctionName, value.Type())
+	}
+
+	switch f := fields.(type) {
+	case *lua.LNilType:
+	case *lua.LTable:
+		result.Fields = make(map[string]string)
+		f.ForEach(func(k, v lua.LValue) {
+			result.Fields[k.String()] = v.String()
+		})
+	default:
+		return Result{}, fmt.Errorf("script %v: the fields returned by %v should be a table or nil, not a %v", name, FunctionName, fields.Type())
+	}
+
+	return result, nil
+}
+
+// openHelpers registers the tables base64, gzip and json, with the functions used by the scripts for decoding,
+// and replaces string.rep by a version limited to MaxInputSize
+func openHelpers(L *lua.LState) {
+	if str, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		str.RawSetString("rep", L.NewFunction(func(L *lua.LState) int {
+			s, n := L.CheckString(1), L.CheckInt(2)
+			if n <= 0 || s == "" {
+				L.Push(lua.LString(""))
+				return 1
+			}
+			if len(s) > MaxInputSize/n {
+				L.RaiseError("string.rep: the result exceeds %v bytes", MaxInputSize)
+			}
+			L.Push(lua.LString(strings.Repeat(s, n)))
+			return 1
+		}))
+	}
+
+	L.SetGlobal("base64", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"encode": func(L *lua.LState) int {
+			L.Push(lua.LString(base64.StdEncoding.EncodeToString([]byte(L.CheckString(1)))))
+			return 1
+		},
+		"decode": func(L *lua.LState) int {
+			s := L.CheckString(1)
+			data, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				data, err = base64.RawStdEncoding.DecodeString(s)
+			}
+			if err != nil {
+				L.RaiseError("invalid base64: %v", err)
+			}
+			L.Push(lua.LString(data))
+			return 1
+		},
+	}))
+
+	L.SetGlobal("gzip", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"decompress": func(L *lua.LState) int {
+			r, err := gzip.NewReader(strings.NewReader(L.CheckString(1)))
+			if err != nil {
+				L.RaiseError("invalid gzip: %v", err)
+			}
+			data, err := io.ReadAll(io.LimitReader(r, MaxInputSize))
+			if err != nil {
+				L.RaiseError("invalid gzip: %v", err)
+			}
+			L.Push(lua.LString(data))
+			return 1
+		},
+	}))
+
+	L.SetGlobal("json", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"decode": func(L *lua.LState) int {
+			var v interface{}
+			d := json.NewDecoder(strings.NewReader(L.CheckString(1)))
+			d.UseNumber()
+			if err := d.Decode(&v); err != nil {
+				L.RaiseError("invalid json: %v", err)
+			}
+			L.Push(fromJSON(L, v))
+			return 1
+		},
+		"encode": func(L *lua.LState) int {
+			v, err := toJSON(L.CheckAny(1), map[*lua.LTable]bool{}, 0)
+			if err != nil {
+				L.RaiseError("cannot encode json: %v", err)
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				L.RaiseError("cannot encode json: %v", err)
+			}
+			L.Push(lua.LString(data))
+			return 1
+		},
+	}))
+}
+
+func fromJSON(L *lua.LState, v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return lua.LString(v)
+		}
+		return lua.LNumber(f)
+	case string:
+		return lua.LString(v)
+	case []interface{}:
+		t := L.CreateTable(len(v), 0)
+		for _, e := range v {
+			t.Append(fromJSON(L, e))
+		}
+		return t
+	case map[string]interface{}:
+		t := L.CreateTable(0, len(v))
+		for k, e := range v {
+			t.RawSetString(k, fromJSON(L, e))
+		}
+		return t
+	default:
+		return lua.LString(fmt.Sprint(v))
+	}
+}
+
+// toJSON converts a lua value to a JSON value, the tables with the keys 1..n are arrays, the other tables are objects.
+// The tables in visited are the ones being converted, a table containing itself cannot be converted.
+func toJSON(v lua.LValue, visited map[*lua.LTable]bool, depth int) (interface{}, error) {
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case lua.LString:
+		return string(v), nil
+	case *lua.LTable:
+		if visited[v] {
+			return nil, errors.New("the table contains itself")
+		}
+		if depth >= maxJSONDepth {
+			return nil, fmt.Errorf("the tables are nested more than %v times", maxJSONDepth)
+		}
+		visited[v] = true
+		defer delete(visited, v)
+
+		if n := v.MaxN(); n > 0 && n == countKeys(v) {
+			result := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				e, err := toJSON(v.RawGetInt(i), visited, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, e)
+			}
+			return result, nil
+		}
+		result := make(map[string]interface{})
+		var err error
+		v.ForEach(func(k, e lua.LValue) {
+			if err != nil {
+				return
+			}
+			result[k.String()], err = toJSON(e, visited, depth+1)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	default:
+		return v.String(), nil
+	}
+}
+
+func countKeys(t *lua.LTable) int {
+	n := 0
+	t.ForEach(func(lua.LValue, lua.LValue) {
+		n++
+	})
+	return n
+}
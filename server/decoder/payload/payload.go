@@ -0,0 +1,109 @@
+package payload
+
+import (
+	"fmt"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+)
+
+var (
+	ErrUnknownScheduleType = fmt.Errorf("unknown schedule type")
+)
+
+// Value returns the value of the message of the schedule
+func Value(s schedule.Schedule) ([]byte, error) {
+	switch sch := s.(type) {
+	case *kafka.Schedule:
+		if sch.Message == nil {
+			return nil, nil
+		}
+		return sch.Value, nil
+	case kafka.Schedule:
+		if sch.Message == nil {
+			return nil, nil
+		}
+		return sch.Value, nil
+	case rest.Schedule:
+		return sch.MessageValue, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnknownScheduleType, s)
+	}
+}
+
+// WithValue returns a copy of the schedule with the value, the input schedule is unchanged
+func WithValue(s schedule.Schedule, value []byte) (schedule.Schedule, error) {
+	switch sch := s.(type) {
+	case *kafka.Schedule:
+		return &kafka.Schedule{Message: withValue(sch.Message, value)}, nil
+	case kafka.Schedule:
+		return kafka.Schedule{Message: withValue(sch.Message, value)}, nil
+	case rest.Schedule:
+		sch.MessageValue = value
+		return sch, nil
+	default:
+		return s, fmt.Errorf("%w: %T", ErrUnknownScheduleType, s)
+	}
+}
+
+// TargetTopic returns the target topic of the message of the schedule, empty for the other types of schedules
+func TargetTopic(s schedule.Schedule) string {
+	switch sch := s.(type) {
+	case *kafka.Schedule:
+		if sch.Message != nil {
+			return sch.TargetTopic()
+		}
+	case kafka.Schedule:
+		if sch.Message != nil {
+			return sch.TargetTopic()
+		}
+	case rest.Schedule:
+		return sch.MessageTargetTopic
+	}
+	return ""
+}
+
+// Topic returns the topic of the schedule, empty for the other types of schedules
+func Topic(s schedule.Schedule) string {
+	switch sch := s.(type) {
+	case *kafka.Schedule:
+		if sch.Message != nil {
+			return sch.Topic()
+		}
+	case kafka.Schedule:
+		if sch.Message != nil {
+			return sch.Topic()
+		}
+	case rest.Schedule:
+		return sch.MessageTopic
+	}
+	return ""
+}
+
+// TargetKey returns the target key of the message of the schedule, empty for the other types of schedules
+func TargetKey(s schedule.Schedule) string {
+	switch sch := s.(type) {
+	case *kafka.Schedule:
+		if sch.Message != nil {
+			return sch.TargetKey()
+		}
+	case kafka.Schedule:
+		if sch.Message != nil {
+			return sch.TargetKey()
+		}
+	case rest.Schedule:
+		return sch.MessageTargetKey
+	}
+	return ""
+}
+
+func withValue(msg *confluent.Message, value []byte) *confluent.Message {
+	result := confluent.Message{}
+	if msg != nil {
+		result = *msg
+	}
+	result.Value = value
+	return &result
+}
@@ -0,0 +1,42 @@
+package schemadecoder
+
+import (
+	"fmt"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemaregistry"
+	"github.com/linkedin/goavro/v2"
+)
+
+type avroCodec struct {
+	*goavro.Codec
+}
+
+// NewAvroDecoder returns a decoder of the avro messages, the decoded value is the JSON encoding of avro
+// (the values of the unions are wrapped in an object with the name of their type)
+func NewAvroDecoder(source schemaregistry.Source) Decoder {
+	return newDecoder("avro", schemaregistry.Avro, source, compileAvro)
+}
+
+func compileAvro(_ schemaregistry.Source, schema schemaregistry.Schema) (codec, error) {
+	// the named types of the references would have to be inlined in the schema
+	if len(schema.References) > 0 {
+		return nil, fmt.Errorf("avro schema references are not supported")
+	}
+
+	c, err := goavro.NewCodec(schema.Definition)
+	if err != nil {
+		return nil, err
+	}
+	return avroCodec{c}, nil
+}
+
+func (c avroCodec) decode(payload []byte) ([]byte, error) {
+	native, rest, err := c.NativeFromBinary(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%v trailing bytes", len(rest))
+	}
+	return c.TextualFromNative(nil, native)
+}
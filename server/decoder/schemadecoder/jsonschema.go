@@ -0,0 +1,69 @@
+package schemadecoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemaregistry"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// base url of the schemas in the compiler, the references are relative to the schema
+const schemasURL = "registry://schemas/"
+
+type jsonCodec struct {
+	*jsonschema.Schema
+}
+
+// NewJSONSchemaDecoder returns a decoder of the JSON messages, the decoded value is the JSON
+// payload when it is valid against its schema
+func NewJSONSchemaDecoder(source schemaregistry.Source) Decoder {
+	return newDecoder("jsonschema", schemaregistry.JSON, source, compileJSONSchema)
+}
+
+func compileJSONSchema(source schemaregistry.Source, schema schemaregistry.Schema) (codec, error) {
+	refs := map[string]string{}
+	if err := references(source, schema, refs); err != nil {
+		return nil, err
+	}
+
+	c := jsonschema.NewCompiler()
+	// the schemas are never loaded from their url
+	c.LoadURL = func(u string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("unknown schema %v", u)
+	}
+
+	for name, definition := range refs {
+		if err := c.AddResource(schemasURL+strings.TrimPrefix(name, "/"), strings.NewReader(definition)); err != nil {
+			return nil, fmt.Errorf("invalid reference %v: %w", name, err)
+		}
+	}
+
+	u := fmt.Sprintf("%v%v.json", schemasURL, schema.ID)
+	if err := c.AddResource(u, strings.NewReader(schema.Definition)); err != nil {
+		return nil, err
+	}
+
+	s, err := c.Compile(u)
+	if err != nil {
+		return nil, err
+	}
+	return jsonCodec{s}, nil
+}
+
+func (c jsonCodec) decode(payload []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(v); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,92 @@
+package schemadecoder
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemaregistry"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+type protobufCodec struct {
+	file *desc.FileDescriptor
+}
+
+// NewProtobufDecoder returns a decoder of the protobuf messages, the decoded value is the JSON encoding of protobuf
+func NewProtobufDecoder(source schemaregistry.Source) Decoder {
+	return newDecoder("protobuf", schemaregistry.Protobuf, source, compileProtobuf)
+}
+
+func compileProtobuf(source schemaregistry.Source, schema schemaregistry.Schema) (codec, error) {
+	files := map[string]string{}
+	if err := references(source, schema, files); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%v.proto", schema.ID)
+	files[name] = schema.Definition
+
+	// the imports which are not references are the well known types, included by the parser
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(files),
+	}
+	fds, err := parser.ParseFiles(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return protobufCodec{fds[0]}, nil
+}
+
+func (c protobufCodec) decode(payload []byte) ([]byte, error) {
+	md, payload, err := c.message(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamic.NewMessage(md)
+	if err := msg.Unmarshal(payload); err != nil {
+		return nil, err
+	}
+	return msg.MarshalJSON()
+}
+
+// message reads the indexes of the message type in the schema, which precede the payload:
+// the number of indexes then the indexes (zigzag varints), a single 0 is the first message type
+func (c protobufCodec) message(payload []byte) (*desc.MessageDescriptor, []byte, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, nil, fmt.Errorf("invalid message indexes")
+	}
+	payload = payload[n:]
+	// each index takes at least one byte, the count comes from the message and cannot be trusted
+	if count > int64(len(payload)) {
+		return nil, nil, fmt.Errorf("invalid message indexes: %v indexes for %v bytes", count, len(payload))
+	}
+
+	indexes := []int64{0}
+	if count > 0 {
+		indexes = make([]int64, count)
+		for i := range indexes {
+			indexes[i], n = binary.Varint(payload)
+			if n <= 0 {
+				return nil, nil, fmt.Errorf("invalid message indexes")
+			}
+			payload = payload[n:]
+		}
+	}
+
+	types := c.file.GetMessageTypes()
+	var md *desc.MessageDescriptor
+	for _, i := range indexes {
+		if i < 0 || int(i) >= len(types) {
+			return nil, nil, fmt.Errorf("message index %v not found in schema", indexes)
+		}
+		md = types[i]
+		types = md.GetNestedMessageTypes()
+	}
+
+	return md, payload, nil
+}
@@ -0,0 +1,124 @@
+package schemadecoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/payload"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemaregistry"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+const (
+	// first byte of the messages serialized with a schema of the registry (Confluent wire format)
+	magicByte = 0
+	// magic byte followed by the schema id on 4 bytes (big endian)
+	headerSize = 5
+)
+
+// codec decodes the payload of the messages serialized with a schema, the payload is the message value without the header
+type codec interface {
+	decode(payload []byte) ([]byte, error)
+}
+
+// Decoder decodes the messages serialized with the schemas of a type (Confluent wire format),
+// the decoded value is JSON. The messages without the magic byte, or serialized with a schema
+// of another type, are not supported by the decoder (decoder.ErrNotSupported).
+type Decoder struct {
+	name       string
+	schemaType string
+	source     schemaregistry.Source
+	compile    func(source schemaregistry.Source, s schemaregistry.Schema) (codec, error)
+	// codecs by schema id
+	codecs *sync.Map
+}
+
+func newDecoder(name, schemaType string, source schemaregistry.Source, compile func(schemaregistry.Source, schemaregistry.Schema) (codec, error)) Decoder {
+	return Decoder{
+		name:       name,
+		schemaType: schemaType,
+		source:     source,
+		compile:    compile,
+		codecs:     &sync.Map{},
+	}
+}
+
+// Decode returns a copy of the input schedule, its value replaced by the decoded value
+func (d Decoder) Decode(s schedule.Schedule) (schedule.Schedule, error) {
+	result, err := d.decode(s)
+	if err != nil && !errors.Is(err, decoder.ErrNotSupported) {
+		metrics.DecoderFailures.WithLabelValues(d.name).Inc()
+	}
+	return result, err
+}
+
+func (d Decoder) decode(s schedule.Schedule) (schedule.Schedule, error) {
+	value, err := payload.Value(s)
+	if err != nil {
+		return s, err
+	}
+	if len(value) == 0 {
+		return s, nil
+	}
+
+	if len(value) < headerSize || value[0] != magicByte {
+		return s, fmt.Errorf("%w: no schema id", decoder.ErrNotSupported)
+	}
+	id := int(binary.BigEndian.Uint32(value[1:headerSize]))
+
+	c, err := d.codec(id)
+	if err != nil {
+		return s, err
+	}
+
+	decoded, err := c.decode(value[headerSize:])
+	if err != nil {
+		return s, fmt.Errorf("cannot decode message with schema %v: %w", id, err)
+	}
+
+	return payload.WithValue(s, decoded)
+}
+
+func (d Decoder) codec(id int) (codec, error) {
+	if c, ok := d.codecs.Load(id); ok {
+		return c.(codec), nil
+	}
+
+	schema, err := d.source.Schema(id)
+	if err != nil {
+		return nil, err
+	}
+	if schema.Type != d.schemaType {
+		return nil, fmt.Errorf("%w: schema %v is %v", decoder.ErrNotSupported, id, schema.Type)
+	}
+
+	c, err := d.compile(d.source, schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v schema %v: %w", schema.Type, id, err)
+	}
+	d.codecs.Store(id, c)
+
+	return c, nil
+}
+
+// references returns the definitions of the schemas imported by the schema and by its references, by import name
+func references(source schemaregistry.Source, schema schemaregistry.Schema, result map[string]string) error {
+	for _, ref := range schema.References {
+		if _, found := result[ref.Name]; found {
+			continue
+		}
+		s, err := source.Reference(ref)
+		if err != nil {
+			return fmt.Errorf("cannot get reference %v: %w", ref.Name, err)
+		}
+		result[ref.Name] = s.Definition
+		if err := references(source, s, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
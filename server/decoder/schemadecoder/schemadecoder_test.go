@@ -0,0 +1,230 @@
+package schemadecoder_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/payload"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemadecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemaregistry"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	avroSchema = `{"type": "record", "name": "Video", "fields": [{"name": "id", "type": "string"}, {"name": "duration", "type": "int"}]}`
+	// the first message type is a reference, the messages can be encoded with Video or Video.Chapter
+	protoSchema = `syntax = "proto3";
+package videos;
+import "common.proto";
+import "google/protobuf/timestamp.proto";
+message Video {
+  string id = 1;
+  int32 duration = 2;
+  common.Status status = 3;
+  message Chapter {
+    string title = 1;
+  }
+  google.protobuf.Timestamp published = 4;
+}`
+	commonSchema = `syntax = "proto3";
+package common;
+enum Status {
+  DRAFT = 0;
+  PUBLISHED = 1;
+}`
+	jsonSchema = `{"$schema": "http://json-schema.org/draft-07/schema#", "type": "object",
+		"properties": {"id": {"type": "string"}, "duration": {"$ref": "duration.json"}}, "required": ["id"]}`
+	durationSchema = `{"type": "integer", "minimum": 0}`
+)
+
+// newFakeRegistry serves the schemas like a schema registry
+func newFakeRegistry() *httptest.Server {
+	quote := func(s string) string {
+		return fmt.Sprintf("%q", s)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			fmt.Fprintf(w, `{"schema": %v}`, quote(avroSchema))
+		case "/schemas/ids/2":
+			fmt.Fprintf(w, `{"schemaType": "PROTOBUF", "schema": %v, "references": [{"name": "common.proto", "subject": "common", "version": 1}]}`, quote(protoSchema))
+		case "/subjects/common/versions/1":
+			fmt.Fprintf(w, `{"schemaType": "PROTOBUF", "schema": %v}`, quote(commonSchema))
+		case "/schemas/ids/3":
+			fmt.Fprintf(w, `{"schemaType": "JSON", "schema": %v, "references": [{"name": "duration.json", "subject": "duration", "version": 1}]}`, quote(jsonSchema))
+		case "/subjects/duration/versions/1":
+			fmt.Fprintf(w, `{"schemaType": "JSON", "schema": %v}`, quote(durationSchema))
+		case "/schemas/ids/4":
+			fmt.Fprintf(w, `{"schema": "invalid"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// wire returns the value in the Confluent wire format
+func wire(id uint32, payload ...[]byte) []byte {
+	result := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(result[1:], id)
+	for _, p := range payload {
+		result = append(result, p...)
+	}
+	return result
+}
+
+// varint returns the zigzag varint of the message indexes
+func varint(i int64) []byte {
+	b := make([]byte, binary.MaxVarintLen64)
+	return b[:binary.PutVarint(b, i)]
+}
+
+func avroPayload(t *testing.T, native map[string]interface{}) []byte {
+	codec, err := goavro.NewCodec(avroSchema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := codec.BinaryFromNative(nil, native)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b
+}
+
+func equalJSON(t *testing.T, actual []byte, expected string) bool {
+	if len(actual) == 0 || expected == "" {
+		return len(actual) == 0 && expected == ""
+	}
+
+	var a, e interface{}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		t.Errorf("invalid JSON %s: %v", actual, err)
+		return false
+	}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatalf("invalid JSON %s: %v", expected, err)
+	}
+	return reflect.DeepEqual(a, e)
+}
+
+func newSchedule(value []byte) *kafka.Schedule {
+	topic := "schedules"
+	return &kafka.Schedule{
+		Message: &confluent.Message{
+			TopicPartition: confluent.TopicPartition{Topic: &topic},
+			Key:            []byte("video-1"),
+			Value:          value,
+		},
+	}
+}
+
+// Rule #1: decoders should decode the messages serialized with their type of schema
+func TestDecoders(t *testing.T) {
+	srv := newFakeRegistry()
+	defer srv.Close()
+
+	registry := schemaregistry.NewRegistry(srv.URL, time.Second)
+	avro := schemadecoder.NewAvroDecoder(registry)
+	protobuf := schemadecoder.NewProtobufDecoder(registry)
+	jsonschema := schemadecoder.NewJSONSchemaDecoder(registry)
+
+	// Video: id=video-1, duration=120, status=PUBLISHED, published=1970-01-01T00:00:10Z
+	video := []byte{0x0a, 0x07, 'v', 'i', 'd', 'e', 'o', '-', '1', 0x10, 0x78, 0x18, 0x01, 0x22, 0x02, 0x08, 0x0a}
+	// Video.Chapter: title=intro
+	chapter := []byte{0x0a, 0x05, 'i', 'n', 't', 'r', 'o'}
+
+	tests := []struct {
+		decoder       schemadecoder.Decoder
+		value         []byte
+		expectedValue string
+		expectedErr   error
+	}{
+		{avro, wire(1, avroPayload(t, map[string]interface{}{"id": "video-1", "duration": 120})), `{"id":"video-1","duration":120}`, nil},
+		// message indexes: single 0 is the first message type
+		{protobuf, wire(2, []byte{0}, video), `{"id":"video-1","duration":120,"status":"PUBLISHED","published":"1970-01-01T00:00:10Z"}`, nil},
+		// message indexes: [0, 0] is the first nested type of the first message type
+		{protobuf, wire(2, []byte{4, 0, 0}, chapter), `{"title":"intro"}`, nil},
+		// more message indexes than bytes in the message
+		{protobuf, wire(2, varint(1<<40), video), "", errors.New("invalid")},
+		{protobuf, wire(2, varint(1<<62)), "", errors.New("invalid")},
+		{jsonschema, wire(3, []byte(`{"id": "video-1", "duration": 120}`)), `{"id": "video-1", "duration": 120}`, nil},
+		// invalid against the referenced schema
+		{jsonschema, wire(3, []byte(`{"id": "video-1", "duration": -1}`)), "", errors.New("invalid")},
+		// not the type of the decoder
+		{avro, wire(2, []byte{0}, video), "", decoder.ErrNotSupported},
+		{protobuf, wire(3, []byte(`{}`)), "", decoder.ErrNotSupported},
+		// no magic byte
+		{avro, []byte(`{"id": "video-1"}`), "", decoder.ErrNotSupported},
+		// invalid schema, unknown schema, invalid payload
+		{avro, wire(4, []byte{0}), "", errors.New("invalid")},
+		{avro, wire(5, []byte{0}), "", schemaregistry.ErrSchemaNotFound},
+		{avro, wire(1, []byte{0xff}), "", errors.New("invalid")},
+		// empty value (deleted schedule)
+		{avro, nil, "", nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			sch := newSchedule(tt.value)
+
+			result, err := tt.decoder.Decode(sch)
+			if tt.expectedErr != nil {
+				if err == nil || (tt.expectedErr.Error() != "invalid" && !errors.Is(err, tt.expectedErr)) {
+					t.Errorf("unexpected error: %v, expected %v", err, tt.expectedErr)
+				}
+				if result != sch {
+					t.Errorf("schedule should be returned unchanged")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			value, _ := payload.Value(result)
+			if !equalJSON(t, value, tt.expectedValue) {
+				t.Errorf("unexpected value: %s, expected %s", value, tt.expectedValue)
+			}
+			// the input schedule is unchanged
+			if string(sch.Value) != string(tt.value) {
+				t.Errorf("input schedule should not be changed")
+			}
+		})
+	}
+}
+
+// Rule #2: decoders should support the local schema files and the rest schedules
+func TestDecoders_directory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "1.avsc"), []byte(avroSchema), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	avro := schemadecoder.NewAvroDecoder(schemaregistry.Directory(dir))
+
+	var sch schedule.Schedule = rest.Schedule{
+		ScheduleID:   "video-1",
+		MessageValue: wire(1, avroPayload(t, map[string]interface{}{"id": "video-1", "duration": 60})),
+	}
+
+	result, err := avro.Decode(sch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v := result.(rest.Schedule).MessageValue; !equalJSON(t, v, `{"id":"video-1","duration":60}`) {
+		t.Errorf("unexpected value: %s", v)
+	}
+}
@@ -0,0 +1,228 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// types of the schemas, as named by the schema registry
+const (
+	Avro     = "AVRO"
+	Protobuf = "PROTOBUF"
+	JSON     = "JSON"
+)
+
+const (
+	DefaultTimeout = 2 * time.Second
+)
+
+var (
+	ErrSchemaNotFound = errors.New("schema not found")
+)
+
+// Reference is a schema imported by another schema, the name is the name used by the import
+// (ie: the file name of a protobuf import)
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+type Schema struct {
+	ID         int         `json:"id"`
+	Type       string      `json:"schemaType"`
+	Definition string      `json:"schema"`
+	References []Reference `json:"references"`
+}
+
+// Source returns the schemas used by the decoders
+type Source interface {
+	// Schema returns the schema with the id of the messages
+	Schema(id int) (Schema, error)
+	// Reference returns the schema imported by another schema
+	Reference(ref Reference) (Schema, error)
+}
+
+// Registry is a client of a schema registry, compatible with the Confluent Schema Registry API.
+// The schemas are immutable, they are cached.
+type Registry struct {
+	url    string
+	client *http.Client
+	cache  *sync.Map
+}
+
+// NewRegistry returns a client of the registry, the credentials of the basic authentication can be set in the url
+func NewRegistry(registryURL string, timeout time.Duration) *Registry {
+	return &Registry{
+		url: strings.TrimSuffix(registryURL, "/"),
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		cache: &sync.Map{},
+	}
+}
+
+func (r *Registry) Schema(id int) (Schema, error) {
+	return r.get(fmt.Sprintf("/schemas/ids/%v", id), func(s *Schema) {
+		s.ID = id
+	})
+}
+
+func (r *Registry) Reference(ref Reference) (Schema, error) {
+	return r.get(fmt.Sprintf("/subjects/%v/versions/%v", url.PathEscape(ref.Subject), ref.Version), nil)
+}
+
+func (r *Registry) get(path string, complete func(s *Schema)) (Schema, error) {
+	if s, ok := r.cache.Load(path); ok {
+		return s.(Schema), nil
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, r.url+path, http.NoBody)
+	if err != nil {
+		return Schema{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Schema{}, fmt.Errorf("cannot get schema %v: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Schema{}, fmt.Errorf("%w: %v", ErrSchemaNotFound, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Schema{}, fmt.Errorf("cannot get schema %v: %v %s", path, resp.StatusCode, body)
+	}
+
+	var s Schema
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return Schema{}, fmt.Errorf("invalid schema %v: %w", path, err)
+	}
+	// the type is omitted for the avro schemas
+	if s.Type == "" {
+		s.Type = Avro
+	}
+	if complete != nil {
+		complete(&s)
+	}
+
+	r.cache.Store(path, s)
+
+	return s, nil
+}
+
+// Directory returns the schemas stored in the files of a directory, the file of a schema is named
+// with its id and the extension of its type: 1.avsc (avro), 2.proto (protobuf) or 3.json (JSON schema).
+// The references are the paths of the imported files, relative to the directory.
+type Directory string
+
+var extensions = map[string]string{
+	".avsc":  Avro,
+	".proto": Protobuf,
+	".json":  JSON,
+}
+
+func (d Directory) Schema(id int) (Schema, error) {
+	for ext, schemaType := range extensions {
+		data, err := os.ReadFile(filepath.Join(string(d), fmt.Sprintf("%v%v", id, ext)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Schema{}, err
+		}
+		return Schema{
+			ID:         id,
+			Type:       schemaType,
+			Definition: string(data),
+			References: d.imports(schemaType, data),
+		}, nil
+	}
+	return Schema{}, fmt.Errorf("%w: %v in %v", ErrSchemaNotFound, id, d)
+}
+
+func (d Directory) Reference(ref Reference) (Schema, error) {
+	path := filepath.Join(string(d), filepath.Clean("/"+ref.Name))
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Schema{}, fmt.Errorf("%w: %v", ErrSchemaNotFound, path)
+	}
+	if err != nil {
+		return Schema{}, err
+	}
+
+	schemaType := extensions[filepath.Ext(path)]
+	return Schema{
+		Type:       schemaType,
+		Definition: string(data),
+		References: d.imports(schemaType, data),
+	}, nil
+}
+
+// imports returns the files imported by a protobuf schema which exist in the directory,
+// the other imports are the well known types of protobuf
+func (d Directory) imports(schemaType string, data []byte) []Reference {
+	if schemaType != Protobuf {
+		return nil
+	}
+
+	result := []Reference{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "import ") {
+			continue
+		}
+		fields := strings.Split(line, `"`)
+		if len(fields) < 3 {
+			continue
+		}
+		name := fields[1]
+		if _, err := os.Stat(filepath.Join(string(d), filepath.Clean("/"+name))); err == nil {
+			result = append(result, Reference{Name: name})
+		}
+	}
+	return result
+}
+
+// Sources returns the schemas of the first source which has them
+type Sources []Source
+
+func (s Sources) Schema(id int) (Schema, error) {
+	return s.find(func(src Source) (Schema, error) {
+		return src.Schema(id)
+	})
+}
+
+func (s Sources) Reference(ref Reference) (Schema, error) {
+	return s.find(func(src Source) (Schema, error) {
+		return src.Reference(ref)
+	})
+}
+
+func (s Sources) find(get func(src Source) (Schema, error)) (Schema, error) {
+	err := ErrSchemaNotFound
+	for _, src := range s {
+		var schema Schema
+		schema, err = get(src)
+		if err == nil {
+			return schema, nil
+		}
+		if !errors.Is(err, ErrSchemaNotFound) {
+			return Schema{}, err
+		}
+	}
+	return Schema{}, err
+}
@@ -0,0 +1,146 @@
+package schemaregistry_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemaregistry"
+)
+
+// newFakeRegistry serves the schemas like a schema registry and counts the requests
+func newFakeRegistry(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			fmt.Fprint(w, `{"schema": "\"string\""}`)
+		case "/schemas/ids/2":
+			fmt.Fprint(w, `{"schemaType": "PROTOBUF", "schema": "syntax = \"proto3\";", "references": [{"name": "common.proto", "subject": "common", "version": 3}]}`)
+		case "/subjects/common/versions/3":
+			fmt.Fprint(w, `{"subject": "common", "version": 3, "id": 5, "schemaType": "PROTOBUF", "schema": "syntax = \"proto3\";"}`)
+		case "/schemas/ids/3":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error_code": 40403, "message": "Schema not found"}`)
+		}
+	}))
+}
+
+// Rule #1: registry should return the schemas and their references, and cache them
+func TestRegistry(t *testing.T) {
+	var requests int32
+	srv := newFakeRegistry(&requests)
+	defer srv.Close()
+
+	r := schemaregistry.NewRegistry(srv.URL+"/", time.Second)
+
+	for i := 0; i < 2; i++ {
+		s, err := r.Schema(1)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if s.ID != 1 || s.Type != schemaregistry.Avro || s.Definition != `"string"` {
+			t.Errorf("unexpected schema: %+v", s)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("schema should be cached: %v requests", requests)
+	}
+
+	s, err := r.Schema(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Type != schemaregistry.Protobuf || len(s.References) != 1 {
+		t.Fatalf("unexpected schema: %+v", s)
+	}
+	ref, err := r.Reference(s.References[0])
+	if err != nil || ref.Type != schemaregistry.Protobuf || ref.Definition != `syntax = "proto3";` {
+		t.Errorf("unexpected reference: %+v %v", ref, err)
+	}
+
+	if _, err := r.Schema(4); !errors.Is(err, schemaregistry.ErrSchemaNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := r.Schema(3); err == nil || errors.Is(err, schemaregistry.ErrSchemaNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// Rule #2: directory should return the schemas of the files named with their id,
+// and sources should return the schema of the first source which has it
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"1.avsc":       `"string"`,
+		"2.proto":      "syntax = \"proto3\";\nimport \"common.proto\";\nimport \"google/protobuf/timestamp.proto\";",
+		"common.proto": `syntax = "proto3";`,
+		"3.json":       `{"type": "object"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	d := schemaregistry.Directory(dir)
+
+	tests := []struct {
+		id           int
+		expectedType string
+		expectedRefs string
+	}{
+		{1, schemaregistry.Avro, "[]"},
+		{2, schemaregistry.Protobuf, "[{common.proto  0}]"},
+		{3, schemaregistry.JSON, "[]"},
+	}
+
+	for i, tt := range tests {
+		s, err := d.Schema(tt.id)
+		if err != nil {
+			t.Fatalf("case #%v: unexpected error: %v", i+1, err)
+		}
+		if s.ID != tt.id || s.Type != tt.expectedType || s.Definition == "" {
+			t.Errorf("case #%v: unexpected schema: %+v", i+1, s)
+		}
+		if refs := fmt.Sprint(append([]schemaregistry.Reference{}, s.References...)); refs != tt.expectedRefs {
+			t.Errorf("case #%v: unexpected references: %v, expected %v", i+1, refs, tt.expectedRefs)
+		}
+	}
+
+	// the references are confined to the directory
+	ref, err := d.Reference(schemaregistry.Reference{Name: "../../common.proto"})
+	if err != nil || ref.Definition != files["common.proto"] {
+		t.Errorf("unexpected reference: %+v %v", ref, err)
+	}
+	if _, err := d.Reference(schemaregistry.Reference{Name: "other.proto"}); !errors.Is(err, schemaregistry.ErrSchemaNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var requests int32
+	srv := newFakeRegistry(&requests)
+	defer srv.Close()
+
+	sources := schemaregistry.Sources{d, schemaregistry.NewRegistry(srv.URL, time.Second)}
+
+	s, err := sources.Schema(3)
+	if err != nil || s.Type != schemaregistry.JSON || requests != 0 {
+		t.Errorf("unexpected schema: %+v %v", s, err)
+	}
+	// not found in the directory
+	s, err = sources.Reference(schemaregistry.Reference{Name: "other.proto", Subject: "common", Version: 3})
+	if err != nil || s.Definition != `syntax = "proto3";` || requests != 1 {
+		t.Errorf("unexpected schema: %+v %v", s, err)
+	}
+	if _, err := sources.Schema(10); !errors.Is(err, schemaregistry.ErrSchemaNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
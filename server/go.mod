@@ -11,16 +11,23 @@ require (
 	github.com/google/go-cmp v0.5.5 // indirect
 	github.com/gorilla/mux v1.8.0
 	github.com/influxdata/influxdb v1.8.5 // indirect
+	github.com/jhump/protoreflect v1.9.0
 	github.com/kr/pretty v0.2.0 // indirect
+	github.com/linkedin/goavro/v2 v2.9.8
 	github.com/prometheus/client_golang v1.8.0
 	github.com/rs/cors v1.7.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.0.0
 	github.com/sergi/go-diff v1.0.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/stretchr/testify v1.6.1 // indirect
 	github.com/tevjef/go-runtime-metrics v0.0.0-20170326170900-527a54029307
+	github.com/yuin/gopher-lua v0.0.0-20210529063254-f4c35e4016d9
 	go.etcd.io/bbolt v1.3.5
-	golang.org/x/sys v0.0.0-20210304124612-50617c2ba197 // indirect
-	google.golang.org/protobuf v1.25.0 // indirect
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 //replace github.com/etf1/kafka-message-scheduler => /Users/fkarakas/go/src/github.com/etf1/kafka-message-scheduler
@@ -0,0 +1,352 @@
+package mask
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// Replacement replaces the masked values
+	Replacement = "****"
+	// AllSchedulers is the scheduler name of the rules applied to all the schedulers
+	AllSchedulers = "*"
+	// AnyKey matches any key of an object or any element of an array in a path
+	AnyKey = "*"
+	// AnyDepth matches any number of levels in a path
+	AnyDepth = "**"
+)
+
+// Rule masks the values matching the JSON paths, the text matching the patterns
+// and the values of the kafka headers of the schedules of the schedulers
+type Rule struct {
+	// scheduler names, the rule is applied to all the schedulers when empty
+	Schedulers []string `yaml:"schedulers"`
+	// JSON paths of the masked values of the message body, ie: customer.email, cards[*].number, **.password
+	Paths []string `yaml:"paths"`
+	// regular expressions of the masked text of the message body and of the fields extracted by the decoder
+	Patterns []string `yaml:"patterns"`
+	// names of the masked kafka headers, ie: scheduler-target-key
+	Headers []string `yaml:"headers"`
+}
+
+type file struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// compiled rule
+type rule struct {
+	paths    [][]string
+	patterns []*regexp.Regexp
+	headers  map[string]bool
+}
+
+// Masker masks the sensitive data of the schedules returned by the API, with the rules of the schedulers.
+// A nil masker returns the schedules unmasked.
+type Masker struct {
+	// rules by scheduler name, AllSchedulers for the rules applied to all the schedulers
+	rules map[string][]rule
+}
+
+// Load loads the rules of a YAML or JSON file
+func Load(path string) (*Masker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid masking rules file %v: %w", path, err)
+	}
+
+	m, err := New(f.Rules...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid masking rules file %v: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// New returns a masker with the rules
+func New(rules ...Rule) (*Masker, error) {
+	m := &Masker{rules: make(map[string][]rule)}
+
+	for i, r := range rules {
+		compiled := rule{headers: make(map[string]bool)}
+		for _, p := range r.Paths {
+			compiled.paths = append(compiled.paths, parsePath(p))
+		}
+		for _, p := range r.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("rule #%v: invalid pattern %q: %w", i+1, p, err)
+			}
+			compiled.patterns = append(compiled.patterns, re)
+		}
+		for _, h := range r.Headers {
+			compiled.headers[h] = true
+		}
+
+		names := r.Schedulers
+		if len(names) == 0 {
+			names = []string{AllSchedulers}
+		}
+		for _, name := range names {
+			m.rules[name] = append(m.rules[name], compiled)
+		}
+	}
+
+	return m, nil
+}
+
+// Mask returns a copy of the schedule with the sensitive data masked, the input schedule is unchanged.
+// The scheduler name of a store.Schedule, when set, has priority on schedulerName.
+func (m *Masker) Mask(schedulerName string, s schedule.Schedule) schedule.Schedule {
+	if m == nil || s == nil {
+		return s
+	}
+
+	if sch, ok := s.(store.Schedule); ok {
+		if sch.SchedulerName != "" {
+			schedulerName = sch.SchedulerName
+		}
+		sch.Schedule = m.Mask(schedulerName, sch.Schedule)
+		return sch
+	}
+
+	rules := m.rulesOf(schedulerName)
+	if len(rules) == 0 {
+		return s
+	}
+
+	return mask(rules, s)
+}
+
+// MasksValue returns true when the message values of the scheduler may be masked, by a path or a pattern
+func (m *Masker) MasksValue(schedulerName string) bool {
+	for _, r := range m.rulesOf(schedulerName) {
+		if len(r.paths) > 0 || len(r.patterns) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// MasksHeader returns true when the kafka header of the messages of the scheduler is masked
+func (m *Masker) MasksHeader(schedulerName, name string) bool {
+	for _, r := range m.rulesOf(schedulerName) {
+		if r.headers[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesOf returns the rules of the scheduler and the rules applied to all the schedulers
+func (m *Masker) rulesOf(schedulerName string) []rule {
+	if m == nil {
+		return nil
+	}
+	rules := make([]rule, 0, len(m.rules[schedulerName])+len(m.rules[AllSchedulers]))
+	rules = append(rules, m.rules[schedulerName]...)
+	rules = append(rules, m.rules[AllSchedulers]...)
+	return rules
+}
+
+func mask(rules []rule, s schedule.Schedule) schedule.Schedule {
+	switch sch := s.(type) {
+	case decoder.Failed:
+		sch.Schedule = mask(rules, sch.Schedule)
+		return sch
+	case decoder.WithFields:
+		sch.Schedule = mask(rules, sch.Schedule)
+		sch.Fields = maskFields(rules, sch.Fields)
+		return sch
+	case *kafka.Schedule:
+		if sch == nil || sch.Message == nil {
+			return s
+		}
+		return &kafka.Schedule{Message: maskMessage(rules, sch.Message)}
+	case kafka.Schedule:
+		if sch.Message == nil {
+			return s
+		}
+		return kafka.Schedule{Message: maskMessage(rules, sch.Message)}
+	case bbolt.Schedule:
+		sch.Value = maskValue(rules, sch.Value)
+		sch.TargetTopic = maskHeader(rules, kafka.TargetTopic, sch.TargetTopic)
+		sch.TargetKey = maskHeader(rules, kafka.TargetKey, sch.TargetKey)
+		sch.Fields = maskFields(rules, sch.Fields)
+		if len(sch.Headers) > 0 {
+			headers := make([]bbolt.Header, len(sch.Headers))
+			for i, h := range sch.Headers {
+				headers[i] = bbolt.Header{Key: h.Key, Value: maskHeader(rules, h.Key, h.Value)}
+			}
+			sch.Headers = headers
+		}
+		return sch
+	case rest.Schedule:
+		sch.MessageValue = maskValue(rules, sch.MessageValue)
+		sch.MessageTargetTopic = maskHeader(rules, kafka.TargetTopic, sch.MessageTargetTopic)
+		sch.MessageTargetKey = maskHeader(rules, kafka.TargetKey, sch.MessageTargetKey)
+		return sch
+	default:
+		return s
+	}
+}
+
+func maskMessage(rules []rule, msg *confluent.Message) *confluent.Message {
+	result := *msg
+	result.Value = maskValue(rules, msg.Value)
+	result.Headers = make([]confluent.Header, len(msg.Headers))
+	for i, h := range msg.Headers {
+		result.Headers[i] = confluent.Header{Key: h.Key, Value: []byte(maskHeader(rules, h.Key, string(h.Value)))}
+	}
+	return &result
+}
+
+func maskHeader(rules []rule, name, value string) string {
+	if value == "" {
+		return value
+	}
+	for _, r := range rules {
+		if r.headers[name] {
+			return Replacement
+		}
+	}
+	return value
+}
+
+func maskFields(rules []rule, fields map[string]string) map[string]string {
+	if len(fields) == 0 {
+		return fields
+	}
+	result := make(map[string]string, len(fields))
+	for k, v := range fields {
+		result[k] = maskText(rules, v)
+	}
+	return result
+}
+
+// maskValue masks the JSON paths when the value is JSON, then the patterns when the value is text,
+// the binary values are returned as is
+func maskValue(rules []rule, value []byte) []byte {
+	if len(value) == 0 || !utf8.Valid(value) {
+		return value
+	}
+
+	result := value
+	if v, ok := maskPaths(rules, value); ok {
+		result = v
+	}
+
+	return []byte(maskText(rules, string(result)))
+}
+
+func maskText(rules []rule, s string) string {
+	for _, r := range rules {
+		for _, re := range r.patterns {
+			s = re.ReplaceAllLiteralString(s, Replacement)
+		}
+	}
+	return s
+}
+
+// maskPaths returns the value with the values of the paths masked, false when the value is not JSON or nothing is masked
+func maskPaths(rules []rule, value []byte) ([]byte, bool) {
+	hasPaths := false
+	for _, r := range rules {
+		hasPaths = hasPaths || len(r.paths) > 0
+	}
+	if !hasPaths {
+		return nil, false
+	}
+
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(value))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, false
+	}
+
+	masked := false
+	for _, r := range rules {
+		for _, p := range r.paths {
+			v = maskPath(v, p, &masked)
+		}
+	}
+	if !masked {
+		return nil, false
+	}
+
+	result, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+func maskPath(v interface{}, path []string, masked *bool) interface{} {
+	if len(path) == 0 {
+		*masked = true
+		return Replacement
+	}
+
+	key, rest := path[0], path[1:]
+
+	if key == AnyDepth {
+		// zero level, then one or more levels
+		v = maskPath(v, rest, masked)
+		if len(rest) == 0 {
+			return v
+		}
+		rest = path
+		key = AnyKey
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if key == AnyKey || key == k {
+				v[k] = maskPath(e, rest, masked)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			if key == AnyKey || key == strconv.Itoa(i) {
+				v[i] = maskPath(e, rest, masked)
+			}
+		}
+	}
+
+	return v
+}
+
+// parsePath splits a path into keys, the brackets are keys: $.cards[*].number is [cards * number]
+func parsePath(p string) []string {
+	p = strings.TrimPrefix(strings.TrimSpace(p), "$")
+	p = strings.NewReplacer("[", ".", "]", "").Replace(p)
+
+	var result []string
+	for _, key := range strings.Split(p, ".") {
+		if key != "" {
+			result = append(result, key)
+		}
+	}
+	return result
+}
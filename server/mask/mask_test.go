@@ -0,0 +1,188 @@
+package mask_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+)
+
+// Rule #1: the values of the paths, the text of the patterns and the headers should be masked
+func TestMasker_Mask(t *testing.T) {
+	m, err := mask.New(
+		mask.Rule{
+			Schedulers: []string{"scheduler-1"},
+			Paths:      []string{"customer.email", "$.cards[*].number", "**.password"},
+			Headers:    []string{kafka.TargetKey},
+		},
+		mask.Rule{
+			Patterns: []string{`\b\d{3}-\d{4}\b`},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value := `{"customer":{"email":"john@example.com","name":"john","phone":"555-1234"},"cards":[{"number":"4111"},{"number":"5500"}],"login":{"user":{"password":"secret"}}}`
+	masked := `{"cards":[{"number":"****"},{"number":"****"}],"customer":{"email":"****","name":"john","phone":"****"},"login":{"user":{"password":"****"}}}`
+
+	tests := []struct {
+		schedulerName string
+		value         string
+		expectedValue string
+		expectedKey   string
+	}{
+		{"scheduler-1", value, masked, mask.Replacement},
+		// only the rules of all the schedulers
+		{"scheduler-2", value, `{"customer":{"email":"john@example.com","name":"john","phone":"****"},"cards":[{"number":"4111"},{"number":"5500"}],"login":{"user":{"password":"secret"}}}`, "target-key"},
+		// not JSON
+		{"scheduler-1", "call 555-1234", "call ****", mask.Replacement},
+		// JSON without masked path, the value is unchanged
+		{"scheduler-1", `{"b":1, "a":2}`, `{"b":1, "a":2}`, mask.Replacement},
+		{"scheduler-1", `{"password":"secret"}`, `{"password":"****"}`, mask.Replacement},
+		{"scheduler-1", `{"password":{"hash":"secret"}}`, `{"password":"****"}`, mask.Replacement},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			schs := []schedule.Schedule{
+				kafka.Schedule{Message: &confluent.Message{
+					Key:     []byte("schedule-1"),
+					Value:   []byte(tt.value),
+					Headers: []confluent.Header{{Key: kafka.TargetTopic, Value: []byte("target-topic")}, {Key: kafka.TargetKey, Value: []byte("target-key")}},
+				}},
+				bbolt.Schedule{ScheduleID: "schedule-1", TargetTopic: "target-topic", TargetKey: "target-key", Value: []byte(tt.value),
+					Headers: []bbolt.Header{{Key: kafka.TargetTopic, Value: "target-topic"}, {Key: kafka.TargetKey, Value: "target-key"}}},
+				rest.Schedule{ScheduleID: "schedule-1", MessageTargetTopic: "target-topic", MessageTargetKey: "target-key", MessageValue: []byte(tt.value)},
+				decoder.Failed{Schedule: rest.Schedule{ScheduleID: "schedule-1", MessageTargetTopic: "target-topic", MessageTargetKey: "target-key", MessageValue: []byte(tt.value)}},
+			}
+
+			for _, s := range schs {
+				result := m.Mask(tt.schedulerName, s)
+
+				fields := fieldsOf(result)
+				if fields.value != tt.expectedValue {
+					t.Errorf("%T: unexpected value: %v", s, fields.value)
+				}
+				if fields.targetKey != tt.expectedKey {
+					t.Errorf("%T: unexpected target key: %v", s, fields.targetKey)
+				}
+				if fields.targetTopic != "target-topic" {
+					t.Errorf("%T: unexpected target topic: %v", s, fields.targetTopic)
+				}
+				// the input schedule is unchanged
+				if v := fieldsOf(s).value; v != tt.value {
+					t.Errorf("%T: input schedule modified: %v", s, v)
+				}
+			}
+		})
+	}
+
+	// scheduler name of the store schedules
+	result := m.Mask("", store.Schedule{
+		SchedulerName: "scheduler-1",
+		Schedule:      rest.Schedule{MessageValue: []byte(`{"password":"secret"}`)},
+	})
+	if v := string(result.(store.Schedule).Schedule.(rest.Schedule).MessageValue); v != `{"password":"****"}` {
+		t.Errorf("unexpected value: %v", v)
+	}
+
+	// fields extracted by the decoder
+	result = m.Mask("scheduler-1", decoder.WithFields{
+		Schedule: rest.Schedule{},
+		Fields:   map[string]string{"phone": "555-1234", "country": "fr"},
+	})
+	if f := result.(decoder.WithFields).Fields; !reflect.DeepEqual(f, map[string]string{"phone": "****", "country": "fr"}) {
+		t.Errorf("unexpected fields: %v", f)
+	}
+
+	// nil masker
+	var nilMasker *mask.Masker
+	s := rest.Schedule{MessageValue: []byte("555-1234")}
+	if result := nilMasker.Mask("scheduler-1", s); !reflect.DeepEqual(result, s) {
+		t.Errorf("unexpected schedule: %v", result)
+	}
+}
+
+type scheduleFields struct {
+	value       string
+	targetTopic string
+	targetKey   string
+}
+
+func fieldsOf(s schedule.Schedule) scheduleFields {
+	switch s := s.(type) {
+	case decoder.Failed:
+		return fieldsOf(s.Schedule)
+	case kafka.Schedule:
+		return scheduleFields{string(s.Value), s.TargetTopic(), s.TargetKey()}
+	case bbolt.Schedule:
+		// the headers are masked like the target topic and key
+		for _, h := range s.Headers {
+			if (h.Key == kafka.TargetTopic && h.Value != s.TargetTopic) || (h.Key == kafka.TargetKey && h.Value != s.TargetKey) {
+				return scheduleFields{}
+			}
+		}
+		return scheduleFields{string(s.Value), s.TargetTopic, s.TargetKey}
+	case rest.Schedule:
+		return scheduleFields{string(s.MessageValue), s.MessageTargetTopic, s.MessageTargetKey}
+	}
+	return scheduleFields{}
+}
+
+// Rule #2: the rules should be loaded from a YAML or JSON file
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		content     string
+		expectedErr bool
+	}{
+		{`
+rules:
+  - schedulers: [scheduler-1]
+    paths: [customer.email]
+    patterns: ['\d{3}-\d{4}']
+    headers: [scheduler-target-key]
+`, false},
+		{`{"rules": [{"paths": ["customer.email"]}]}`, false},
+		{`rules: [{patterns: ["("]}]`, true},
+		{`rules: [{unknown: [a]}]`, true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("rules-%v.yaml", i))
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			m, err := mask.Load(path)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+
+			result := m.Mask("scheduler-1", rest.Schedule{MessageValue: []byte(`{"customer":{"email":"john@example.com"}}`)})
+			if v := string(result.(rest.Schedule).MessageValue); v != `{"customer":{"email":"****"}}` {
+				t.Errorf("unexpected value: %v", v)
+			}
+		})
+	}
+
+	if _, err := mask.Load(filepath.Join(dir, "unknown.yaml")); err == nil {
+		t.Errorf("expected error")
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	namespace = "kafka_message_scheduler_admin"
+)
+
+var (
+	// store events processed by the watcher of a bleve db
+	WatchEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "watch_events_total",
+		Help:      "Number of store events (upsert, delete, reset) processed by the db watcher.",
+	}, []string{"db", "scheduler", "type"})
+
+	// messages consumed from the schedules topics
+	ConsumedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "consumed_messages_total",
+		Help:      "Number of kafka messages consumed.",
+	}, []string{"scheduler", "topic"})
+
+	// difference between the high watermark and the offset of the last consumed message
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "consumer_lag",
+		Help:      "Number of kafka messages not yet consumed per topic partition.",
+	}, []string{"scheduler", "topic", "partition"})
+
+	IndexingBatchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "indexing_batch_duration_seconds",
+		Help:      "Duration of the batch indexing of the bleve db.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"db"})
+
+	DecoderFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decoder_failures_total",
+		Help:      "Number of kafka message bodies which cannot be decoded.",
+	}, []string{"decoder"})
+
+	DecoderCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decoder_cache_requests_total",
+		Help:      "Number of lookups in the cache of the decoded message bodies.",
+	}, []string{"result"})
+
+	DecoderCircuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "decoder_circuit_open",
+		Help:      "1 when the circuit breaker of the decoder is open (decoder not called), 0 otherwise.",
+	}, []string{"decoder"})
+
+	// updated by the overdue checker
+	OverdueSchedules = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "overdue_schedules",
+		Help:      "Number of schedules not triggered with an epoch older than the grace period.",
+	}, []string{"scheduler"})
+)
+
+// SchedulesCollector collects the number of schedules per scheduler in each db,
+// the counts are computed on each scrape (same as the stats route)
+type SchedulesCollector struct {
+	resolver schedulers.Resolver
+	dbs      map[string]db.DB
+	desc     *prometheus.Desc
+}
+
+// NewSchedulesCollector creates a collector for the dbs, the key of the map is the label of the db (live, cold, history)
+func NewSchedulesCollector(resolver schedulers.Resolver, dbs map[string]db.DB) SchedulesCollector {
+	return SchedulesCollector{
+		resolver: resolver,
+		dbs:      dbs,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "schedules"),
+			"Number of schedules per scheduler.",
+			[]string{"scheduler", "db"},
+			nil,
+		),
+	}
+}
+
+func (c SchedulesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c SchedulesCollector) Collect(ch chan<- prometheus.Metric) {
+	schs, err := c.resolver.List()
+	if err != nil {
+		log.Errorf("cannot list schedulers: %v", err)
+		return
+	}
+
+	for _, sch := range schs {
+		for name, d := range c.dbs {
+			total, err := db.Count(d, sch.Name())
+			if err != nil {
+				log.Errorf("cannot count schedules of %v in %v db: %v", sch.Name(), name, err)
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(total), sch.Name(), name)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	simple_schedule "github.com/etf1/kafka-message-scheduler/schedule/simple"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// Rule #1: schedules collector should return the number of schedules per scheduler and db
+func TestSchedulesCollector(t *testing.T) {
+	now := time.Now().Unix()
+
+	cold := hmap.NewStore()
+	history := hmap.NewStore()
+
+	cold.Add("scheduler-1", simple_schedule.NewSchedule("schedule-1", now), simple_schedule.NewSchedule("schedule-2", now))
+	cold.Add("scheduler-2", simple_schedule.NewSchedule("schedule-3", now))
+	history.Add("scheduler-1", simple_schedule.NewSchedule("schedule-1", now))
+
+	resolver := slice.NewResolver()
+	resolver.Add(slice.Scheduler{SchedulerName: "scheduler-1"}, slice.Scheduler{SchedulerName: "scheduler-2"})
+
+	collector := metrics.NewSchedulesCollector(resolver, map[string]db.DB{
+		"cold":    simple.DB{Store: cold},
+		"history": simple.DB{Store: history},
+	})
+
+	expected := `
+# HELP kafka_message_scheduler_admin_schedules Number of schedules per scheduler.
+# TYPE kafka_message_scheduler_admin_schedules gauge
+kafka_message_scheduler_admin_schedules{db="cold",scheduler="scheduler-1"} 2
+kafka_message_scheduler_admin_schedules{db="cold",scheduler="scheduler-2"} 1
+kafka_message_scheduler_admin_schedules{db="history",scheduler="scheduler-1"} 1
+kafka_message_scheduler_admin_schedules{db="history",scheduler="scheduler-2"} 0
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
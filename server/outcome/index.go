@@ -0,0 +1,141 @@
+package outcome
+
+import (
+	"sync"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// trigger is a history entry of a schedule: the epoch triggered and the time of the trigger in milliseconds
+type trigger struct {
+	epoch       int64
+	timestampMs int64
+}
+
+type entry struct {
+	// the history of the schedule has been read, the next triggers come from the history events
+	loaded   bool
+	triggers []trigger
+}
+
+func (e *entry) add(tr trigger) {
+	for _, t := range e.triggers {
+		if t == tr {
+			return
+		}
+	}
+	e.triggers = append(e.triggers, tr)
+}
+
+// index contains the triggers of the schedules, the history of a schedule is read once then updated by the history events.
+// Without history events, the history is read for each outcome.
+type index struct {
+	mutex   sync.Mutex
+	history store.Store
+	watched bool
+	// by scheduler name and schedule id
+	entries map[string]map[string]*entry
+}
+
+func newIndex(history store.Store, watched bool) *index {
+	return &index{
+		history: history,
+		watched: watched,
+		entries: make(map[string]map[string]*entry),
+	}
+}
+
+// watch indexes the triggers of the history events, they are acknowledged once indexed
+// as the index is read again from the history on restart
+func (x *index) watch(watchChan chan store.Event) {
+	for evt := range watchChan {
+		switch evt.EventType {
+		case store.UpsertType:
+			x.add(evt.SchedulerName, evt.Schedule)
+		case store.DeletedType:
+			x.delete(evt.SchedulerName, evt.ID())
+		case store.StoreResetType:
+			x.reset(evt.SchedulerName)
+		}
+		evt.Acknowledge()
+	}
+}
+
+// get returns the triggers of a schedule
+func (x *index) get(schedulerName, scheduleID string) []trigger {
+	if !x.watched {
+		triggers, _ := x.read(schedulerName, scheduleID)
+		return triggers
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	e := x.entry(schedulerName, scheduleID)
+	if !e.loaded {
+		triggers, err := x.read(schedulerName, scheduleID)
+		if err != nil {
+			return nil
+		}
+		for _, tr := range triggers {
+			e.add(tr)
+		}
+		e.loaded = true
+	}
+
+	result := make([]trigger, len(e.triggers))
+	copy(result, e.triggers)
+	return result
+}
+
+// read returns the triggers of the history entries of a schedule
+func (x *index) read(schedulerName, scheduleID string) ([]trigger, error) {
+	entries, err := x.history.Get(schedulerName, scheduleID)
+	if err != nil {
+		log.Errorf("cannot get history of %v: %v", scheduleID, err)
+		return nil, err
+	}
+	result := make([]trigger, 0, len(entries))
+	for _, h := range entries {
+		result = append(result, trigger{epoch: h.Epoch(), timestampMs: timestampMs(h)})
+	}
+	return result, nil
+}
+
+func (x *index) add(schedulerName string, sch store.Schedule) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.entry(schedulerName, sch.ID()).add(trigger{epoch: sch.Epoch(), timestampMs: timestampMs(sch)})
+}
+
+// delete forgets the triggers of a schedule, its history entries are deleted
+func (x *index) delete(schedulerName, scheduleID string) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	*x.entry(schedulerName, scheduleID) = entry{loaded: true}
+}
+
+// reset forgets the triggers of a scheduler, its history is read again
+func (x *index) reset(schedulerName string) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	delete(x.entries, schedulerName)
+}
+
+func (x *index) entry(schedulerName, scheduleID string) *entry {
+	schedules, ok := x.entries[schedulerName]
+	if !ok {
+		schedules = make(map[string]*entry)
+		x.entries[schedulerName] = schedules
+	}
+	e, ok := schedules[scheduleID]
+	if !ok {
+		e = &entry{}
+		schedules[scheduleID] = e
+	}
+	return e
+}
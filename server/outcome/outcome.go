@@ -0,0 +1,474 @@
+package outcome
+
+import (
+	"errors"
+	"fmt"
+	stdsort "sort"
+	"sync"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/sort"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultLateThreshold = 10 * time.Second
+	DefaultMax           = 300
+	ChanSize             = 1000
+	// PurgeInterval is the interval between the purges of the expired tombstones
+	PurgeInterval = time.Hour
+	// ScanPageSize is the number of schedules of the cold DB read at once by a search with statuses
+	ScanPageSize = 500
+)
+
+var ErrInvalidStatus = errors.New("invalid status")
+
+// Status is the outcome of a schedule
+type Status string
+
+const (
+	// not triggered yet, the epoch is not passed (or passed by less than the late threshold)
+	Pending Status = "pending"
+	// triggered by the scheduler, in the late threshold
+	Triggered Status = "triggered"
+	// triggered by the scheduler, after the late threshold
+	TriggeredLate Status = "triggered-late"
+	// deleted (tombstone) before being triggered
+	Cancelled Status = "cancelled"
+	// not triggered, the epoch is passed by more than the late threshold
+	Overdue Status = "overdue"
+)
+
+// Statuses are all the statuses
+var Statuses = []Status{Pending, Triggered, TriggeredLate, Cancelled, Overdue}
+
+// ParseStatus returns the status of its name, ErrInvalidStatus when unknown
+func ParseStatus(s string) (Status, error) {
+	for _, status := range Statuses {
+		if string(status) == s {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %v", ErrInvalidStatus, s)
+}
+
+// Outcome is the status of a schedule, with the time of the trigger and its delay compared to the epoch when triggered
+type Outcome struct {
+	Status Status `json:"status"`
+	// in milliseconds
+	TriggeredAt int64  `json:"triggered-at-ms,omitempty"`
+	Delay       *int64 `json:"delay-ms,omitempty"`
+}
+
+// Config contains the dependencies of the tracker
+// ColdEvents are the events of the cold schedules, the deletions are recorded as cancellations, the events are
+// acknowledged once their cancellation is persisted
+// History is the store of the schedules triggered by the schedulers
+// HistoryEvents are the events of the history, they update the triggers indexed by the tracker,
+// without them the history is read for each outcome
+// CancelledStore records the tombstones of the cancelled schedules
+// LateThreshold is the delay after the epoch from which a triggered schedule is late and a schedule not triggered is overdue
+// Schedulers and HistoryRetention enable the purge of the tombstones older than the retention of the history,
+// of the schedulers of the resolver
+type Config struct {
+	ColdEvents       store.Watchable
+	History          store.Store
+	HistoryEvents    store.Watchable
+	CancelledStore   store.BatchableStore
+	LateThreshold    time.Duration
+	Schedulers       schedulers.Resolver
+	HistoryRetention time.Duration
+}
+
+// Tracker computes the outcome of the cold schedules by joining them with the history entries
+// and with the cancellations recorded from the cold events
+type Tracker struct {
+	triggers      *index
+	cancelled     store.BatchableStore
+	lateThreshold time.Duration
+	schedulers    schedulers.Resolver
+	retention     time.Duration
+	// closed when the cancellations are flushed and the history events are indexed, after the events are closed
+	done chan struct{}
+}
+
+func NewTracker(cfg Config) (*Tracker, error) {
+	// watch before returning, so no cancellation is missed
+	watchChan, err := cfg.ColdEvents.Watch()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get watch channel: %w", err)
+	}
+
+	var historyChan chan store.Event
+	if cfg.HistoryEvents != nil {
+		historyChan, err = cfg.HistoryEvents.Watch()
+		if err != nil {
+			return nil, fmt.Errorf("cannot get history watch channel: %w", err)
+		}
+	}
+
+	lateThreshold := cfg.LateThreshold
+	if lateThreshold <= 0 {
+		lateThreshold = DefaultLateThreshold
+	}
+
+	t := &Tracker{
+		triggers:      newIndex(cfg.History, historyChan != nil),
+		cancelled:     cfg.CancelledStore,
+		lateThreshold: lateThreshold,
+		schedulers:    cfg.Schedulers,
+		retention:     cfg.HistoryRetention,
+		done:          make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t.watch(watchChan)
+	}()
+	if historyChan != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.triggers.watch(historyChan)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(t.done)
+	}()
+
+	return t, nil
+}
+
+// watch records the tombstones of the cold schedules, forgets them when the schedules are created again,
+// and purges the expired ones
+func (t *Tracker) watch(watchChan chan store.Event) {
+	defer log.Printf("outcome tracker closed")
+
+	batchChan := make(chan store.Event, ChanSize)
+	flushed := make(chan struct{})
+	defer func() {
+		close(batchChan)
+		<-flushed
+	}()
+
+	go func() {
+		defer close(flushed)
+		for err := range t.cancelled.Batch(batchChan) {
+			log.Errorf("cannot record cancellation: %v", err)
+		}
+	}()
+
+	var purgeChan <-chan time.Time
+	if t.schedulers != nil && t.retention > 0 {
+		ticker := time.NewTicker(PurgeInterval)
+		defer ticker.Stop()
+		purgeChan = ticker.C
+		t.purge(batchChan)
+	}
+
+	for {
+		select {
+		case evt, ok := <-watchChan:
+			if !ok {
+				return
+			}
+			t.record(batchChan, evt)
+		case <-purgeChan:
+			t.purge(batchChan)
+		}
+	}
+}
+
+// record sends the tombstones of a cold event to the cancelled store, the event is acknowledged
+// once its tombstone is persisted, or immediately when there is none
+func (t *Tracker) record(batchChan chan store.Event, evt store.Event) {
+	switch evt.EventType {
+	case store.DeletedType:
+		batchChan <- store.Event{EventType: store.UpsertType, Schedule: evt.Schedule, Ack: evt.Ack}
+	case store.UpsertType:
+		if t.isCancelled(evt.SchedulerName, evt.ID()) {
+			batchChan <- store.Event{EventType: store.DeletedType, Schedule: evt.Schedule, Ack: evt.Ack}
+			return
+		}
+		evt.Acknowledge()
+	case store.StoreResetType:
+		defer evt.Acknowledge()
+		list, err := t.cancelled.List(evt.SchedulerName)
+		if err != nil {
+			log.Errorf("cannot list cancellations of %v: %v", evt.SchedulerName, err)
+			return
+		}
+		for sch := range list {
+			batchChan <- store.Event{EventType: store.DeletedType, Schedule: sch}
+		}
+	default:
+		evt.Acknowledge()
+	}
+}
+
+// purge deletes the tombstones whose epoch and deletion are older than the retention of the history,
+// their triggers are not in the history anymore
+func (t *Tracker) purge(batchChan chan store.Event) {
+	schs, err := t.schedulers.List()
+	if err != nil {
+		log.Errorf("cannot list schedulers: %v", err)
+		return
+	}
+
+	expiry := time.Now().Add(-t.retention).UnixNano() / int64(time.Millisecond)
+
+	for _, s := range schs {
+		list, err := t.cancelled.List(s.Name())
+		if err != nil {
+			log.Errorf("cannot list cancellations of %v: %v", s.Name(), err)
+			continue
+		}
+		// listed before being deleted, the store is not written while it is read
+		expired := []store.Schedule{}
+		for sch := range list {
+			if sch.Epoch()*int64(time.Second/time.Millisecond) < expiry && timestampMs(sch) < expiry {
+				expired = append(expired, sch)
+			}
+		}
+		for _, sch := range expired {
+			batchChan <- store.Event{EventType: store.DeletedType, Schedule: sch}
+		}
+		if len(expired) != 0 {
+			log.Printf("%v expired cancellations of %v purged", len(expired), s.Name())
+		}
+	}
+}
+
+// Close waits for the cancellations to be recorded, it returns once the cold events are closed
+// and the last batch is written, the cancelled store can be closed after it
+func (t *Tracker) Close() error {
+	<-t.done
+	return nil
+}
+
+func (t *Tracker) isCancelled(schedulerName, scheduleID string) bool {
+	schs, err := t.cancelled.Get(schedulerName, scheduleID)
+	if err != nil {
+		log.Errorf("cannot get cancellation of %v: %v", scheduleID, err)
+		return false
+	}
+	return len(schs) > 0
+}
+
+// Outcome returns the outcome of a schedule of the cold DB, the schedule is triggered when
+// the history contains an entry with the same epoch
+func (t *Tracker) Outcome(schedulerName string, sch schedule.Schedule) Outcome {
+	epoch := sch.Epoch()
+
+	last, found := t.trigger(schedulerName, sch.ID(), func(tr trigger) bool {
+		return tr.epoch == epoch
+	})
+	if found {
+		return t.triggered(epoch, last)
+	}
+
+	if time.Now().After(time.Unix(epoch, 0).Add(t.lateThreshold)) {
+		return Outcome{Status: Overdue}
+	}
+	return Outcome{Status: Pending}
+}
+
+// cancelledOutcome returns the outcome of a tombstone, a schedule deleted after being triggered is not cancelled
+func (t *Tracker) cancelledOutcome(schedulerName string, tombstone schedule.Schedule) Outcome {
+	deletedAt := timestampMs(tombstone)
+
+	last, found := t.trigger(schedulerName, tombstone.ID(), func(tr trigger) bool {
+		return tr.timestampMs <= deletedAt
+	})
+	if found {
+		return t.triggered(last.epoch, last)
+	}
+
+	return Outcome{Status: Cancelled}
+}
+
+// trigger returns the latest trigger of the schedule matching the predicate, from the indexed history
+func (t *Tracker) trigger(schedulerName, scheduleID string, matches func(trigger) bool) (trigger, bool) {
+	var result trigger
+	found := false
+	for _, tr := range t.triggers.get(schedulerName, scheduleID) {
+		if matches(tr) && (!found || tr.timestampMs > result.timestampMs) {
+			result, found = tr, true
+		}
+	}
+	return result, found
+}
+
+func (t *Tracker) triggered(epoch int64, tr trigger) Outcome {
+	triggeredAt := tr.timestampMs
+	delay := triggeredAt - epoch*int64(time.Second/time.Millisecond)
+
+	status := Triggered
+	if time.Duration(delay)*time.Millisecond > t.lateThreshold {
+		status = TriggeredLate
+	}
+
+	return Outcome{
+		Status:      status,
+		TriggeredAt: triggeredAt,
+		Delay:       &delay,
+	}
+}
+
+// Get returns the versions of a schedule of the cold DB with its outcome computed from the latest version,
+// when the schedule has been deleted the tombstones are returned
+func (t *Tracker) Get(d db.DB, schedulerName, scheduleID string) ([]store.Schedule, Outcome, error) {
+	schs, err := d.Get(schedulerName, scheduleID)
+	if err != nil {
+		return nil, Outcome{}, err
+	}
+	if len(schs) != 0 {
+		versions := db.Versions(schs)
+		return schs, t.Outcome(schedulerName, versions[len(versions)-1].Schedule), nil
+	}
+
+	tombstones, err := t.cancelled.Get(schedulerName, scheduleID)
+	if err != nil || len(tombstones) == 0 {
+		return tombstones, Outcome{}, err
+	}
+
+	versions := db.Versions(tombstones)
+	return tombstones, t.cancelledOutcome(schedulerName, versions[len(versions)-1].Schedule), nil
+}
+
+// Search returns the schedules of the cold DB and the tombstones matching the query with one of the statuses,
+// the outcome is computed for each schedule matching the other filters. Without statuses the query is delegated to the DB.
+// The cold DB is scanned by pages in the order of the query until the page of the result is full, the total counts
+// the matches up to the end of the page plus one when there are more (all of them when Max is -1).
+func (t *Tracker) Search(d db.DB, q db.SearchQuery, statuses ...Status) (int, chan schedule.Schedule, error) {
+	if t == nil || len(statuses) == 0 {
+		return d.Search(q)
+	}
+
+	wanted := make(map[Status]bool, len(statuses))
+	for _, s := range statuses {
+		wanted[s] = true
+	}
+
+	max := DefaultMax
+	if q.Max > 0 {
+		max = q.Max
+	}
+	// -1 when all the matches are needed
+	needed := -1
+	if q.Max != -1 {
+		needed = q.Offset + max + 1
+	}
+
+	arr := []schedule.Schedule{}
+
+	scan := q
+	scan.Limit = db.Limit{Max: ScanPageSize}
+	for {
+		_, list, err := d.Search(scan)
+		if err != nil {
+			return 0, nil, err
+		}
+		scanned := 0
+		for sch := range list {
+			scanned++
+			if wanted[t.Outcome(schedulerNameOf(sch, q.SchedulerName), sch).Status] {
+				arr = append(arr, sch)
+			}
+		}
+		if scanned < ScanPageSize || (needed != -1 && len(arr) >= needed) {
+			break
+		}
+		scan.Offset += scanned
+	}
+
+	// the tombstones are purged after the retention of the history, they are all read
+	all := q
+	all.Limit = db.Limit{Max: -1}
+	_, list, err := simple.DB{Store: t.cancelled}.Search(all)
+	if err != nil {
+		return 0, nil, err
+	}
+	for sch := range list {
+		schedulerName := schedulerNameOf(sch, q.SchedulerName)
+		// the schedule has been created again
+		if schs, err := d.Get(schedulerName, sch.ID()); err == nil && len(schs) != 0 {
+			continue
+		}
+		if wanted[t.cancelledOutcome(schedulerName, sch).Status] {
+			arr = append(arr, sch)
+		}
+	}
+
+	// the schedules of the cold DB which are not scanned come after the scanned ones
+	stdsort.Sort(sort.NewSort(arr, q.SortBy))
+	total := len(arr)
+	if needed != -1 && total > needed {
+		total = needed
+	}
+	if q.Max == -1 {
+		max = len(arr)
+	}
+
+	page := arr
+	if q.Offset >= len(arr) {
+		page = nil
+	} else if q.Offset > 0 {
+		page = arr[q.Offset:]
+	}
+	if len(page) > max {
+		page = page[:max]
+	}
+
+	result := make(chan schedule.Schedule, len(page))
+	for _, sch := range page {
+		result <- sch
+	}
+	close(result)
+
+	return total, result, nil
+}
+
+func schedulerNameOf(sch schedule.Schedule, defaultName string) string {
+	if s, ok := sch.(store.Schedule); ok && s.SchedulerName != "" {
+		return s.SchedulerName
+	}
+	return defaultName
+}
+
+// timestampMs returns the timestamp of the kafka message of the schedule in milliseconds when known,
+// otherwise the timestamp of the schedule
+func timestampMs(sch schedule.Schedule) int64 {
+	if s, ok := sch.(store.Schedule); ok {
+		sch = s.Schedule
+	}
+	s, _, _ := decoder.Unwrap(sch)
+
+	switch s := s.(type) {
+	case bbolt.Schedule:
+		if s.TimestampMs != 0 {
+			return s.TimestampMs
+		}
+	case kafka.Schedule:
+		if s.Message != nil {
+			return s.Message.Timestamp.UnixNano() / int64(time.Millisecond)
+		}
+	case *kafka.Schedule:
+		if s != nil && s.Message != nil {
+			return s.Message.Timestamp.UnixNano() / int64(time.Millisecond)
+		}
+	}
+
+	return s.Timestamp() * int64(time.Second/time.Millisecond)
+}
@@ -0,0 +1,505 @@
+package outcome_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/sort"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+func newSchedule(id string, epoch, timestampMs int64) bbolt.Schedule {
+	return bbolt.Schedule{
+		ScheduleID:        id,
+		ScheduleEpoch:     epoch,
+		ScheduleTimestamp: timestampMs / 1000,
+		TimestampMs:       timestampMs,
+	}
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// waitFor polls the condition until it is true, for at most 5s
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met after 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func count(t *testing.T, s store.Store, schedulerName string) int {
+	t.Helper()
+	list, err := s.List(schedulerName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := 0
+	for range list {
+		result++
+	}
+	return result
+}
+
+// Rule #1: the outcome should be computed from the history entries with the epoch of the schedule
+func TestTracker_Outcome(t *testing.T) {
+	history := hmap.NewStore()
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     hmap.NewStore(),
+		History:        history,
+		CancelledStore: hmap.NewStore(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now().Unix()
+	past := now - 3600
+
+	err = history.Add("scheduler-1",
+		newSchedule("schedule-3", past, past*1000+2000),
+		newSchedule("schedule-4", past, past*1000+60000),
+		// triggered before being rescheduled
+		newSchedule("schedule-5", past-3600, (past-3600)*1000),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		sch      schedule.Schedule
+		expected outcome.Outcome
+	}{
+		{newSchedule("schedule-1", now+3600, 0), outcome.Outcome{Status: outcome.Pending}},
+		{newSchedule("schedule-2", past, 0), outcome.Outcome{Status: outcome.Overdue}},
+		{newSchedule("schedule-3", past, 0), outcome.Outcome{Status: outcome.Triggered, TriggeredAt: past*1000 + 2000, Delay: int64Ptr(2000)}},
+		{newSchedule("schedule-4", past, 0), outcome.Outcome{Status: outcome.TriggeredLate, TriggeredAt: past*1000 + 60000, Delay: int64Ptr(60000)}},
+		{newSchedule("schedule-5", past, 0), outcome.Outcome{Status: outcome.Overdue}},
+		// in the late threshold
+		{newSchedule("schedule-6", now-5, 0), outcome.Outcome{Status: outcome.Pending}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result := tracker.Outcome("scheduler-1", tt.sch)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("unexpected outcome: %+v", result)
+			}
+		})
+	}
+}
+
+// Rule #2: the deleted schedules should be cancelled, unless triggered before the deletion or created again
+func TestTracker_cancelled(t *testing.T) {
+	cold := hmap.NewStore()
+	coldDB := simple.DB{Store: cold}
+	history := hmap.NewStore()
+
+	cancelled := hmap.NewStore()
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     cold,
+		History:        history,
+		CancelledStore: cancelled,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now().Unix()
+	past := now - 3600
+
+	err = history.Add("scheduler-1", newSchedule("schedule-2", past, past*1000+1000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = cold.Add("scheduler-1",
+		newSchedule("schedule-1", now+3600, now*1000),
+		newSchedule("schedule-2", past, past*1000),
+		newSchedule("schedule-3", past, past*1000),
+		newSchedule("schedule-4", now+3600, now*1000),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = cold.Delete("scheduler-1",
+		newSchedule("schedule-1", 0, now*1000),
+		newSchedule("schedule-2", 0, now*1000),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		return count(t, cancelled, "scheduler-1") == 2
+	})
+
+	tests := []struct {
+		scheduleID     string
+		expectedCount  int
+		expectedStatus outcome.Status
+	}{
+		{"schedule-1", 1, outcome.Cancelled},
+		{"schedule-2", 1, outcome.Triggered},
+		{"schedule-3", 1, outcome.Overdue},
+		{"schedule-4", 1, outcome.Pending},
+		{"schedule-5", 0, ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			schs, o, err := tracker.Get(coldDB, "scheduler-1", tt.scheduleID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(schs) != tt.expectedCount || o.Status != tt.expectedStatus {
+				t.Errorf("unexpected result: %v %+v", schs, o)
+			}
+		})
+	}
+
+	search := func(statuses ...outcome.Status) []string {
+		total, list, err := tracker.Search(coldDB, db.SearchQuery{
+			Filter: db.Filter{SchedulerName: "scheduler-1"},
+			SortBy: sort.ToSortBy("id asc"),
+		}, statuses...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result := []string{}
+		for sch := range list {
+			result = append(result, sch.ID())
+		}
+		if total != len(result) {
+			t.Errorf("unexpected total: %v", total)
+		}
+		return result
+	}
+
+	if result := search(outcome.Cancelled); !reflect.DeepEqual(result, []string{"schedule-1"}) {
+		t.Errorf("unexpected cancelled schedules: %v", result)
+	}
+	if result := search(outcome.Overdue, outcome.Triggered); !reflect.DeepEqual(result, []string{"schedule-2", "schedule-3"}) {
+		t.Errorf("unexpected overdue and triggered schedules: %v", result)
+	}
+	if result := search(); len(result) != 2 {
+		t.Errorf("unexpected schedules: %v", result)
+	}
+
+	// created again
+	err = cold.Add("scheduler-1", newSchedule("schedule-1", now+7200, now*1000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		return count(t, cancelled, "scheduler-1") == 1
+	})
+
+	if result := search(outcome.Cancelled); len(result) != 0 {
+		t.Errorf("unexpected cancelled schedules: %v", result)
+	}
+	if result := search(outcome.Pending); !reflect.DeepEqual(result, []string{"schedule-1", "schedule-4"}) {
+		t.Errorf("unexpected pending schedules: %v", result)
+	}
+}
+
+// Rule #3: the statuses should be parsed from their names
+func TestParseStatus(t *testing.T) {
+	for _, s := range outcome.Statuses {
+		if result, err := outcome.ParseStatus(string(s)); err != nil || result != s {
+			t.Errorf("unexpected result: %v %v", result, err)
+		}
+	}
+	if _, err := outcome.ParseStatus("unknown"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+// events of a cold store closed by the test
+type coldEvents chan store.Event
+
+func (c coldEvents) Watch() (chan store.Event, error) {
+	return c, nil
+}
+
+// Rule #4: the cancellations should be recorded when the tracker is closed, once the cold events are closed
+func TestTracker_Close(t *testing.T) {
+	cancelled, err := bbolt.NewStore(filepath.Join(t.TempDir(), "cancelled.bbolt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cancelled.Close()
+
+	events := make(coldEvents, 10)
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     events,
+		History:        hmap.NewStore(),
+		CancelledStore: cancelled,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now().Unix()
+	for i := 1; i <= 3; i++ {
+		events <- store.Event{
+			EventType: store.DeletedType,
+			Schedule:  store.Schedule{SchedulerName: "scheduler-1", Schedule: newSchedule(fmt.Sprintf("schedule-%v", i), 0, now*1000)},
+		}
+	}
+	close(events)
+
+	if err := tracker.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := count(t, cancelled, "scheduler-1"); n != 3 {
+		t.Errorf("unexpected tombstones: %v", n)
+	}
+}
+
+// Rule #5: the tombstones should be purged once their epoch and their deletion are older than the retention of the history
+func TestTracker_purge(t *testing.T) {
+	cancelled := hmap.NewStore()
+
+	now := time.Now().Unix()
+	old := now - 7200
+
+	err := cancelled.Add("scheduler-1",
+		newSchedule("schedule-1", old, old*1000),
+		// cancelled in advance
+		newSchedule("schedule-2", now+3600, old*1000),
+		// deleted recently
+		newSchedule("schedule-3", old, now*1000),
+		// no epoch in the tombstone
+		newSchedule("schedule-4", 0, old*1000),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolver := slice.NewResolver()
+	resolver.Add(slice.Scheduler{SchedulerName: "scheduler-1"})
+
+	_, err = outcome.NewTracker(outcome.Config{
+		ColdEvents:       hmap.NewStore(),
+		History:          hmap.NewStore(),
+		CancelledStore:   cancelled,
+		Schedulers:       resolver,
+		HistoryRetention: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		return count(t, cancelled, "scheduler-1") == 2
+	})
+
+	for _, id := range []string{"schedule-2", "schedule-3"} {
+		if schs, err := cancelled.Get("scheduler-1", id); err != nil || len(schs) != 1 {
+			t.Errorf("unexpected tombstones of %v: %v %v", id, schs, err)
+		}
+	}
+}
+
+// history counting the reads
+type countingStore struct {
+	store.Store
+	gets int32
+}
+
+func (c *countingStore) Get(schedulerName, scheduleID string) ([]store.Schedule, error) {
+	atomic.AddInt32(&c.gets, 1)
+	return c.Store.Get(schedulerName, scheduleID)
+}
+
+// Rule #6: the triggers should be read once from the history, then updated by the history events
+func TestTracker_index(t *testing.T) {
+	history := hmap.NewStore()
+	counting := &countingStore{Store: history}
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     hmap.NewStore(),
+		History:        counting,
+		HistoryEvents:  history,
+		CancelledStore: hmap.NewStore(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	past := time.Now().Unix() - 3600
+	sch := newSchedule("schedule-1", past, 0)
+
+	if result := tracker.Outcome("scheduler-1", sch); result.Status != outcome.Overdue {
+		t.Errorf("unexpected outcome: %+v", result)
+	}
+
+	err = history.Add("scheduler-1", newSchedule("schedule-1", past, past*1000+1000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		return tracker.Outcome("scheduler-1", sch).Status == outcome.Triggered
+	})
+
+	if gets := atomic.LoadInt32(&counting.gets); gets != 1 {
+		t.Errorf("unexpected reads of the history: %v", gets)
+	}
+}
+
+// Rule #7: the deletions should be acknowledged once their tombstones are persisted, the other events immediately
+func TestTracker_acknowledgement(t *testing.T) {
+	cancelled, err := bbolt.NewStore(filepath.Join(t.TempDir(), "cancelled.bbolt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cancelled.Close()
+
+	events := make(coldEvents, 10)
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     events,
+		History:        hmap.NewStore(),
+		CancelledStore: cancelled,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() {
+		close(events)
+		tracker.Close()
+	}()
+
+	var upserted, deleted int32
+	now := time.Now().Unix()
+	events <- store.Event{
+		EventType: store.UpsertType,
+		Schedule:  store.Schedule{SchedulerName: "scheduler-1", Schedule: newSchedule("schedule-1", now, now*1000)},
+		Ack:       func() { atomic.AddInt32(&upserted, 1) },
+	}
+	events <- store.Event{
+		EventType: store.DeletedType,
+		Schedule:  store.Schedule{SchedulerName: "scheduler-1", Schedule: newSchedule("schedule-2", now, now*1000)},
+		Ack: func() {
+			// the tombstone is persisted before the acknowledgement
+			if n := count(t, cancelled, "scheduler-1"); n != 1 {
+				t.Errorf("unexpected tombstones when acknowledged: %v", n)
+			}
+			atomic.AddInt32(&deleted, 1)
+		},
+	}
+
+	waitFor(t, func() bool {
+		return atomic.LoadInt32(&upserted) == 1 && atomic.LoadInt32(&deleted) == 1
+	})
+}
+
+// scanningDB counts the schedules read by the searches
+type scanningDB struct {
+	simple.DB
+	scanned *int32
+}
+
+func (d scanningDB) Search(q db.SearchQuery) (int, chan schedule.Schedule, error) {
+	total, list, err := d.DB.Search(q)
+	if err != nil {
+		return total, list, err
+	}
+	result := make(chan schedule.Schedule, simple.ChanSize)
+	go func() {
+		defer close(result)
+		for sch := range list {
+			atomic.AddInt32(d.scanned, 1)
+			result <- sch
+		}
+	}()
+	return total, result, nil
+}
+
+// Rule #8: the search with statuses should scan the cold DB by pages until the page of the result is full
+func TestTracker_Search_scan(t *testing.T) {
+	cold := hmap.NewStore()
+	coldDB := scanningDB{DB: simple.DB{Store: cold}, scanned: new(int32)}
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     make(coldEvents),
+		History:        hmap.NewStore(),
+		CancelledStore: hmap.NewStore(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now().Unix()
+	size := 3 * outcome.ScanPageSize
+	for i := 0; i < size; i++ {
+		// pending and overdue schedules alternately
+		epoch := now + 3600
+		if i%2 == 0 {
+			epoch = now - 3600
+		}
+		if err := cold.Add("scheduler-1", newSchedule(fmt.Sprintf("schedule-%04d", i), epoch, now*1000)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		limit           db.Limit
+		expectedTotal   int
+		expectedFirstID string
+		expectedIDs     int
+		expectedScanned int
+	}{
+		{db.Limit{Max: 10}, 11, "schedule-0000", 10, outcome.ScanPageSize},
+		{db.Limit{Max: 10, Offset: outcome.ScanPageSize}, outcome.ScanPageSize + 11, "schedule-1000", 10, 3 * outcome.ScanPageSize},
+		{db.Limit{Max: -1}, size / 2, "schedule-0000", size / 2, 3 * outcome.ScanPageSize},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			atomic.StoreInt32(coldDB.scanned, 0)
+
+			total, list, err := tracker.Search(coldDB, db.SearchQuery{
+				Filter: db.Filter{SchedulerName: "scheduler-1"},
+				Limit:  tt.limit,
+				SortBy: sort.By{Field: sort.ID, Order: sort.Asc},
+			}, outcome.Overdue)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := []string{}
+			for sch := range list {
+				ids = append(ids, sch.ID())
+			}
+			if total != tt.expectedTotal || len(ids) != tt.expectedIDs || ids[0] != tt.expectedFirstID {
+				t.Errorf("unexpected result: total=%v ids=%v first=%v", total, len(ids), ids[0])
+			}
+			if scanned := int(atomic.LoadInt32(coldDB.scanned)); scanned != tt.expectedScanned {
+				t.Errorf("unexpected scanned schedules: %v", scanned)
+			}
+		})
+	}
+}
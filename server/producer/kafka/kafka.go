@@ -0,0 +1,204 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+	kafka_schedule "github.com/etf1/kafka-message-scheduler/schedule/kafka"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BaseNumber   = 10
+	FlushTimeout = 5000
+)
+
+var (
+	DeliveryTimeout = 10 * time.Second
+)
+
+// Producer sends schedule messages to the topic of the schedulers returned by the resolver.
+// A kafka producer is created lazily for each distinct bootstrap servers, with the client properties of the consumers
+// (security protocol, SASL, TLS ...), the schedulers with their own properties have their own producers.
+type Producer struct {
+	resolver   schedulers.Resolver
+	properties kafka.ClientProperties
+	mutex      *sync.Mutex
+	producers  map[string]*confluent.Producer
+}
+
+func NewProducer(resolver schedulers.Resolver, properties kafka.ClientProperties) *Producer {
+	return &Producer{
+		resolver:   resolver,
+		properties: properties,
+		mutex:      &sync.Mutex{},
+		producers:  make(map[string]*confluent.Producer),
+	}
+}
+
+func (p *Producer) Close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for name, kp := range p.producers {
+		if remaining := kp.Flush(FlushTimeout); remaining > 0 {
+			log.Warnf("kafka producer for %v closed with %v unflushed messages", name, remaining)
+		}
+		kp.Close()
+	}
+	p.producers = make(map[string]*confluent.Producer)
+
+	log.Printf("kafka producer closed")
+}
+
+func (p *Producer) Produce(schedulerName string, sch producer.Schedule) (producer.Result, error) {
+	headers := []confluent.Header{
+		{
+			Key:   kafka_schedule.Epoch,
+			Value: []byte(strconv.FormatInt(sch.Epoch, BaseNumber)),
+		},
+		{
+			Key:   kafka_schedule.TargetTopic,
+			Value: []byte(sch.TargetTopic),
+		},
+		{
+			Key:   kafka_schedule.TargetKey,
+			Value: []byte(sch.TargetKey),
+		},
+	}
+
+	return p.send(schedulerName, sch.ID, sch.Value, headers)
+}
+
+func (p *Producer) Cancel(schedulerName, scheduleID string) (producer.Result, error) {
+	// a message with a nil value is a tombstone for the scheduler
+	return p.send(schedulerName, scheduleID, nil, nil)
+}
+
+func (p *Producer) send(schedulerName, scheduleID string, value []byte, headers []confluent.Header) (producer.Result, error) {
+	result := producer.Result{
+		Scheduler: schedulerName,
+	}
+
+	sch, err := p.scheduler(schedulerName)
+	if err != nil {
+		return result, err
+	}
+
+	topics := sch.Topics()
+	if len(topics) == 0 {
+		return result, fmt.Errorf("%w: %v", producer.ErrNoTopic, schedulerName)
+	}
+	// schedules are always written to the first topic consumed by the scheduler
+	topic := topics[0]
+
+	kp, err := p.producer(schedulerName, sch.BootstrapServers())
+	if err != nil {
+		return result, err
+	}
+
+	deliveryChan := make(chan confluent.Event, 1)
+
+	err = kp.Produce(&confluent.Message{
+		TopicPartition: confluent.TopicPartition{Topic: &topic, Partition: confluent.PartitionAny},
+		Key:            []byte(scheduleID),
+		Value:          value,
+		Headers:        headers,
+	}, deliveryChan)
+	if err != nil {
+		return result, fmt.Errorf("cannot produce schedule %v to topic %v: %w", scheduleID, topic, err)
+	}
+
+	timeout := time.NewTimer(DeliveryTimeout)
+	defer timeout.Stop()
+
+	select {
+	case e := <-deliveryChan:
+		m, ok := e.(*confluent.Message)
+		if !ok {
+			return result, fmt.Errorf("unexpected delivery event: %v", e)
+		}
+		if m.TopicPartition.Error != nil {
+			return result, fmt.Errorf("delivery failed for schedule %v: %w", scheduleID, m.TopicPartition.Error)
+		}
+
+		result.Topic = topic
+		result.Partition = m.TopicPartition.Partition
+		result.Offset = int64(m.TopicPartition.Offset)
+
+		log.Printf("delivered schedule %v to topic %s [%d] at offset %v", scheduleID, topic, result.Partition, result.Offset)
+	case <-timeout.C:
+		return result, fmt.Errorf("delivery timeout for schedule %v to topic %v", scheduleID, topic)
+	}
+
+	return result, nil
+}
+
+func (p *Producer) scheduler(schedulerName string) (httpresolver.Scheduler, error) {
+	// resolver can return partial results with an error, so first look into the results
+	schs, err := p.resolver.List()
+	for _, s := range schs {
+		if s.Name() != schedulerName {
+			continue
+		}
+		sch, ok := s.(httpresolver.Scheduler)
+		if !ok {
+			return httpresolver.Scheduler{}, fmt.Errorf("unable to cast: %T", s)
+		}
+		return sch, nil
+	}
+
+	if err != nil {
+		return httpresolver.Scheduler{}, err
+	}
+
+	return httpresolver.Scheduler{}, fmt.Errorf("%w: %v", producer.ErrSchedulerNotFound, schedulerName)
+}
+
+func (p *Producer) producer(schedulerName, bootstrapServers string) (*confluent.Producer, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// the producers are shared by the schedulers with the same bootstrap servers and the default properties
+	name := bootstrapServers
+	if len(p.properties.Buckets[schedulerName]) != 0 {
+		name = schedulerName + "@" + bootstrapServers
+	}
+	if kp, found := p.producers[name]; found {
+		return kp, nil
+	}
+
+	properties := p.properties.Get(schedulerName)
+	log.Printf("new producer bootstrapServers=%v properties=%v", bootstrapServers, properties.Keys())
+	cm := &confluent.ConfigMap{
+		"bootstrap.servers": bootstrapServers,
+	}
+	if err := properties.Apply(cm); err != nil {
+		return nil, err
+	}
+
+	kp, err := confluent.NewProducer(cm)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create kafka producer for %v: %w", bootstrapServers, err)
+	}
+
+	// delivery reports are sent to the delivery channel, only errors are left here
+	go func() {
+		for e := range kp.Events() {
+			if kerr, ok := e.(confluent.Error); ok {
+				log.Errorf("received kafka producer error: %v", kerr)
+			}
+		}
+	}()
+
+	p.producers[name] = kp
+
+	return kp, nil
+}
@@ -0,0 +1,89 @@
+// INTEGRATION TESTS
+package kafka_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	storekafka "github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+)
+
+func newResolver(topic string) *slice.Slice {
+	resolver := slice.NewResolver()
+	resolver.Add(httpresolver.Scheduler{
+		HostName: "scheduler-1",
+		Instances: []httpresolver.Instance{
+			{
+				Topics:           []string{topic},
+				BootstrapServers: helper.GetDefaultBootstrapServers(),
+			},
+		},
+	})
+	return resolver
+}
+
+// Rule #1: produce and cancel should write a schedule message and a tombstone in the scheduler's topic
+func TestKafkaProducer_Produce_Cancel(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	topics, err := helper.CreateTopics(1, []int{1}, "schedules")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := kafka.NewProducer(newResolver(topics[0]), storekafka.ClientProperties{})
+	defer p.Close()
+
+	epoch := time.Now().Add(1 * time.Hour).Unix()
+
+	result, err := p.Produce("scheduler-1", producer.Schedule{
+		ID:          "schedule-1",
+		Epoch:       epoch,
+		TargetTopic: "target-topic",
+		TargetKey:   "target-key",
+		Value:       []byte("value"),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.Topic != topics[0] || result.Offset != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	result, err = p.Cancel("scheduler-1", "schedule-1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.Topic != topics[0] || result.Offset != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	expected := helper.NewKafkaSchedule(topics[0], "schedule-1", "value", epoch, "target-topic", "target-key")
+	err = helper.AssertMessagesinTopic(topics[0], []*confluent.Message{
+		expected.Message,
+		helper.Message(topics[0], "schedule-1", nil, 0),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// Rule #2: producing for an unknown scheduler should fail
+func TestKafkaProducer_scheduler_not_found(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	p := kafka.NewProducer(newResolver("schedules"), storekafka.ClientProperties{})
+	defer p.Close()
+
+	_, err := p.Cancel("unknown", "schedule-1")
+	if !errors.Is(err, producer.ErrSchedulerNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package mutable
+
+import (
+	"sync"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler/schedule/simple"
+)
+
+const (
+	DefaultTopic = "schedules"
+)
+
+// Producer writes the schedules directly into a mutable store instead of a kafka topic,
+// offsets are simulated with a counter (used by the mini runner and tests)
+type Producer struct {
+	store.MutableStore
+	Topic  string
+	mutex  *sync.Mutex
+	offset int64
+}
+
+func NewProducer(ms store.MutableStore) *Producer {
+	return &Producer{
+		MutableStore: ms,
+		Topic:        DefaultTopic,
+		mutex:        &sync.Mutex{},
+	}
+}
+
+func (p *Producer) nextResult(schedulerName string) producer.Result {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	result := producer.Result{
+		Scheduler: schedulerName,
+		Topic:     p.Topic,
+		Offset:    p.offset,
+	}
+	p.offset++
+
+	return result
+}
+
+func (p *Producer) Produce(schedulerName string, sch producer.Schedule) (producer.Result, error) {
+	result := p.nextResult(schedulerName)
+
+	ksch := helper.NewKafkaSchedule(p.Topic, sch.ID, sch.Value, sch.Epoch, sch.TargetTopic, sch.TargetKey)
+	ksch.TopicPartition.Partition = result.Partition
+	ksch.TopicPartition.Offset = confluent.Offset(result.Offset)
+
+	err := p.Add(schedulerName, ksch)
+	if err != nil {
+		return producer.Result{}, err
+	}
+	return result, nil
+}
+
+func (p *Producer) Cancel(schedulerName, scheduleID string) (producer.Result, error) {
+	err := p.Delete(schedulerName, simple.NewSchedule(scheduleID, 0))
+	if err != nil {
+		return producer.Result{}, err
+	}
+	return p.nextResult(schedulerName), nil
+}
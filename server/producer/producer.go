@@ -0,0 +1,66 @@
+package producer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+)
+
+var (
+	ErrSchedulerNotFound = errors.New("producer: scheduler not found")
+	ErrNoTopic           = errors.New("producer: no topic defined for scheduler")
+)
+
+// Schedule represents the content of a schedule message sent to a scheduler's topic,
+// json tags are the same as the ones used by the schedules returned by the API
+type Schedule struct {
+	ID          string `json:"id"`
+	Epoch       int64  `json:"epoch"`
+	TargetTopic string `json:"target-topic"`
+	TargetKey   string `json:"target-key"`
+	Value       []byte `json:"value"`
+}
+
+func (s Schedule) String() string {
+	return fmt.Sprintf("{id:%s epoch:%v target-topic:%s target-key:%s}", s.ID, s.Epoch, s.TargetTopic, s.TargetKey)
+}
+
+// Result tells where the message has been written
+type Result struct {
+	Scheduler string `json:"scheduler"`
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+type Producer interface {
+	// Produce sends a schedule message to the scheduler's topic (create or update)
+	Produce(schedulerName string, sch Schedule) (Result, error)
+	// Cancel sends a tombstone message for the schedule ID to the scheduler's topic
+	Cancel(schedulerName, scheduleID string) (Result, error)
+}
+
+// FromSchedule converts a schedule to a producer schedule, it works for all types of schedules
+// since they share the same json representation
+func FromSchedule(sch schedule.Schedule) (Schedule, error) {
+	if s, ok := sch.(store.Schedule); ok {
+		sch = s.Schedule
+	}
+
+	var result Schedule
+
+	data, err := json.Marshal(sch)
+	if err != nil {
+		return result, fmt.Errorf("cannot marshal schedule %v: %w", sch.ID(), err)
+	}
+
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return result, fmt.Errorf("cannot unmarshal schedule %v: %w", sch.ID(), err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,232 @@
+package fileresolver
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DefaultReloadInterval = 5 * time.Second
+)
+
+// Definition is a scheduler declared in the file
+type Definition struct {
+	Name             string   `yaml:"name"`
+	BootstrapServers string   `yaml:"bootstrap_servers"`
+	Topics           []string `yaml:"topics"`
+	HistoryTopic     string   `yaml:"history_topic"`
+	// http addresses of the scheduler instances, with the format host:port (default port is 8000)
+	Endpoints []string `yaml:"endpoints"`
+}
+
+type file struct {
+	Schedulers []Definition `yaml:"schedulers"`
+}
+
+// Resolver returns the schedulers defined in a YAML or JSON file, the file is reloaded when it changes
+// and the watchers are notified of the changes.
+// The schedulers are returned as httpresolver.Scheduler, with an instance per endpoint.
+type Resolver struct {
+	path     string
+	mutex    *sync.RWMutex
+	data     []byte
+	schs     []schedulers.Scheduler
+	notifier schedulers.Notifier
+	// used by close
+	stopChan chan bool
+	exitChan chan bool
+}
+
+// NewResolver loads the file and checks it every interval for changes,
+// an invalid file is ignored on reload and the previous schedulers are kept
+func NewResolver(path string, interval time.Duration) (*Resolver, error) {
+	r := &Resolver{
+		path:     path,
+		mutex:    &sync.RWMutex{},
+		notifier: schedulers.NewNotifier(),
+		stopChan: make(chan bool, 1),
+		exitChan: make(chan bool, 1),
+	}
+
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer func() {
+			r.exitChan <- true
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				changed, err := r.reload()
+				if err != nil {
+					log.Errorf("cannot reload schedulers file, previous definitions are kept: %v", err)
+				} else if changed {
+					log.Printf("schedulers file %v reloaded", r.path)
+				}
+			case <-r.stopChan:
+				return
+			}
+		}
+	}()
+
+	return r, nil
+}
+
+func (r *Resolver) Close() {
+	r.stopChan <- true
+	<-r.exitChan
+	r.notifier.Close()
+}
+
+func (r *Resolver) List() ([]schedulers.Scheduler, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return append([]schedulers.Scheduler{}, r.schs...), nil
+}
+
+func (r *Resolver) Watch() (<-chan schedulers.Event, func()) {
+	return r.notifier.Watch()
+}
+
+// Health returns the endpoints of a scheduler, their status is unknown as they are not contacted by the resolver
+func (r *Resolver) Health(name string) ([]schedulers.InstanceHealth, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, s := range r.schs {
+		sch, ok := s.(httpresolver.Scheduler)
+		if !ok || sch.Name() != name {
+			continue
+		}
+		result := make([]schedulers.InstanceHealth, 0, len(sch.Instances))
+		for _, instance := range sch.Instances {
+			result = append(result, schedulers.InstanceHealth{
+				Address: net.JoinHostPort(instance.Name(), sch.HTTPPort),
+				Status:  schedulers.StatusUnknown,
+			})
+		}
+		return result, true
+	}
+
+	return nil, false
+}
+
+// reload reads the file and updates the schedulers when its content has changed
+func (r *Resolver) reload() (bool, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, fmt.Errorf("cannot read schedulers file: %w", err)
+	}
+
+	// the file is being written (truncated), or emptied by mistake
+	if len(bytes.TrimSpace(data)) == 0 {
+		return false, fmt.Errorf("schedulers file %v is empty", r.path)
+	}
+
+	r.mutex.RLock()
+	unchanged := r.data != nil && bytes.Equal(data, r.data)
+	r.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	schs, err := Parse(data)
+	if err != nil {
+		return false, fmt.Errorf("invalid schedulers file %v: %w", r.path, err)
+	}
+
+	r.mutex.Lock()
+	events := schedulers.Diff(r.schs, schs)
+	r.data = data
+	r.schs = schs
+	r.mutex.Unlock()
+
+	r.notifier.Notify(events...)
+
+	return true, nil
+}
+
+// Parse returns the schedulers of the file content, JSON being a subset of YAML both formats are supported
+func Parse(data []byte) ([]schedulers.Scheduler, error) {
+	var f file
+	err := yaml.UnmarshalStrict(data, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []schedulers.Scheduler{}
+	names := map[string]bool{}
+
+	for i, def := range f.Schedulers {
+		if def.Name == "" {
+			return nil, fmt.Errorf("scheduler #%v: name is missing", i+1)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("scheduler %v: duplicated name", def.Name)
+		}
+		names[def.Name] = true
+
+		sch, err := def.scheduler()
+		if err != nil {
+			return nil, fmt.Errorf("scheduler %v: %w", def.Name, err)
+		}
+		result = append(result, sch)
+	}
+
+	return result, nil
+}
+
+func (def Definition) scheduler() (httpresolver.Scheduler, error) {
+	if def.BootstrapServers == "" {
+		return httpresolver.Scheduler{}, fmt.Errorf("bootstrap_servers is missing")
+	}
+	if len(def.Topics) == 0 {
+		return httpresolver.Scheduler{}, fmt.Errorf("topics are missing")
+	}
+	if len(def.Endpoints) == 0 {
+		return httpresolver.Scheduler{}, fmt.Errorf("endpoints are missing")
+	}
+
+	sch := httpresolver.Scheduler{
+		HostName: def.Name,
+	}
+
+	for _, endpoint := range def.Endpoints {
+		host, port := endpoint, httpresolver.SchedulerDefaultPort
+		if h, p, err := net.SplitHostPort(endpoint); err == nil {
+			host, port = h, p
+		}
+
+		// the instances of a scheduler share the same http port
+		if sch.HTTPPort != "" && sch.HTTPPort != port {
+			return httpresolver.Scheduler{}, fmt.Errorf("endpoints must have the same port: %v", def.Endpoints)
+		}
+		sch.HTTPPort = port
+
+		sch.Instances = append(sch.Instances, httpresolver.Instance{
+			IP:               net.ParseIP(host),
+			HostNames:        []string{host},
+			Topics:           def.Topics,
+			HistoryTopic:     def.HistoryTopic,
+			BootstrapServers: def.BootstrapServers,
+		})
+	}
+
+	return sch, nil
+}
@@ -0,0 +1,148 @@
+package fileresolver_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/fileresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+)
+
+const yamlFile = `
+schedulers:
+  - name: scheduler-1
+    bootstrap_servers: kafka:9092
+    topics: [schedules]
+    history_topic: history
+    endpoints: [scheduler-1a:8001, scheduler-1b:8001]
+  - name: scheduler-2
+    bootstrap_servers: kafka:9092
+    topics: [schedules-2]
+    endpoints: [10.0.0.1]
+`
+
+const jsonFile = `{
+  "schedulers": [
+    {"name": "scheduler-3", "bootstrap_servers": "kafka:9092", "topics": ["schedules-3"], "endpoints": ["scheduler-3"]}
+  ]
+}`
+
+func writeFile(t *testing.T, path, content string) {
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func names(schs []schedulers.Scheduler) string {
+	result := []string{}
+	for _, sch := range schs {
+		result = append(result, sch.Name())
+	}
+	return fmt.Sprint(result)
+}
+
+// Rule #1: schedulers should be parsed from a YAML or JSON file
+func TestParse(t *testing.T) {
+	schs, err := fileresolver.Parse([]byte(yamlFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names(schs) != "[scheduler-1 scheduler-2]" {
+		t.Fatalf("unexpected schedulers: %v", names(schs))
+	}
+
+	sch1 := schs[0].(httpresolver.Scheduler)
+	if sch1.HTTPPort != "8001" || len(sch1.Instances) != 2 || sch1.Instances[1].Name() != "scheduler-1b" {
+		t.Errorf("unexpected scheduler: %+v", sch1)
+	}
+	if sch1.BootstrapServers() != "kafka:9092" || fmt.Sprint(sch1.Topics()) != "[schedules]" || sch1.History() != "history" {
+		t.Errorf("unexpected scheduler kafka info: %+v", sch1)
+	}
+
+	sch2 := schs[1].(httpresolver.Scheduler)
+	if sch2.HTTPPort != httpresolver.SchedulerDefaultPort || sch2.Instances[0].IP.String() != "10.0.0.1" {
+		t.Errorf("unexpected scheduler: %+v", sch2)
+	}
+
+	schs, err = fileresolver.Parse([]byte(jsonFile))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names(schs) != "[scheduler-3]" {
+		t.Errorf("unexpected schedulers: %v", names(schs))
+	}
+
+	invalids := []string{
+		`schedulers: [{bootstrap_servers: kafka, topics: [t], endpoints: [h]}]`,
+		`schedulers: [{name: s, topics: [t], endpoints: [h]}]`,
+		`schedulers: [{name: s, bootstrap_servers: kafka, endpoints: [h]}]`,
+		`schedulers: [{name: s, bootstrap_servers: kafka, topics: [t]}]`,
+		`schedulers: [{name: s, bootstrap_servers: kafka, topics: [t], endpoints: [h1:8000, h2:8001]}]`,
+		`schedulers: [{name: s, bootstrap_servers: kafka, topics: [t], endpoints: [h]}, {name: s, bootstrap_servers: kafka, topics: [t], endpoints: [h]}]`,
+		`schedulers: [{name: s, unknown: field}]`,
+	}
+	for i, invalid := range invalids {
+		if _, err := fileresolver.Parse([]byte(invalid)); err == nil {
+			t.Errorf("case #%v: expected an error", i+1)
+		}
+	}
+}
+
+// Rule #2: resolver should reload the file when it changes, and keep the previous schedulers when it is invalid
+func TestResolver_reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedulers.yaml")
+	writeFile(t, path, yamlFile)
+
+	r, err := fileresolver.NewResolver(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	events, stop := r.Watch()
+	defer stop()
+
+	waitFor := func(expected string) {
+		t.Helper()
+		actual := ""
+		for i := 0; i < 100; i++ {
+			schs, err := r.List()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual = names(schs); actual == expected {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("unexpected schedulers: %v, expected %v", actual, expected)
+	}
+
+	waitFor("[scheduler-1 scheduler-2]")
+
+	writeFile(t, path, jsonFile)
+	waitFor("[scheduler-3]")
+
+	actual := []string{}
+	for i := 0; i < 3; i++ {
+		evt := <-events
+		actual = append(actual, fmt.Sprintf("%v %v", evt.EventType, evt.Name()))
+	}
+	if expected := "[added scheduler-3 removed scheduler-1 removed scheduler-2]"; fmt.Sprint(actual) != expected {
+		t.Errorf("unexpected events: %v, expected %v", actual, expected)
+	}
+
+	writeFile(t, path, "schedulers: [{name: invalid}]")
+	time.Sleep(50 * time.Millisecond)
+	waitFor("[scheduler-3]")
+
+	_, err = fileresolver.NewResolver(filepath.Join(t.TempDir(), "missing.yaml"), time.Second)
+	if err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,136 @@
+package schedulers
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// status of an instance
+const (
+	// StatusUp means the last contact with the instance succeeded
+	StatusUp = "up"
+	// StatusDown means the last contact with the instance failed
+	StatusDown = "down"
+	// StatusUnknown means the instance has never been contacted by the resolver
+	StatusUnknown = "unknown"
+)
+
+// InstanceHealth is the health of a scheduler instance, as seen by the resolver
+type InstanceHealth struct {
+	Address             string     `json:"address"`
+	Status              string     `json:"status"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorTime       *time.Time `json:"last_error_time,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	// duration of the last contact in milliseconds
+	ResponseTime int64 `json:"response_time_ms"`
+}
+
+// HealthReporter is implemented by the resolvers which know the instances of the schedulers
+type HealthReporter interface {
+	// Health returns the instances of the scheduler, found is false when the scheduler is unknown
+	Health(name string) (instances []InstanceHealth, found bool)
+}
+
+// HealthTracker records the contacts of a resolver with the instances of the schedulers,
+// a nil tracker records nothing
+type HealthTracker struct {
+	mutex *sync.RWMutex
+	// scheduler name => instance address => health
+	records map[string]map[string]InstanceHealth
+}
+
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{
+		mutex:   &sync.RWMutex{},
+		records: make(map[string]map[string]InstanceHealth),
+	}
+}
+
+// Record records the result of a contact with an instance of the scheduler
+func (h *HealthTracker) Record(name, address string, responseTime time.Duration, err error) {
+	if h == nil {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	instances, ok := h.records[name]
+	if !ok {
+		instances = make(map[string]InstanceHealth)
+		h.records[name] = instances
+	}
+
+	now := time.Now()
+	health := instances[address]
+	health.Address = address
+	health.ResponseTime = responseTime.Milliseconds()
+
+	if err != nil {
+		health.Status = StatusDown
+		health.LastError = err.Error()
+		health.LastErrorTime = &now
+		health.ConsecutiveFailures++
+	} else {
+		health.Status = StatusUp
+		health.LastSuccess = &now
+		health.ConsecutiveFailures = 0
+	}
+
+	instances[address] = health
+}
+
+// Retain forgets the instances of the scheduler which are not in the addresses,
+// the scheduler is forgotten when there are no addresses
+func (h *HealthTracker) Retain(name string, addresses []string) {
+	if h == nil {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(addresses) == 0 {
+		delete(h.records, name)
+		return
+	}
+
+	kept := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		kept[address] = true
+	}
+
+	for address := range h.records[name] {
+		if !kept[address] {
+			delete(h.records[name], address)
+		}
+	}
+}
+
+// Health returns the instances of the scheduler sorted by address
+func (h *HealthTracker) Health(name string) ([]InstanceHealth, bool) {
+	if h == nil {
+		return nil, false
+	}
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	instances, found := h.records[name]
+	if !found {
+		return nil, false
+	}
+
+	result := make([]InstanceHealth, 0, len(instances))
+	for _, health := range instances {
+		result = append(result, health)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+
+	return result, true
+}
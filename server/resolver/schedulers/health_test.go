@@ -0,0 +1,65 @@
+package schedulers_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+)
+
+// Rule #3: health tracker should record the contacts and forget the instances which are not retained
+func TestHealthTracker(t *testing.T) {
+	h := schedulers.NewHealthTracker()
+
+	if _, found := h.Health("scheduler-1"); found {
+		t.Errorf("scheduler should not be found")
+	}
+
+	h.Record("scheduler-1", "10.0.0.2:8000", 5*time.Millisecond, nil)
+	h.Record("scheduler-1", "10.0.0.1:8000", 3*time.Millisecond, nil)
+	h.Record("scheduler-1", "10.0.0.1:8000", time.Millisecond, errors.New("timeout"))
+	h.Record("scheduler-1", "10.0.0.1:8000", 2*time.Millisecond, errors.New("connection refused"))
+
+	instances, found := h.Health("scheduler-1")
+	if !found || len(instances) != 2 {
+		t.Fatalf("unexpected instances: %+v", instances)
+	}
+
+	down := instances[0]
+	if down.Address != "10.0.0.1:8000" || down.Status != schedulers.StatusDown || down.ConsecutiveFailures != 2 ||
+		down.LastError != "connection refused" || down.LastErrorTime == nil || down.LastSuccess == nil || down.ResponseTime != 2 {
+		t.Errorf("unexpected instance: %+v", down)
+	}
+
+	up := instances[1]
+	if up.Address != "10.0.0.2:8000" || up.Status != schedulers.StatusUp || up.ConsecutiveFailures != 0 ||
+		up.LastError != "" || up.LastSuccess == nil || up.ResponseTime != 5 {
+		t.Errorf("unexpected instance: %+v", up)
+	}
+
+	// a success resets the failures
+	h.Record("scheduler-1", "10.0.0.1:8000", time.Millisecond, nil)
+	instances, _ = h.Health("scheduler-1")
+	if instances[0].Status != schedulers.StatusUp || instances[0].ConsecutiveFailures != 0 || instances[0].LastError != "connection refused" {
+		t.Errorf("unexpected instance: %+v", instances[0])
+	}
+
+	h.Retain("scheduler-1", []string{"10.0.0.2:8000"})
+	instances, _ = h.Health("scheduler-1")
+	if len(instances) != 1 || instances[0].Address != "10.0.0.2:8000" {
+		t.Errorf("unexpected instances: %+v", instances)
+	}
+
+	h.Retain("scheduler-1", nil)
+	if _, found := h.Health("scheduler-1"); found {
+		t.Errorf("scheduler should be forgotten")
+	}
+
+	// nil tracker records nothing
+	var nilTracker *schedulers.HealthTracker
+	nilTracker.Record("scheduler-1", "10.0.0.1:8000", time.Millisecond, nil)
+	if _, found := nilTracker.Health("scheduler-1"); found {
+		t.Errorf("scheduler should not be found")
+	}
+}
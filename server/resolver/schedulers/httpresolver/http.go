@@ -69,13 +69,17 @@ func (i Instance) Name() string {
 	return i.IP.String()
 }
 
+// Resolver returns a scheduler per host, with an instance per IP address of the host.
+// The health of the instances is tracked when the resolver is created by NewResolver.
 type Resolver struct {
-	Hosts []string
+	Hosts  []string
+	health *schedulers.HealthTracker
 }
 
 func NewResolver(hosts []string) Resolver {
 	return Resolver{
-		Hosts: hosts,
+		Hosts:  hosts,
+		health: schedulers.NewHealthTracker(),
 	}
 }
 
@@ -105,6 +109,11 @@ func (r Resolver) List() ([]schedulers.Scheduler, error) {
 		ips, err := net.LookupIP(host)
 		if err != nil {
 			log.Errorf("unable to lookup ip for host %v: %v", host, err)
+			// the known instances are kept, they are not reachable
+			instances, _ := r.health.Health(host)
+			for _, i := range instances {
+				r.health.Record(host, i.Address, 0, err)
+			}
 		}
 
 		sch := Scheduler{
@@ -114,39 +123,39 @@ func (r Resolver) List() ([]schedulers.Scheduler, error) {
 
 		log.Infof("ips: %+v", ips)
 
+		addresses := []string{}
 		for _, ip := range ips {
 			log.Printf("ip: %v", ip)
 
 			// keep only v4 ips
 			if ip.To4() != nil {
 				log.Printf("ip is v4: %v", ip)
+				addr := net.JoinHostPort(ip.String(), port)
+				addresses = append(addresses, addr)
+
 				names, err := net.LookupAddr(ip.String())
 				if err != nil {
 					log.Errorf("unable to lookup addr for ip %v: %v", ip, err)
+					r.health.Record(host, addr, 0, err)
 					continue
 				}
 
-				instance := Instance{
-					IP:        ip,
-					HostNames: names,
-				}
-
-				info, err := getKafkaInfo(instance.Name() + ":" + port)
+				instance, err := NewTrackedInstance(r.health, host, ip, names, port)
 				if err != nil {
 					log.Errorf("unable to get kafka info for instance %v: %v", instance, err)
 					continue
 				}
 
-				log.Printf("received info: %+v", info)
-
-				instance.BootstrapServers = info.BootstrapServers
-				instance.Topics = info.Topics
-				instance.HistoryTopic = info.HistoryTopic
 				sch.Instances = append(sch.Instances, instance)
 
 				log.Printf("instances: %+v", sch.Instances)
 			}
 		}
+		// instances which are not resolved anymore are forgotten, unless the lookup failed
+		if err == nil {
+			r.health.Retain(host, addresses)
+		}
+
 		if len(sch.Instances) > 0 {
 			result = append(result, sch)
 		}
@@ -163,6 +172,40 @@ func (r Resolver) List() ([]schedulers.Scheduler, error) {
 	return result, nil
 }
 
+// NewInstance returns the instance with the kafka configuration retrieved from its /info endpoint
+func NewInstance(ip net.IP, hostNames []string, port string) (Instance, error) {
+	instance := Instance{
+		IP:        ip,
+		HostNames: hostNames,
+	}
+
+	info, err := getKafkaInfo(instance.Name() + ":" + port)
+	if err != nil {
+		return instance, err
+	}
+
+	log.Printf("received info: %+v", info)
+
+	instance.BootstrapServers = info.BootstrapServers
+	instance.Topics = info.Topics
+	instance.HistoryTopic = info.HistoryTopic
+
+	return instance, nil
+}
+
+// NewTrackedInstance returns the instance like NewInstance, and records the contact in the health of the scheduler instances
+func NewTrackedInstance(health *schedulers.HealthTracker, name string, ip net.IP, hostNames []string, port string) (Instance, error) {
+	start := time.Now()
+	instance, err := NewInstance(ip, hostNames, port)
+	health.Record(name, net.JoinHostPort(ip.String(), port), time.Since(start), err)
+	return instance, err
+}
+
+// Health returns the health of the instances of a scheduler, recorded when the schedulers are listed
+func (r Resolver) Health(name string) ([]schedulers.InstanceHealth, bool) {
+	return r.health.Health(name)
+}
+
 type kafka struct {
 	BootstrapServers string   `json:"bootstrap_servers"`
 	Topics           []string `json:"topics"`
@@ -0,0 +1,238 @@
+package k8sresolver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	// label set by kubernetes on the endpoint slices with the name of their service
+	serviceNameLabel = "kubernetes.io/service-name"
+	// duration of a watch request before it is renewed
+	watchTimeout = 5 * time.Minute
+)
+
+var (
+	// errGone is returned when the resource version of a watch is too old, the resources have to be listed again
+	errGone = errors.New("resource version too old")
+)
+
+// client is a minimal client of the kubernetes API, for listing and watching the endpoints
+type client struct {
+	baseURL   string
+	tokenFile string
+	http      *http.Client
+}
+
+func newClient(cfg Config) (*client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("invalid CA file: %v", cfg.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    pool,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &client{
+		baseURL:   strings.TrimSuffix(cfg.APIServer, "/"),
+		tokenFile: cfg.TokenFile,
+		// no timeout on the client, the watch requests are long running
+		http: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (c *client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// the token is read for each request, service account tokens are rotated
+	if c.tokenFile != "" {
+		token, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read token file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode == http.StatusGone {
+			return nil, errGone
+		}
+		return nil, fmt.Errorf("unexpected status code from kubernetes API %v: %v %s", path, resp.StatusCode, body)
+	}
+
+	return resp, nil
+}
+
+type objectMeta struct {
+	Name            string            `json:"name"`
+	ResourceVersion string            `json:"resourceVersion"`
+	Labels          map[string]string `json:"labels"`
+}
+
+type list struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []json.RawMessage `json:"items"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// status is the object of the ERROR watch events
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type endpointPort struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+// endpointSlice is a discovery.k8s.io/v1 EndpointSlice
+type endpointSlice struct {
+	Metadata  objectMeta `json:"metadata"`
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready *bool `json:"ready"`
+		} `json:"conditions"`
+	} `json:"endpoints"`
+	Ports []endpointPort `json:"ports"`
+}
+
+// endpoints is a v1 Endpoints
+type endpoints struct {
+	Metadata objectMeta `json:"metadata"`
+	Subsets  []struct {
+		Addresses []struct {
+			IP string `json:"ip"`
+		} `json:"addresses"`
+		Ports []endpointPort `json:"ports"`
+	} `json:"subsets"`
+}
+
+// target is the ready addresses of a service found in an endpoints object
+type target struct {
+	service   string
+	addresses []string
+	port      string
+}
+
+// resource is a kind of endpoints object, EndpointSlice or Endpoints
+type resource struct {
+	path   func(namespace string) string
+	decode func(data []byte, portName string) (objectMeta, target, error)
+}
+
+var endpointSlices = resource{
+	path: func(namespace string) string {
+		return "/apis/discovery.k8s.io/v1/namespaces/" + namespace + "/endpointslices"
+	},
+	decode: func(data []byte, portName string) (objectMeta, target, error) {
+		var es endpointSlice
+		if err := json.Unmarshal(data, &es); err != nil {
+			return objectMeta{}, target{}, err
+		}
+
+		t := target{
+			service: es.Metadata.Labels[serviceNameLabel],
+			port:    findPort(es.Ports, portName),
+		}
+		for _, e := range es.Endpoints {
+			// an unknown condition is interpreted as ready
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				continue
+			}
+			t.addresses = append(t.addresses, e.Addresses...)
+		}
+
+		return es.Metadata, t, nil
+	},
+}
+
+var legacyEndpoints = resource{
+	path: func(namespace string) string {
+		return "/api/v1/namespaces/" + namespace + "/endpoints"
+	},
+	decode: func(data []byte, portName string) (objectMeta, target, error) {
+		var ep endpoints
+		if err := json.Unmarshal(data, &ep); err != nil {
+			return objectMeta{}, target{}, err
+		}
+
+		// the name of the endpoints is the name of its service
+		t := target{
+			service: ep.Metadata.Name,
+		}
+		for _, subset := range ep.Subsets {
+			port := findPort(subset.Ports, portName)
+			if port == "" {
+				continue
+			}
+			t.port = port
+			// not ready addresses are listed in notReadyAddresses
+			for _, a := range subset.Addresses {
+				t.addresses = append(t.addresses, a.IP)
+			}
+		}
+
+		return ep.Metadata, t, nil
+	},
+}
+
+// findPort returns the port with the name, or the only port when there is one
+func findPort(ports []endpointPort, name string) string {
+	for _, p := range ports {
+		if p.Name == name {
+			return strconv.Itoa(p.Port)
+		}
+	}
+	if len(ports) == 1 {
+		return strconv.Itoa(ports[0].Port)
+	}
+	return ""
+}
@@ -0,0 +1,473 @@
+package k8sresolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultPortName = "http"
+	listTimeout     = 10 * time.Second
+	maxBackoff      = 30 * time.Second
+)
+
+var (
+	// ResolveRetryInterval is the delay before the first retry of the retrieval of the kafka configuration of an instance,
+	// doubled after each failure
+	ResolveRetryInterval = time.Second
+)
+
+// Config is the configuration of the kubernetes resolver, the empty fields
+// are set with the in-cluster configuration (service account of the pod)
+type Config struct {
+	// url of the kubernetes API, default is https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_SERVICE_PORT
+	APIServer string
+	TokenFile string
+	CAFile    string
+	// namespace of the schedulers services, default is the namespace of the pod
+	Namespace string
+	// label selector of the schedulers services, ie: app=kafka-message-scheduler
+	LabelSelector string
+	// name of the http port of the schedulers, default is http
+	PortName string
+	// Endpoints tells to watch the v1 Endpoints instead of the EndpointSlices (kubernetes < 1.21)
+	Endpoints bool
+}
+
+func (cfg Config) withDefaults() (Config, error) {
+	if cfg.APIServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return cfg, fmt.Errorf("not running in a kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+		}
+		cfg.APIServer = "https://" + net.JoinHostPort(host, port)
+		if cfg.TokenFile == "" {
+			cfg.TokenFile = serviceAccountDir + "token"
+		}
+		if cfg.CAFile == "" {
+			cfg.CAFile = serviceAccountDir + "ca.crt"
+		}
+	}
+	if cfg.Namespace == "" {
+		ns, err := os.ReadFile(serviceAccountDir + "namespace")
+		if err != nil {
+			return cfg, fmt.Errorf("cannot read namespace of the pod: %w", err)
+		}
+		cfg.Namespace = strings.TrimSpace(string(ns))
+	}
+	if cfg.PortName == "" {
+		cfg.PortName = DefaultPortName
+	}
+	return cfg, nil
+}
+
+// Resolver returns a scheduler per service matching the label selector, with an instance per ready pod.
+// The endpoints are watched through the kubernetes API and the watchers are notified of the changes.
+type Resolver struct {
+	cfg      Config
+	client   *client
+	resource resource
+
+	mutex *sync.RWMutex
+	schs  []schedulers.Scheduler
+
+	// guards the state of the updates, changed by the watch goroutine and by the resolutions of the instances
+	updating *sync.Mutex
+	// endpoints objects by name
+	objects map[string]target
+	// kafka configuration of the instances by address
+	instances map[string]httpresolver.Instance
+	// addresses of the instances whose kafka configuration is being retrieved
+	resolving map[string]bool
+	// services of the last update
+	services []string
+	// running resolutions, waited by close
+	resolutions *sync.WaitGroup
+	// first attempts of the running resolutions, waited by the first list
+	attempts *sync.WaitGroup
+
+	health   *schedulers.HealthTracker
+	notifier schedulers.Notifier
+
+	// used by close
+	ctx      context.Context
+	cancel   context.CancelFunc
+	exitChan chan bool
+}
+
+// NewResolver lists the endpoints and starts watching them
+func NewResolver(cfg Config) (*Resolver, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{
+		cfg:         cfg,
+		client:      c,
+		resource:    endpointSlices,
+		mutex:       &sync.RWMutex{},
+		updating:    &sync.Mutex{},
+		instances:   make(map[string]httpresolver.Instance),
+		resolving:   make(map[string]bool),
+		resolutions: &sync.WaitGroup{},
+		attempts:    &sync.WaitGroup{},
+		health:      schedulers.NewHealthTracker(),
+		notifier:    schedulers.NewNotifier(),
+		exitChan:    make(chan bool, 1),
+	}
+	if cfg.Endpoints {
+		r.resource = legacyEndpoints
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.ctx, r.cancel = ctx, cancel
+
+	resourceVersion, err := r.list(ctx)
+	if err != nil {
+		r.close()
+		return nil, fmt.Errorf("cannot list endpoints: %w", err)
+	}
+	// the schedulers are returned with the instances of the first list, the failed ones are added once retrieved
+	r.attempts.Wait()
+
+	go r.run(ctx, resourceVersion)
+
+	return r, nil
+}
+
+func (r *Resolver) Close() {
+	r.close()
+	<-r.exitChan
+	r.resolutions.Wait()
+	r.notifier.Close()
+}
+
+// close cancels the requests, no resolution is started after it
+func (r *Resolver) close() {
+	r.updating.Lock()
+	defer r.updating.Unlock()
+
+	r.cancel()
+}
+
+func (r *Resolver) List() ([]schedulers.Scheduler, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return append([]schedulers.Scheduler{}, r.schs...), nil
+}
+
+func (r *Resolver) Watch() (<-chan schedulers.Event, func()) {
+	return r.notifier.Watch()
+}
+
+// Health returns the health of the instances of a scheduler, the instances are contacted
+// when they appear in the endpoints and until their kafka configuration is retrieved
+func (r *Resolver) Health(name string) ([]schedulers.InstanceHealth, bool) {
+	return r.health.Health(name)
+}
+
+// run watches the endpoints until the resolver is closed, the endpoints are listed again after an error
+func (r *Resolver) run(ctx context.Context, resourceVersion string) {
+	defer func() {
+		if e := recover(); e != nil {
+			log.Errorf("recovering from panic in kubernetes resolver: %v", e)
+		}
+		r.exitChan <- true
+	}()
+
+	backoff := time.Second
+	var err error
+
+	for {
+		if resourceVersion == "" {
+			resourceVersion, err = r.list(ctx)
+		}
+		if err == nil {
+			resourceVersion, err = r.watch(ctx, resourceVersion)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			backoff = time.Second
+			continue
+		}
+
+		if !errors.Is(err, errGone) {
+			log.Errorf("kubernetes resolver error, retrying in %v: %v", backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		resourceVersion, err = "", nil
+	}
+}
+
+// list replaces the endpoints objects and returns the resource version of the list
+func (r *Resolver) list(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, listTimeout)
+	defer cancel()
+
+	resp, err := r.client.get(ctx, r.resource.path(r.cfg.Namespace), url.Values{
+		"labelSelector": {r.cfg.LabelSelector},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var l list
+	if err := json.NewDecoder(resp.Body).Decode(&l); err != nil {
+		return "", fmt.Errorf("cannot decode list: %w", err)
+	}
+
+	objects := make(map[string]target)
+	for _, item := range l.Items {
+		meta, t, err := r.resource.decode(item, r.cfg.PortName)
+		if err != nil {
+			return "", fmt.Errorf("cannot decode endpoints: %w", err)
+		}
+		objects[meta.Name] = t
+	}
+	r.update(func() {
+		r.objects = objects
+	})
+
+	return l.Metadata.ResourceVersion, nil
+}
+
+// watch applies the changes of the endpoints objects until the watch request ends, and returns the last resource version
+func (r *Resolver) watch(ctx context.Context, resourceVersion string) (string, error) {
+	resp, err := r.client.get(ctx, r.resource.path(r.cfg.Namespace), url.Values{
+		"labelSelector":       {r.cfg.LabelSelector},
+		"watch":               {"1"},
+		"allowWatchBookmarks": {"true"},
+		"resourceVersion":     {resourceVersion},
+		"timeoutSeconds":      {strconv.Itoa(int(watchTimeout.Seconds()))},
+	})
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return resourceVersion, nil
+			}
+			return resourceVersion, fmt.Errorf("cannot decode watch event: %w", err)
+		}
+
+		if event.Type == "ERROR" {
+			var s status
+			_ = json.Unmarshal(event.Object, &s)
+			if s.Code == http.StatusGone {
+				return "", errGone
+			}
+			return resourceVersion, fmt.Errorf("watch error: %v %v", s.Code, s.Message)
+		}
+
+		meta, t, err := r.resource.decode(event.Object, r.cfg.PortName)
+		if err != nil {
+			return resourceVersion, fmt.Errorf("cannot decode endpoints: %w", err)
+		}
+		resourceVersion = meta.ResourceVersion
+
+		var change func()
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			change = func() {
+				r.objects[meta.Name] = t
+			}
+		case "DELETED":
+			change = func() {
+				delete(r.objects, meta.Name)
+			}
+		default:
+			// BOOKMARK only updates the resource version
+			continue
+		}
+
+		log.Printf("kubernetes resolver: %v %v", event.Type, meta.Name)
+		r.update(change)
+	}
+}
+
+// update applies the change to the endpoints objects, builds the schedulers and notifies the watchers when they have changed.
+// The kafka configuration of the new instances is retrieved in the background, they are added by the update of their resolution.
+func (r *Resolver) update(change func()) {
+	r.updating.Lock()
+	defer r.updating.Unlock()
+
+	if change != nil {
+		change()
+	}
+
+	addresses := make(map[string][]string)
+	ports := make(map[string]string)
+	for _, t := range r.objects {
+		if t.service == "" || t.port == "" {
+			continue
+		}
+		addresses[t.service] = append(addresses[t.service], t.addresses...)
+		ports[t.service] = t.port
+	}
+
+	names := make([]string, 0, len(addresses))
+	for name := range addresses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	instances := make(map[string]httpresolver.Instance)
+	schs := []schedulers.Scheduler{}
+
+	for _, name := range names {
+		sch := httpresolver.Scheduler{
+			HostName: name,
+			HTTPPort: ports[name],
+		}
+
+		ips := addresses[name]
+		sort.Strings(ips)
+		addrs := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			addr := net.JoinHostPort(ip, sch.HTTPPort)
+			addrs = append(addrs, addr)
+			instance, ok := r.instances[addr]
+			if !ok {
+				r.resolve(name, ip, sch.HTTPPort)
+				continue
+			}
+			instances[addr] = instance
+			sch.Instances = append(sch.Instances, instance)
+		}
+		r.health.Retain(name, addrs)
+
+		if len(sch.Instances) > 0 {
+			schs = append(schs, sch)
+		}
+	}
+
+	// instances of the removed pods are forgotten
+	r.instances = instances
+	for _, name := range r.services {
+		if _, found := addresses[name]; !found {
+			r.health.Retain(name, nil)
+		}
+	}
+	r.services = names
+
+	r.mutex.Lock()
+	events := schedulers.Diff(r.schs, schs)
+	r.schs = schs
+	r.mutex.Unlock()
+
+	if len(events) > 0 {
+		log.Printf("kubernetes resolver: schedulers changed: %+v", events)
+		r.notifier.Notify(events...)
+	}
+}
+
+// resolve retrieves the kafka configuration of an instance in the background, unless already running,
+// the schedulers are updated with the instance once retrieved. The failed retrievals are retried with a backoff,
+// until the instance is removed from the endpoints or the resolver is closed. Called with the updating lock.
+func (r *Resolver) resolve(name, ip, port string) {
+	addr := net.JoinHostPort(ip, port)
+	if r.resolving[addr] || r.ctx.Err() != nil {
+		return
+	}
+	r.resolving[addr] = true
+
+	r.resolutions.Add(1)
+	r.attempts.Add(1)
+	go func() {
+		defer r.resolutions.Done()
+
+		// the first attempt ends with the first failure or the update of the instance
+		attempted := &sync.Once{}
+		defer attempted.Do(r.attempts.Done)
+
+		backoff := ResolveRetryInterval
+
+		for {
+			instance, err := httpresolver.NewTrackedInstance(r.health, name, net.ParseIP(ip), nil, port)
+			if err == nil {
+				r.update(func() {
+					delete(r.resolving, addr)
+					r.instances[addr] = instance
+				})
+				return
+			}
+
+			log.Errorf("unable to get kafka info for instance %v of scheduler %v, retrying in %v: %v", addr, name, backoff, err)
+			attempted.Do(r.attempts.Done)
+
+			select {
+			case <-time.After(backoff):
+			case <-r.ctx.Done():
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+
+			r.updating.Lock()
+			retry := r.ctx.Err() == nil && r.serves(name, ip, port)
+			if !retry {
+				delete(r.resolving, addr)
+			}
+			r.updating.Unlock()
+			if !retry {
+				return
+			}
+		}
+	}()
+}
+
+// serves returns true when the instance is in the endpoints of the scheduler. Called with the updating lock.
+func (r *Resolver) serves(name, ip, port string) bool {
+	for _, t := range r.objects {
+		if t.service != name || t.port != port {
+			continue
+		}
+		for _, address := range t.addresses {
+			if address == ip {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,357 @@
+package k8sresolver_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/k8sresolver"
+)
+
+// fakeAPIServer serves the list and the watch of the endpoints, like the kubernetes API
+type fakeAPIServer struct {
+	*httptest.Server
+	path   string
+	mutex  *sync.Mutex
+	items  []string
+	events chan string
+}
+
+func newFakeAPIServer(t *testing.T, path string, items ...string) *fakeAPIServer {
+	s := &fakeAPIServer{
+		path:   path,
+		mutex:  &sync.Mutex{},
+		items:  items,
+		events: make(chan string, 10),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != s.path || r.URL.Query().Get("labelSelector") != "app=scheduler" {
+			t.Errorf("unexpected request: %v", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("watch") != "1" {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			fmt.Fprintf(w, `{"metadata": {"resourceVersion": "1"}, "items": [%v]}`, strings.Join(s.items, ","))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case event := <-s.events:
+				fmt.Fprintln(w, event)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+
+	return s
+}
+
+func (s *fakeAPIServer) setItems(items ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.items = items
+}
+
+// newInfoServer returns the address and port of a scheduler instance
+func newInfoServer() (*httptest.Server, string, string) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"hostname": "scheduler", "kafka": {"bootstrap_servers": "kafka:9092", "topics": ["schedules"], "history_topic": "history"}}`)
+	}))
+	u, _ := url.Parse(srv.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	return srv, host, port
+}
+
+func endpointSlice(name, service, ip, port string, ready bool) string {
+	return fmt.Sprintf(`{"metadata": {"name": %q, "resourceVersion": "2", "labels": {"kubernetes.io/service-name": %q}},
+		"endpoints": [{"addresses": [%q], "conditions": {"ready": %v}}], "ports": [{"name": "http", "port": %v}, {"name": "metrics", "port": 9001}]}`,
+		name, service, ip, ready, port)
+}
+
+func newConfig(t *testing.T, apiServer string, endpoints bool) k8sresolver.Config {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return k8sresolver.Config{
+		APIServer:     apiServer,
+		TokenFile:     tokenFile,
+		Namespace:     "schedulers",
+		LabelSelector: "app=scheduler",
+		Endpoints:     endpoints,
+	}
+}
+
+func names(schs []schedulers.Scheduler) string {
+	result := []string{}
+	for _, sch := range schs {
+		result = append(result, sch.Name())
+	}
+	return fmt.Sprint(result)
+}
+
+// Rule #1: resolver should return a scheduler per service and notify the changes of the endpoint slices
+func TestResolver_endpointSlices(t *testing.T) {
+	info, ip, port := newInfoServer()
+	defer info.Close()
+
+	api := newFakeAPIServer(t, "/apis/discovery.k8s.io/v1/namespaces/schedulers/endpointslices",
+		endpointSlice("scheduler-1-abc", "scheduler-1", ip, port, true),
+		endpointSlice("scheduler-2-abc", "scheduler-2", ip, port, false),
+	)
+	defer api.Close()
+
+	r, err := k8sresolver.NewResolver(newConfig(t, api.URL, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	schs, err := r.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// scheduler-2 has no ready instance
+	if names(schs) != "[scheduler-1]" {
+		t.Fatalf("unexpected schedulers: %v", names(schs))
+	}
+	sch := schs[0].(httpresolver.Scheduler)
+	if sch.HTTPPort != port || len(sch.Instances) != 1 || sch.Instances[0].Name() != ip {
+		t.Errorf("unexpected scheduler: %+v", sch)
+	}
+	if sch.BootstrapServers() != "kafka:9092" || fmt.Sprint(sch.Topics()) != "[schedules]" || sch.History() != "history" {
+		t.Errorf("unexpected kafka info: %+v", sch)
+	}
+
+	instances, found := r.Health("scheduler-1")
+	if !found || len(instances) != 1 || instances[0].Address != net.JoinHostPort(ip, port) || instances[0].Status != schedulers.StatusUp {
+		t.Errorf("unexpected health: %+v", instances)
+	}
+	if _, found := r.Health("scheduler-2"); found {
+		t.Errorf("scheduler-2 has no ready instance, it should not have health records")
+	}
+
+	events, stop := r.Watch()
+	defer stop()
+
+	tests := []struct {
+		events         []string
+		items          []string
+		expectedEvents []string
+		expected       string
+	}{
+		{
+			events:         []string{`{"type": "MODIFIED", "object": ` + endpointSlice("scheduler-2-abc", "scheduler-2", ip, port, true) + `}`},
+			expectedEvents: []string{"added scheduler-2"},
+			expected:       "[scheduler-1 scheduler-2]",
+		},
+		{
+			events: []string{
+				`{"type": "BOOKMARK", "object": {"metadata": {"resourceVersion": "3"}}}`,
+				`{"type": "DELETED", "object": ` + endpointSlice("scheduler-1-abc", "scheduler-1", ip, port, true) + `}`,
+			},
+			expectedEvents: []string{"removed scheduler-1"},
+			expected:       "[scheduler-2]",
+		},
+		// resource version too old, endpoints are listed again
+		{
+			items:          []string{endpointSlice("scheduler-3-abc", "scheduler-3", ip, port, true)},
+			events:         []string{`{"type": "ERROR", "object": {"kind": "Status", "code": 410, "message": "too old resource version"}}`},
+			expectedEvents: []string{"added scheduler-3", "removed scheduler-2"},
+			expected:       "[scheduler-3]",
+		},
+	}
+
+	for i, tt := range tests {
+		if tt.items != nil {
+			api.setItems(tt.items...)
+		}
+		for _, event := range tt.events {
+			api.events <- event
+		}
+
+		for _, expected := range tt.expectedEvents {
+			select {
+			case evt := <-events:
+				if actual := fmt.Sprintf("%v %v", evt.EventType, evt.Name()); actual != expected {
+					t.Errorf("case #%v: unexpected event: %v, expected %v", i+1, actual, expected)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("case #%v: change not notified", i+1)
+			}
+		}
+
+		schs, _ := r.List()
+		if names(schs) != tt.expected {
+			t.Errorf("case #%v: unexpected schedulers: %v, expected %v", i+1, names(schs), tt.expected)
+		}
+	}
+
+	r.Close()
+
+	if _, ok := <-events; ok {
+		t.Errorf("events channel should be closed")
+	}
+}
+
+// Rule #2: resolver should support the v1 endpoints
+func TestResolver_endpoints(t *testing.T) {
+	info, ip, port := newInfoServer()
+	defer info.Close()
+
+	api := newFakeAPIServer(t, "/api/v1/namespaces/schedulers/endpoints",
+		fmt.Sprintf(`{"metadata": {"name": "scheduler-1"}, "subsets": [{"addresses": [{"ip": %q}], "notReadyAddresses": [{"ip": "10.0.0.1"}], "ports": [{"port": %v}]}]}`, ip, port),
+	)
+	defer api.Close()
+
+	r, err := k8sresolver.NewResolver(newConfig(t, api.URL, true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	schs, _ := r.List()
+	if names(schs) != "[scheduler-1]" || len(schs[0].(httpresolver.Scheduler).Instances) != 1 {
+		t.Errorf("unexpected schedulers: %+v", schs)
+	}
+
+	// invalid credentials
+	cfg := newConfig(t, api.URL, true)
+	cfg.TokenFile = ""
+	_, err = k8sresolver.NewResolver(cfg)
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+// Rule #3: the kafka configuration of the new instances should be retrieved without blocking the watch
+func TestResolver_slow_instance(t *testing.T) {
+	info, ip, port := newInfoServer()
+	defer info.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+		fmt.Fprint(w, `{"hostname": "scheduler", "kafka": {"bootstrap_servers": "kafka:9092", "topics": ["schedules"], "history_topic": "history"}}`)
+	}))
+	defer slow.Close()
+	u, _ := url.Parse(slow.URL)
+	_, slowPort, _ := net.SplitHostPort(u.Host)
+
+	api := newFakeAPIServer(t, "/apis/discovery.k8s.io/v1/namespaces/schedulers/endpointslices",
+		endpointSlice("scheduler-1-abc", "scheduler-1", ip, port, true),
+	)
+	defer api.Close()
+
+	r, err := k8sresolver.NewResolver(newConfig(t, api.URL, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	events, stop := r.Watch()
+	defer stop()
+
+	start := time.Now()
+	api.events <- `{"type": "ADDED", "object": ` + endpointSlice("scheduler-2-abc", "scheduler-2", ip, slowPort, true) + `}`
+	api.events <- `{"type": "ADDED", "object": ` + endpointSlice("scheduler-3-abc", "scheduler-3", ip, port, true) + `}`
+
+	for _, expected := range []string{"added scheduler-3", "added scheduler-2"} {
+		select {
+		case evt := <-events:
+			if actual := fmt.Sprintf("%v %v", evt.EventType, evt.Name()); actual != expected {
+				t.Errorf("unexpected event: %v, expected %v", actual, expected)
+			}
+			if expected == "added scheduler-3" && time.Since(start) > 500*time.Millisecond {
+				t.Errorf("scheduler-3 delayed by the slow instance: %v", time.Since(start))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("change not notified")
+		}
+	}
+
+	schs, _ := r.List()
+	if names(schs) != "[scheduler-1 scheduler-2 scheduler-3]" {
+		t.Errorf("unexpected schedulers: %v", names(schs))
+	}
+}
+
+// Rule #4: the kafka configuration of the unreachable instances should be retrieved again, without waiting for a change of the endpoints
+func TestResolver_unreachable_instance(t *testing.T) {
+	interval := k8sresolver.ResolveRetryInterval
+	k8sresolver.ResolveRetryInterval = 10 * time.Millisecond
+	defer func() {
+		k8sresolver.ResolveRetryInterval = interval
+	}()
+
+	var mutex sync.Mutex
+	failures := 3
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"hostname": "scheduler", "kafka": {"bootstrap_servers": "kafka:9092", "topics": ["schedules"], "history_topic": "history"}}`)
+	}))
+	defer info.Close()
+	u, _ := url.Parse(info.URL)
+	ip, port, _ := net.SplitHostPort(u.Host)
+
+	api := newFakeAPIServer(t, "/apis/discovery.k8s.io/v1/namespaces/schedulers/endpointslices",
+		endpointSlice("scheduler-1-abc", "scheduler-1", ip, port, true),
+	)
+	defer api.Close()
+
+	r, err := k8sresolver.NewResolver(newConfig(t, api.URL, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer r.Close()
+
+	events, stop := r.Watch()
+	defer stop()
+
+	schs, _ := r.List()
+	if names(schs) != "[]" {
+		t.Errorf("unexpected schedulers: %v", names(schs))
+	}
+
+	select {
+	case evt := <-events:
+		if actual := fmt.Sprintf("%v %v", evt.EventType, evt.Name()); actual != "added scheduler-1" {
+			t.Errorf("unexpected event: %v", actual)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("instance not retried")
+	}
+
+	schs, _ = r.List()
+	if names(schs) != "[scheduler-1]" {
+		t.Errorf("unexpected schedulers: %v", names(schs))
+	}
+}
@@ -0,0 +1,142 @@
+package multi
+
+import (
+	"sync"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	log "github.com/sirupsen/logrus"
+)
+
+// Resolver combines the schedulers of several resolvers, when a scheduler name is returned
+// by more than one resolver, the scheduler of the first resolver is kept
+type Resolver []schedulers.Resolver
+
+func NewResolver(resolvers ...schedulers.Resolver) Resolver {
+	return Resolver(resolvers)
+}
+
+// List returns the schedulers of all the resolvers, when a resolver returns an error
+// the schedulers of the other resolvers are returned with the error (partial results)
+func (m Resolver) List() ([]schedulers.Scheduler, error) {
+	result := []schedulers.Scheduler{}
+	names := map[string]bool{}
+	var lastErr error
+
+	for _, r := range m {
+		schs, err := r.List()
+		if err != nil {
+			log.Errorf("resolver %T returned an error: %v", r, err)
+			lastErr = err
+		}
+
+		for _, sch := range schs {
+			if names[sch.Name()] {
+				log.Warnf("scheduler %v already returned by another resolver, ignored", sch.Name())
+				continue
+			}
+			names[sch.Name()] = true
+			result = append(result, sch)
+		}
+	}
+
+	return result, lastErr
+}
+
+// Watch returns the changes of the combined schedulers, the schedulers are listed again when a resolver
+// which is a watcher notifies a change. The channel never receives events when none of the resolvers is a watcher.
+func (m Resolver) Watch() (<-chan schedulers.Event, func()) {
+	events := make(chan schedulers.Event, schedulers.NotifierChanSize)
+	changed := make(chan struct{}, 1)
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	stops := []func(){}
+
+	for _, r := range m {
+		w, ok := r.(schedulers.Watcher)
+		if !ok {
+			continue
+		}
+
+		ch, stop := w.Watch()
+		stops = append(stops, stop)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the channel is closed by stop
+			for range ch {
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	previous, _ := m.List()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-changed:
+				current, err := m.List()
+				changes := schedulers.Diff(previous, current)
+				previous = current
+
+				for _, evt := range changes {
+					// with partial results, the missing schedulers are not removed
+					if err != nil && evt.EventType == schedulers.RemovedType {
+						previous = append(previous, evt.Scheduler)
+						continue
+					}
+					select {
+					case events <- evt:
+					case <-done:
+						return
+					}
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	once := &sync.Once{}
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			for _, s := range stops {
+				s()
+			}
+			wg.Wait()
+			close(events)
+		})
+	}
+
+	return events, stop
+}
+
+// Health returns the health of the instances of a scheduler from the first resolver which knows the scheduler
+func (m Resolver) Health(name string) ([]schedulers.InstanceHealth, bool) {
+	for _, r := range m {
+		h, ok := r.(schedulers.HealthReporter)
+		if !ok {
+			continue
+		}
+		if instances, found := h.Health(name); found {
+			return instances, true
+		}
+	}
+	return nil, false
+}
+
+// Close closes the resolvers which need to be closed (ie: file resolver)
+func (m Resolver) Close() {
+	for _, r := range m {
+		if c, ok := r.(interface{ Close() }); ok {
+			c.Close()
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package multi_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/multi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+)
+
+var errResolver = errors.New("resolver error")
+
+type failingResolver struct{}
+
+func (failingResolver) List() ([]schedulers.Scheduler, error) {
+	return nil, errResolver
+}
+
+// Rule #1: schedulers of all the resolvers should be returned, the first resolver has priority
+func TestResolver_List(t *testing.T) {
+	r1 := slice.NewResolver()
+	r1.Add(slice.Scheduler{SchedulerName: "scheduler-1"}, slice.Scheduler{SchedulerName: "scheduler-2"})
+	r2 := slice.NewResolver()
+	r2.Add(slice.Scheduler{SchedulerName: "scheduler-2"}, slice.Scheduler{SchedulerName: "scheduler-3"})
+
+	tests := []struct {
+		resolver      multi.Resolver
+		expected      string
+		expectedError error
+	}{
+		{multi.NewResolver(r1, r2), "[{scheduler-1} {scheduler-2} {scheduler-3}]", nil},
+		// partial results
+		{multi.NewResolver(failingResolver{}, r2), "[{scheduler-2} {scheduler-3}]", errResolver},
+		{multi.NewResolver(failingResolver{}), "[]", errResolver},
+		{multi.NewResolver(), "[]", nil},
+	}
+
+	for i, tt := range tests {
+		schs, err := tt.resolver.List()
+		if !errors.Is(err, tt.expectedError) {
+			t.Errorf("case #%v: unexpected error: %v", i+1, err)
+		}
+		if actual := fmt.Sprint(schs); actual != tt.expected {
+			t.Errorf("case #%v: unexpected schedulers: %v", i+1, actual)
+		}
+	}
+}
+
+type watcher struct {
+	*slice.Slice
+	schedulers.Notifier
+}
+
+// Rule #2: changes of the watchers should be notified with the priority of the resolvers
+func TestResolver_Watch(t *testing.T) {
+	r1 := slice.NewResolver()
+	r1.Add(slice.Scheduler{SchedulerName: "scheduler-1"})
+	w := watcher{slice.NewResolver(), schedulers.NewNotifier()}
+	r := multi.NewResolver(r1, w)
+
+	events, stop := r.Watch()
+
+	tests := []struct {
+		update   func()
+		expected string
+	}{
+		{
+			update: func() {
+				w.Add(slice.Scheduler{SchedulerName: "scheduler-1"}, slice.Scheduler{SchedulerName: "scheduler-2"})
+			},
+			// scheduler-1 of the first resolver is kept
+			expected: "added {scheduler-2}",
+		},
+		{
+			update: func() {
+				w.Reset()
+				w.Add(slice.Scheduler{SchedulerName: "scheduler-1"})
+			},
+			expected: "removed {scheduler-2}",
+		},
+	}
+
+	for i, tt := range tests {
+		tt.update()
+		w.Notify(schedulers.Event{EventType: schedulers.ChangedType, Scheduler: slice.Scheduler{}})
+
+		select {
+		case evt := <-events:
+			if actual := fmt.Sprintf("%v %v", evt.EventType, evt.Scheduler); actual != tt.expected {
+				t.Errorf("case #%v: unexpected event: %v, expected %v", i+1, actual, tt.expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("case #%v: change not notified", i+1)
+		}
+	}
+
+	stop()
+	if _, ok := <-events; ok {
+		t.Errorf("events channel should be closed")
+	}
+}
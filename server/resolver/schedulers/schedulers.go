@@ -1,5 +1,17 @@
 package schedulers
 
+import (
+	"reflect"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// NotifierChanSize is the size of the watchers channels, events are dropped when a channel is full
+	NotifierChanSize = 100
+)
+
 type Resolver interface {
 	List() ([]Scheduler, error)
 }
@@ -7,3 +19,127 @@ type Resolver interface {
 type Scheduler interface {
 	Name() string
 }
+
+type EventType int
+
+const (
+	AddedType EventType = iota
+	ChangedType
+	RemovedType
+)
+
+func (e EventType) String() string {
+	switch e {
+	case AddedType:
+		return "added"
+	case ChangedType:
+		return "changed"
+	case RemovedType:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a scheduler, for a removed scheduler it is the last known definition
+type Event struct {
+	EventType
+	Scheduler
+}
+
+// Watcher is implemented by the resolvers which are notified of the changes of the schedulers
+type Watcher interface {
+	// Watch returns a channel receiving the changes of the schedulers,
+	// stop closes the channel and must be called when the events are not read anymore
+	Watch() (events <-chan Event, stop func())
+}
+
+// Diff returns the events for the changes between two lists of schedulers
+func Diff(previous, current []Scheduler) []Event {
+	result := []Event{}
+
+	byName := make(map[string]Scheduler, len(previous))
+	for _, sch := range previous {
+		byName[sch.Name()] = sch
+	}
+
+	for _, sch := range current {
+		prev, found := byName[sch.Name()]
+		switch {
+		case !found:
+			result = append(result, Event{AddedType, sch})
+		case !reflect.DeepEqual(prev, sch):
+			result = append(result, Event{ChangedType, sch})
+		}
+		delete(byName, sch.Name())
+	}
+
+	// keep the order of the previous list for the removed schedulers
+	for _, sch := range previous {
+		if _, removed := byName[sch.Name()]; removed {
+			result = append(result, Event{RemovedType, sch})
+		}
+	}
+
+	return result
+}
+
+// Notifier sends the events to the watchers of a resolver
+type Notifier struct {
+	mutex    *sync.Mutex
+	watchers map[chan Event]bool
+}
+
+func NewNotifier() Notifier {
+	return Notifier{
+		mutex:    &sync.Mutex{},
+		watchers: make(map[chan Event]bool),
+	}
+}
+
+func (n Notifier) Watch() (<-chan Event, func()) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	ch := make(chan Event, NotifierChanSize)
+	n.watchers[ch] = true
+
+	stop := func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		if n.watchers[ch] {
+			delete(n.watchers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, stop
+}
+
+// Notify sends the events to the watchers, without blocking the resolver: the events are
+// dropped for a watcher which doesn't read them, it has to list the schedulers periodically
+func (n Notifier) Notify(events ...Event) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for ch := range n.watchers {
+		for _, evt := range events {
+			select {
+			case ch <- evt:
+			default:
+				log.Warnf("watcher channel is full, scheduler event dropped: %v %v", evt.EventType, evt.Name())
+			}
+		}
+	}
+}
+
+// Close closes the channels of the watchers
+func (n Notifier) Close() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for ch := range n.watchers {
+		delete(n.watchers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,69 @@
+package schedulers_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+)
+
+type scheduler struct {
+	name    string
+	address string
+}
+
+func (s scheduler) Name() string {
+	return s.name
+}
+
+// Rule #1: diff should return the added, changed and removed schedulers
+func TestDiff(t *testing.T) {
+	previous := []schedulers.Scheduler{scheduler{"scheduler-1", "a"}, scheduler{"scheduler-2", "a"}, scheduler{"scheduler-3", "a"}}
+	current := []schedulers.Scheduler{scheduler{"scheduler-2", "b"}, scheduler{"scheduler-3", "a"}, scheduler{"scheduler-4", "a"}}
+
+	actual := []string{}
+	for _, evt := range schedulers.Diff(previous, current) {
+		actual = append(actual, fmt.Sprintf("%v %v", evt.EventType, evt.Scheduler))
+	}
+	expected := "[changed {scheduler-2 b} added {scheduler-4 a} removed {scheduler-1 a}]"
+	if fmt.Sprint(actual) != expected {
+		t.Errorf("unexpected events: %v, expected %v", actual, expected)
+	}
+
+	if events := schedulers.Diff(current, current); len(events) != 0 {
+		t.Errorf("unexpected events: %v", events)
+	}
+}
+
+// Rule #2: notifier should send the events to all the watchers, until they stop
+func TestNotifier(t *testing.T) {
+	n := schedulers.NewNotifier()
+
+	events1, stop1 := n.Watch()
+	events2, stop2 := n.Watch()
+
+	evt := schedulers.Event{EventType: schedulers.AddedType, Scheduler: scheduler{"scheduler-1", "a"}}
+	n.Notify(evt)
+
+	for i, events := range []<-chan schedulers.Event{events1, events2} {
+		if actual := <-events; actual != evt {
+			t.Errorf("watcher #%v: unexpected event: %v", i+1, actual)
+		}
+	}
+
+	stop1()
+	if _, ok := <-events1; ok {
+		t.Errorf("events channel should be closed")
+	}
+
+	// events are dropped when the channel is full
+	for i := 0; i < schedulers.NotifierChanSize+10; i++ {
+		n.Notify(evt)
+	}
+	if len(events2) != schedulers.NotifierChanSize {
+		t.Errorf("unexpected events count: %v", len(events2))
+	}
+
+	n.Close()
+	stop2()
+}
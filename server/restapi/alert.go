@@ -0,0 +1,20 @@
+package restapi
+
+import (
+	"net/http"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/alert"
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+)
+
+// overdueAlerts responds with the report of the last check of the overdue schedules,
+// restricted to the schedulers visible by the principal of the request
+func overdueAlerts(c *alert.Checker) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Last().Filter(func(schedulerName string) bool {
+			return allowed(r, auth.Viewer, schedulerName)
+		})
+
+		respondWithJSON(w, http.StatusOK, report)
+	}
+}
@@ -0,0 +1,111 @@
+package restapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/alert"
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+)
+
+// Rule #33: the overdue schedules of the last check should be returned for the schedulers visible by the principal
+func TestRestAPIServer_overdueAlerts(t *testing.T) {
+	dir := t.TempDir()
+
+	bindings, err := auth.LoadRoleBindings(writeFile(t, dir, "roles", `
+viewer:viewer@scheduler-1
+admin:admin
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := auth.LoadTokens(writeFile(t, dir, "tokens", `
+viewer:viewer-token
+admin:admin-token
+`), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolver := slice.NewResolver()
+	resolver.Add(slice.Scheduler{SchedulerName: "scheduler-1"}, slice.Scheduler{SchedulerName: "scheduler-2"})
+
+	cold := hmap.NewStore()
+	cold.Add("scheduler-1", rest.Schedule{ScheduleID: "schedule-1", ScheduleEpoch: 1000})
+	cold.Add("scheduler-2", rest.Schedule{ScheduleID: "schedule-2", ScheduleEpoch: 1000})
+	coldDB := simple.DB{Store: cold}
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     hmap.NewStore(),
+		History:        hmap.NewStore(),
+		CancelledStore: hmap.NewStore(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checker := alert.NewChecker(alert.Config{
+		ColdDB:   coldDB,
+		Tracker:  tracker,
+		Resolver: resolver,
+	})
+	checker.Check()
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    coldDB,
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Resolver:  resolver,
+		Auth:      auth.Chain{tokens},
+		Outcomes:  tracker,
+		Alerts:    checker,
+	})
+
+	tests := []struct {
+		token          string
+		expectedCode   int
+		expectedTotals map[string]int
+		expectedIDs    []string
+	}{
+		{"viewer-token", http.StatusOK, map[string]int{"scheduler-1": 1}, []string{"schedule-1"}},
+		{"admin-token", http.StatusOK, map[string]int{"scheduler-1": 1, "scheduler-2": 1}, []string{"schedule-1", "schedule-2"}},
+		{"unknown-token", http.StatusUnauthorized, nil, nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/alerts/overdue", http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			response := executeRequest(router, req)
+			checkResponseCode(t, tt.expectedCode, response.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var report alert.Report
+			if err := json.Unmarshal(response.Body.Bytes(), &report); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ids := []string{}
+			for _, o := range report.Schedules {
+				ids = append(ids, o.ID)
+				if o.Epoch != 1000 || o.Late < time.Now().Unix()-1000-1 {
+					t.Errorf("unexpected overdue schedule: %+v", o)
+				}
+			}
+			if !reflect.DeepEqual(report.Totals, tt.expectedTotals) || report.Total != len(tt.expectedIDs) || !reflect.DeepEqual(ids, tt.expectedIDs) {
+				t.Errorf("unexpected report: %+v", report)
+			}
+		})
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
 	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler/schedule"
 )
 
@@ -110,7 +112,7 @@ func TestRestAPIServer_getSchedule_found(t *testing.T) {
 func TestRestAPIServer_listSchedulers_not_found_or_error(t *testing.T) {
 	resolver := slice.NewResolver()
 
-	router := restapi.NewRouter(nil, nil, nil, resolver)
+	router := restapi.NewRouter(restapi.Config{Resolver: resolver})
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelFunc()
@@ -142,7 +144,7 @@ func TestRestAPIServer_listSchedulers_not_found_or_error(t *testing.T) {
 func TestRestAPIServer_listSchedulers_found(t *testing.T) {
 	resolver := slice.NewResolver()
 
-	router := restapi.NewRouter(nil, nil, nil, resolver)
+	router := restapi.NewRouter(restapi.Config{Resolver: resolver})
 
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelFunc()
@@ -363,17 +365,18 @@ func TestRestAPIServer_searchSchedules_max(t *testing.T) {
 		query             searchQuery
 		expectedFound     int
 		expectedSchedules []schedule.Schedule
+		expectedNext      string
 	}{
 		// max to 1
-		{schedulersSchedules(scheduler1), "scheduler-1", searchQuery{max: 1}, 10, schedules10[0:1]},
+		{schedulersSchedules(scheduler1), "scheduler-1", searchQuery{max: 1}, 10, schedules10[0:1], cursor(1)},
 		// max greater than result set
-		{schedulersSchedules(scheduler1), "scheduler-1", searchQuery{max: 20}, 10, schedules10},
+		{schedulersSchedules(scheduler1), "scheduler-1", searchQuery{max: 20}, 10, schedules10, ""},
 		// invalid max, should default to default max
-		{schedulersSchedules(scheduler3), "scheduler-3", searchQuery{max: -10}, len(schedulesOverMax), schedulesOverMax[0:max]},
-		{schedulersSchedules(scheduler3), "scheduler-3", searchQuery{max: 0}, len(schedulesOverMax), schedulesOverMax[0:max]},
+		{schedulersSchedules(scheduler3), "scheduler-3", searchQuery{max: -10}, len(schedulesOverMax), schedulesOverMax[0:max], cursor(max)},
+		{schedulersSchedules(scheduler3), "scheduler-3", searchQuery{max: 0}, len(schedulesOverMax), schedulesOverMax[0:max], cursor(max)},
 		// no max defined, should default to default max
-		{schedulersSchedules(scheduler2), "scheduler-2", searchQuery{}, max, schedulesMax},
-		{schedulersSchedules(scheduler3), "scheduler-3", searchQuery{}, len(schedulesOverMax), schedulesOverMax[0:max]},
+		{schedulersSchedules(scheduler2), "scheduler-2", searchQuery{}, max, schedulesMax, ""},
+		{schedulersSchedules(scheduler3), "scheduler-3", searchQuery{}, len(schedulesOverMax), schedulesOverMax[0:max], cursor(max)},
 	}
 
 	for _, url := range SchedulesEndpoints {
@@ -385,12 +388,10 @@ func TestRestAPIServer_searchSchedules_max(t *testing.T) {
 				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, tt.query.toURLParams(surl), http.NoBody)
 				response := executeRequest(router, req)
 
-				checkResponseJSON(t, http.StatusOK, response, toJSON(t, struct {
-					Found     int                 `json:"found"`
-					Schedules []schedule.Schedule `json:"schedules"`
-				}{
+				checkResponseJSON(t, http.StatusOK, response, toJSON(t, searchResult{
 					Found:     tt.expectedFound,
 					Schedules: tt.expectedSchedules,
+					Next:      tt.expectedNext,
 				}))
 			})
 		}
@@ -566,17 +567,18 @@ func TestRestAPIServer_live_searchSchedules_search_multicriteria(t *testing.T) {
 		expectedCode      int
 		expectedFound     int
 		expectedSchedules []schedule.Schedule
+		expectedNext      string
 	}{
-		{schedules, "scheduler-1", searchQuery{schedulerName: scheduler1.SchedulerName, schedulerID: s1.ID(), max: 1}, http.StatusOK, 1, schedulesSlice(s1)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, schedulerID: "sch", max: 2, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, schedulerID: "sch", max: 10, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4, s3)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, schedulerID: "sch", max: -1, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4, s3)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: -1, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4, s3)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: -1, sortField: "id", sortOrder: "asc"}, http.StatusOK, 3, schedulesSlice(s3, s4, s5)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 2, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "asc"}, http.StatusOK, 2, schedulesSlice(s3, s4)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 2, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "desc"}, http.StatusOK, 2, schedulesSlice(s4, s3)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 1, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "asc"}, http.StatusOK, 2, schedulesSlice(s3)},
-		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 99, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "desc"}, http.StatusOK, 2, schedulesSlice(s4, s3)},
+		{schedules, "scheduler-1", searchQuery{schedulerName: scheduler1.SchedulerName, schedulerID: s1.ID(), max: 1}, http.StatusOK, 1, schedulesSlice(s1), ""},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, schedulerID: "sch", max: 2, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4), cursor(2)},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, schedulerID: "sch", max: 10, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4, s3), ""},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, schedulerID: "sch", max: -1, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4, s3), ""},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: -1, sortField: "id", sortOrder: "desc"}, http.StatusOK, 3, schedulesSlice(s5, s4, s3), ""},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: -1, sortField: "id", sortOrder: "asc"}, http.StatusOK, 3, schedulesSlice(s3, s4, s5), ""},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 2, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "asc"}, http.StatusOK, 2, schedulesSlice(s3, s4), ""},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 2, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "desc"}, http.StatusOK, 2, schedulesSlice(s4, s3), ""},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 1, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "asc"}, http.StatusOK, 2, schedulesSlice(s3), cursor(1)},
+		{schedules, "scheduler-2", searchQuery{schedulerName: scheduler2.SchedulerName, max: 99, epochFrom: s3.Epoch(), epochTo: s4.Epoch(), sortField: "id", sortOrder: "desc"}, http.StatusOK, 2, schedulesSlice(s4, s3), ""},
 	}
 
 	for _, url := range SchedulesEndpoints {
@@ -588,12 +590,10 @@ func TestRestAPIServer_live_searchSchedules_search_multicriteria(t *testing.T) {
 				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, tt.query.toURLParams(surl), http.NoBody)
 				response := executeRequest(router, req)
 
-				checkResponseJSON(t, tt.expectedCode, response, toJSON(t, struct {
-					Found     int                 `json:"found"`
-					Schedules []schedule.Schedule `json:"schedules"`
-				}{
+				checkResponseJSON(t, tt.expectedCode, response, toJSON(t, searchResult{
 					Found:     tt.expectedFound,
 					Schedules: tt.expectedSchedules,
+					Next:      tt.expectedNext,
 				}))
 			})
 		}
@@ -658,3 +658,106 @@ func TestRestAPIServer_stats(t *testing.T) {
 		}
 	}
 }
+
+// Rule #19: search schedules by target topic, target key and value
+func TestRestAPIServer_searchSchedules_search_by_target_and_value(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	newKafkaSchedule := func(id string, epoch int64, targetTopic, targetKey, value string) schedule.Schedule {
+		return store.Schedule{
+			SchedulerName: "scheduler-1",
+			Schedule:      helper.NewKafkaSchedule("schedules", id, value, epoch, targetTopic, targetKey),
+		}
+	}
+
+	now := time.Now().Unix()
+	s1 := newKafkaSchedule("schedule-1", now+1, "videos", "video-1", `{"title":"Hello World"}`)
+	s2 := newKafkaSchedule("schedule-2", now+2, "videos", "video-2", `{"title":"Goodbye World"}`)
+	s3 := newKafkaSchedule("schedule-3", now+3, "programs", "video-1", `{"title":"Hello"}`)
+
+	schedules := schedulersSchedules(schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(s1, s2, s3),
+	})
+
+	tests := []struct {
+		query             searchQuery
+		expectedFound     int
+		expectedSchedules []schedule.Schedule
+	}{
+		{searchQuery{targetTopic: "videos", sortField: "id", sortOrder: "asc"}, 2, schedulesSlice(s1, s2)},
+		{searchQuery{targetKey: "video-1", sortField: "id", sortOrder: "asc"}, 2, schedulesSlice(s1, s3)},
+		{searchQuery{targetTopic: "videos", targetKey: "video-1"}, 1, schedulesSlice(s1)},
+		{searchQuery{targetTopic: "unknown"}, 0, []schedule.Schedule{}},
+		{searchQuery{value: "hello", sortField: "id", sortOrder: "asc"}, 2, schedulesSlice(s1, s3)},
+		{searchQuery{value: "hello world"}, 1, schedulesSlice(s1)},
+		{searchQuery{value: "world", targetKey: "video-2"}, 1, schedulesSlice(s2)},
+	}
+
+	for _, url := range SchedulesEndpoints {
+		for i, tt := range tests {
+			t.Run(fmt.Sprintf("case #%v (%s)", i+1, url), func(t *testing.T) {
+				createSchedulerSchedules(schedules, stores...)
+
+				surl := fmt.Sprintf(url, "scheduler-1")
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, tt.query.toURLParams(surl), http.NoBody)
+				response := executeRequest(router, req)
+
+				checkResponseJSON(t, http.StatusOK, response, toJSON(t, searchResult{
+					Found:     tt.expectedFound,
+					Schedules: tt.expectedSchedules,
+				}))
+			})
+		}
+	}
+}
+
+// Rule #20: search schedules with a cursor should return the next pages
+func TestRestAPIServer_searchSchedules_cursor(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	schedules5 := newSchedules("scheduler-1", 5)
+	createSchedulerSchedules(schedulersSchedules(schedulerSchedules{"scheduler-1", schedules5}), stores...)
+
+	tests := []struct {
+		query             searchQuery
+		expectedSchedules []schedule.Schedule
+		expectedNext      string
+	}{
+		{searchQuery{max: 2}, schedules5[0:2], cursor(2)},
+		{searchQuery{max: 2, cursor: cursor(2)}, schedules5[2:4], cursor(4)},
+		{searchQuery{max: 2, cursor: cursor(4)}, schedules5[4:5], ""},
+		// cursor after the end
+		{searchQuery{max: 2, cursor: cursor(10)}, []schedule.Schedule{}, ""},
+	}
+
+	for _, url := range SchedulesEndpoints {
+		for i, tt := range tests {
+			t.Run(fmt.Sprintf("case #%v (%s)", i+1, url), func(t *testing.T) {
+				surl := fmt.Sprintf(url, "scheduler-1")
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, tt.query.toURLParams(surl), http.NoBody)
+				response := executeRequest(router, req)
+
+				checkResponseJSON(t, http.StatusOK, response, toJSON(t, searchResult{
+					Found:     len(schedules5),
+					Schedules: tt.expectedSchedules,
+					Next:      tt.expectedNext,
+				}))
+			})
+		}
+
+		t.Run(fmt.Sprintf("invalid cursor (%s)", url), func(t *testing.T) {
+			surl := fmt.Sprintf(url, "scheduler-1")
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, searchQuery{cursor: "$$$"}.toURLParams(surl), http.NoBody)
+			response := executeRequest(router, req)
+
+			checkResponseJSON(t, http.StatusBadRequest, response, `{"error":"invalid cursor"}`)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package restapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// user of the audit entries when the authentication is disabled
+	AnonymousUser = "anonymous"
+)
+
+// record appends an entry to the audit log with the user of the request, does nothing when the audit is disabled
+func record(al *audit.Log, r *http.Request, e audit.Entry, err error) {
+	recorder(al, r)(e, err)
+}
+
+// recorder returns a function recording the entries with the user of the request, the request is not used
+// by the returned function so it can record entries after the response (ie: the schedules of a bulk job)
+func recorder(al *audit.Log, r *http.Request) func(e audit.Entry, err error) {
+	if al == nil {
+		return func(audit.Entry, error) {}
+	}
+
+	user := AnonymousUser
+	if p, ok := auth.FromContext(r.Context()); ok {
+		user = p.Name
+	}
+	remoteAddr := r.RemoteAddr
+
+	return func(e audit.Entry, err error) {
+		e.User = user
+		e.RemoteAddr = remoteAddr
+		if err != nil {
+			e.Error = err.Error()
+		}
+
+		if _, err := al.Record(e); err != nil {
+			log.Errorf("cannot record audit entry %+v: %v", e, err)
+		}
+	}
+}
+
+// parseTime parses a unix time in seconds, zero time when empty
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	i, err := strconv.ParseInt(s, BaseNumber, BitSize)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(i, 0), nil
+}
+
+func searchAudit(al *audit.Log, resv schedulers.Resolver) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		from, err := parseTime(query.Get("from"))
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, "invalid from: "+err.Error())
+			return
+		}
+		to, err := parseTime(query.Get("to"))
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, "invalid to: "+err.Error())
+			return
+		}
+		max := 0
+		if s := query.Get("max"); s != "" {
+			max, err = strconv.Atoi(s)
+			if err != nil {
+				respondWithErrorCode(w, http.StatusBadRequest, "invalid max: "+err.Error())
+				return
+			}
+		}
+
+		filter := audit.Filter{
+			From: from,
+			To:   to,
+			User: query.Get("user"),
+			Max:  max,
+		}
+
+		if name := query.Get("scheduler"); name != "" {
+			if !allowed(r, auth.Admin, name) {
+				respondWithErrorCode(w, http.StatusForbidden, "forbidden")
+				return
+			}
+			filter.Schedulers = []string{name}
+		} else if p, ok := auth.FromContext(r.Context()); ok && !p.Allows(auth.Admin, auth.AllSchedulers) {
+			// only the entries of the schedulers on which the user is admin
+			schs, ok := resolveSchedulers(w, r, resv, auth.Admin)
+			if !ok {
+				return
+			}
+			for _, sch := range schs {
+				filter.Schedulers = append(filter.Schedulers, sch.Name())
+			}
+			if len(filter.Schedulers) == 0 {
+				respondWithJSON(w, http.StatusOK, []audit.Entry{})
+				return
+			}
+		}
+
+		entries, err := al.Search(filter)
+		if err != nil {
+			respondWithError(w, err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, entries)
+	}
+}
+
+func verifyAudit(al *audit.Log) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		type verification struct {
+			Valid   bool   `json:"valid"`
+			Entries int    `json:"entries"`
+			Error   string `json:"error,omitempty"`
+		}
+
+		count, err := al.Verify()
+		if err != nil && !errors.Is(err, audit.ErrTampered) {
+			respondWithError(w, err.Error())
+			return
+		}
+
+		result := verification{
+			Valid:   err == nil,
+			Entries: count,
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		respondWithJSON(w, http.StatusOK, result)
+	}
+}
@@ -0,0 +1,279 @@
+package restapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer/mutable"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+)
+
+// Rule #26: write actions should be recorded in the audit log with the payload before and after
+func TestRestAPIServer_audit(t *testing.T) {
+	al, err := audit.Open(filepath.Join(t.TempDir(), "audit.bbolt"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer al.Close()
+
+	cold := hmap.NewStore()
+	coldDB := simple.DB{Store: cold}
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    coldDB,
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Producer:  mutable.NewProducer(cold),
+		Audit:     al,
+	})
+
+	requests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{http.MethodPost, "/scheduler/scheduler-1/schedule/schedule-1", `{"epoch": 1623456789, "target-topic": "target", "value": "aGVsbG8="}`},
+		{http.MethodPut, "/scheduler/scheduler-1/schedule/schedule-1", `{"epoch": 1623456790, "target-topic": "target", "value": "aGVsbG8="}`},
+		{http.MethodDelete, "/scheduler/scheduler-1/schedule/schedule-1", ""},
+		{http.MethodPost, "/scheduler/scheduler-2/schedule/schedule-2", `{"epoch": 1623456789, "target-topic": "target", "value": "aGVsbG8="}`},
+		// not found, nothing is recorded
+		{http.MethodPut, "/scheduler/scheduler-1/schedule/schedule-3", `{"epoch": 1623456789, "target-topic": "target", "value": "aGVsbG8="}`},
+	}
+	for _, rr := range requests {
+		req, _ := http.NewRequest(rr.method, rr.url, bytes.NewBufferString(rr.body))
+		executeRequest(router, req)
+	}
+
+	tests := []struct {
+		query           string
+		expectedCode    int
+		expectedActions string
+	}{
+		{"", http.StatusOK, "[create update cancel create]"},
+		{"?scheduler=scheduler-1", http.StatusOK, "[create update cancel]"},
+		{"?user=anonymous&max=1", http.StatusOK, "[create]"},
+		{"?user=someone", http.StatusOK, "[]"},
+		{fmt.Sprintf("?from=%v&to=%v", time.Now().Add(-time.Minute).Unix(), time.Now().Add(time.Minute).Unix()), http.StatusOK, "[create update cancel create]"},
+		{fmt.Sprintf("?from=%v", time.Now().Add(time.Minute).Unix()), http.StatusOK, "[]"},
+		{"?from=yesterday", http.StatusBadRequest, ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/audit"+tt.query, http.NoBody)
+			response := executeRequest(router, req)
+			checkResponseCode(t, tt.expectedCode, response.Code)
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+
+			var entries []audit.Entry
+			err := json.Unmarshal(response.Body.Bytes(), &entries)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// entries are returned most recent first
+			actions := []audit.Action{}
+			for i := len(entries) - 1; i >= 0; i-- {
+				actions = append(actions, entries[i].Action)
+			}
+			if fmt.Sprint(actions) != tt.expectedActions {
+				t.Errorf("unexpected actions: %v, expected %v", actions, tt.expectedActions)
+			}
+		})
+	}
+
+	// payloads of the update
+	entries, _ := al.Search(audit.Filter{Schedulers: []string{"scheduler-1"}})
+	update := entries[1]
+	var before, after producer.Schedule
+	if err := json.Unmarshal(update.Before, &before); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := json.Unmarshal(update.After, &after); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.ScheduleID != "schedule-1" || before.Epoch != 1623456789 || after.Epoch != 1623456790 || string(after.Value) != "hello" {
+		t.Errorf("unexpected update entry: %+v before=%+v after=%+v", update, before, after)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/audit/verify", http.NoBody)
+	response := executeRequest(router, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if response.Body.String() != `{"valid":true,"entries":4}` {
+		t.Errorf("unexpected verification: %v", response.Body.String())
+	}
+}
+
+// Rule #37: each schedule processed by a bulk job should be recorded in the audit log with its payload before and after
+func TestRestAPIServer_audit_bulk(t *testing.T) {
+	al, err := audit.Open(filepath.Join(t.TempDir(), "audit.bbolt"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer al.Close()
+
+	cold := hmap.NewStore()
+	coldDB := simple.DB{Store: cold}
+	prod := mutable.NewProducer(cold)
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    coldDB,
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Producer:  prod,
+		Bulk: bulk.NewManager(bulk.Config{
+			ColdDB:   coldDB,
+			Producer: prod,
+		}),
+		Audit: al,
+	})
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	now := time.Now().Unix()
+	createSchedulerSchedules(schedulersSchedules(schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(
+			newSchedule("scheduler-1", "schedule-1", now+10),
+			newSchedule("scheduler-1", "schedule-2", now+20),
+			newSchedule("scheduler-1", "schedule-3", now+30),
+		),
+	}), cold)
+
+	job := startBulkJob(ctx, t, router, "scheduler-1", bulkRequest{
+		Action:  bulk.RescheduleAction,
+		EpochTo: now + 25,
+		Offset:  3600,
+	}, http.StatusAccepted)
+	waitBulkJob(ctx, t, router, job.ID)
+
+	job = startBulkJob(ctx, t, router, "scheduler-1", bulkRequest{
+		Action:     bulk.CancelAction,
+		ScheduleID: "schedule-3",
+	}, http.StatusAccepted)
+	waitBulkJob(ctx, t, router, job.ID)
+
+	entries, err := al.Search(audit.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// entries are returned most recent first
+	summary := []string{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Job == "" {
+			t.Errorf("unexpected entry without job: %+v", e)
+		}
+		if e.ScheduleID == "" {
+			summary = append(summary, string(e.Action))
+			continue
+		}
+
+		// shift of the epoch, none when cancelled
+		var before, after producer.Schedule
+		if err := json.Unmarshal(e.Before, &before); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		shift := "none"
+		if e.After != nil {
+			if err := json.Unmarshal(e.After, &after); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			shift = fmt.Sprint(after.Epoch - before.Epoch)
+		}
+		summary = append(summary, fmt.Sprintf("%v:%v:%v", e.Action, e.ScheduleID, shift))
+	}
+
+	expected := "[bulk-start update:schedule-1:3600 update:schedule-2:3600 bulk-start cancel:schedule-3:none]"
+	if fmt.Sprint(summary) != expected {
+		t.Errorf("unexpected entries: %v, expected %v", summary, expected)
+	}
+}
+
+// Rule #40: the audit entries of the schedulers returned by the resolver should be searched when it returns partial results,
+// with the degraded header
+func TestRestAPIServer_audit_degraded(t *testing.T) {
+	dir := t.TempDir()
+
+	al, err := audit.Open(filepath.Join(dir, "audit.bbolt"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer al.Close()
+
+	for _, name := range []string{"scheduler-1", "scheduler-2"} {
+		if _, err := al.Record(audit.Entry{Action: audit.CreateSchedule, Scheduler: name, ScheduleID: "schedule-1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	bindings, err := auth.LoadRoleBindings(writeFile(t, dir, "roles", `
+admin:admin@scheduler-1
+admin:admin@scheduler-2
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := auth.LoadTokens(writeFile(t, dir, "tokens", `
+admin:admin-token
+`), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// scheduler-2 cannot be resolved
+	resolver := degradedResolver{
+		Slice: slice.NewResolver(),
+		err:   errors.New("resolver: partial results"),
+	}
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    simple.DB{Store: hmap.NewStore()},
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Resolver:  resolver,
+		Audit:     al,
+		Auth:      auth.Chain{tokens},
+	})
+
+	// no results
+	req, _ := http.NewRequest(http.MethodGet, "/audit", http.NoBody)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	response := executeRequest(router, req)
+	checkResponseJSON(t, http.StatusInternalServerError, response, `{"error":"resolver: partial results"}`)
+
+	// partial results
+	createSchedulers(resolver.Slice, schedulersSlice(slice.Scheduler{SchedulerName: "scheduler-1"}))
+
+	req, _ = http.NewRequest(http.MethodGet, "/audit", http.NoBody)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	response = executeRequest(router, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if v := response.Header().Get(restapi.DegradedHeader); v != "true" {
+		t.Errorf("unexpected degraded header: %v", v)
+	}
+
+	var entries []audit.Entry
+	if err := json.Unmarshal(response.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Scheduler != "scheduler-1" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
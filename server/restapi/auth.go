@@ -0,0 +1,73 @@
+package restapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// authenticate verifies the credentials of the request and stores the principal in the request context,
+// the public routes are not authenticated
+func authenticate(a auth.Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := mux.CurrentRoute(r); route != nil && route.GetName() == publicRoute {
+				next.ServeHTTP(w, r)
+				return
+			}
+			p, err := a.Authenticate(r)
+			if err != nil {
+				if !errors.Is(err, auth.ErrNoCredentials) {
+					log.Warnf("authentication failed for %v %v: %v", r.Method, r.URL.Path, err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="kafka-message-scheduler-admin"`)
+				respondWithErrorCode(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+		})
+	}
+}
+
+// authorizer returns a function which wraps a handler with the verification of the role
+// on the scheduler of the route, handlers are not wrapped when the authentication is disabled
+func authorizer(a auth.Authenticator) func(role auth.Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(role auth.Role, h http.HandlerFunc) http.HandlerFunc {
+		if a == nil {
+			return h
+		}
+		return func(w http.ResponseWriter, r *http.Request) {
+			p, ok := auth.FromContext(r.Context())
+			if !ok {
+				respondWithErrorCode(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			if !p.Allows(role, mux.Vars(r)["name"]) {
+				respondWithErrorCode(w, http.StatusForbidden, "forbidden")
+				return
+			}
+			h(w, r)
+		}
+	}
+}
+
+// allowed tells if the principal of the request has the role on the scheduler, always true when the authentication is disabled
+func allowed(r *http.Request, role auth.Role, schedulerName string) bool {
+	p, ok := auth.FromContext(r.Context())
+	return !ok || p.Allows(role, schedulerName)
+}
+
+// filterSchedulers returns the schedulers on which the principal of the request has the role
+func filterSchedulers(r *http.Request, role auth.Role, schs []schedulers.Scheduler) []schedulers.Scheduler {
+	result := []schedulers.Scheduler{}
+	for _, sch := range schs {
+		if allowed(r, role, sch.Name()) {
+			result = append(result, sch)
+		}
+	}
+	return result
+}
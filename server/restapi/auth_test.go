@@ -0,0 +1,180 @@
+package restapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer/mutable"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func newAuthRouter(t *testing.T, allowedOrigins ...string) http.Handler {
+	dir := t.TempDir()
+
+	bindings, err := auth.LoadRoleBindings(writeFile(t, dir, "roles", `
+viewer:viewer@scheduler-1
+operator:operator@scheduler-1,viewer@scheduler-2
+admin:admin
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tokens, err := auth.LoadTokens(writeFile(t, dir, "tokens", `
+viewer:viewer-token
+operator:operator-token
+admin:admin-token
+norole:norole-token
+`), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolver := slice.NewResolver()
+	resolver.Add(slice.Scheduler{SchedulerName: "scheduler-1"}, slice.Scheduler{SchedulerName: "scheduler-2"})
+
+	cold := hmap.NewStore()
+	coldDB := simple.DB{Store: cold}
+	prod := mutable.NewProducer(cold)
+
+	return restapi.NewRouter(restapi.Config{
+		ColdDB:    coldDB,
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Resolver:  resolver,
+		Producer:  prod,
+		Bulk: bulk.NewManager(bulk.Config{
+			ColdDB:   coldDB,
+			Producer: prod,
+		}),
+		Auth:           auth.Chain{tokens},
+		AllowedOrigins: allowedOrigins,
+	})
+}
+
+// Rule #23: routes should require a role on the scheduler of the route
+func TestRestAPIServer_auth(t *testing.T) {
+	router := newAuthRouter(t)
+
+	body := `{"epoch": 1623456789, "target-topic": "target", "value": "aGVsbG8="}`
+
+	tests := []struct {
+		token        string
+		method       string
+		url          string
+		body         string
+		expectedCode int
+	}{
+		{"", http.MethodGet, "/scheduler/scheduler-1/schedules", "", http.StatusUnauthorized},
+		{"unknown-token", http.MethodGet, "/scheduler/scheduler-1/schedules", "", http.StatusUnauthorized},
+		{"norole-token", http.MethodGet, "/scheduler/scheduler-1/schedules", "", http.StatusForbidden},
+		{"viewer-token", http.MethodGet, "/scheduler/scheduler-1/schedules", "", http.StatusOK},
+		{"viewer-token", http.MethodGet, "/scheduler/scheduler-2/schedules", "", http.StatusForbidden},
+		{"viewer-token", http.MethodPost, "/scheduler/scheduler-1/schedule/schedule-1", body, http.StatusForbidden},
+		{"operator-token", http.MethodPost, "/scheduler/scheduler-1/schedule/schedule-1", body, http.StatusCreated},
+		{"operator-token", http.MethodPost, "/scheduler/scheduler-2/schedule/schedule-2", body, http.StatusForbidden},
+		{"operator-token", http.MethodGet, "/scheduler/scheduler-2/schedule/schedule-1", "", http.StatusNotFound},
+		{"operator-token", http.MethodPost, "/scheduler/scheduler-1/schedules/bulk", `{"action": "cancel", "dry-run": true}`, http.StatusForbidden},
+		{"admin-token", http.MethodPost, "/scheduler/scheduler-1/schedules/bulk", `{"action": "cancel", "dry-run": true}`, http.StatusOK},
+		{"norole-token", http.MethodGet, "/schedulers", "", http.StatusForbidden},
+		{"viewer-token", http.MethodGet, "/bulk/jobs", "", http.StatusForbidden},
+		{"admin-token", http.MethodGet, "/bulk/jobs", "", http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			response := executeRequest(router, req)
+			checkResponseCode(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
+// Rule #24: schedulers list should only contain the schedulers on which the user has a role
+func TestRestAPIServer_auth_listSchedulers(t *testing.T) {
+	router := newAuthRouter(t)
+
+	tests := []struct {
+		token    string
+		expected []string
+	}{
+		{"viewer-token", []string{"scheduler-1"}},
+		{"operator-token", []string{"scheduler-1", "scheduler-2"}},
+		{"admin-token", []string{"scheduler-1", "scheduler-2"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			// token in the query parameter (used by the events stream)
+			req, _ := http.NewRequest(http.MethodGet, "/schedulers?access_token="+tt.token, http.NoBody)
+			response := executeRequest(router, req)
+			checkResponseCode(t, http.StatusOK, response.Code)
+
+			var schs []slice.Scheduler
+			err := json.Unmarshal(response.Body.Bytes(), &schs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual := []string{}
+			for _, sch := range schs {
+				actual = append(actual, sch.Name())
+			}
+			if fmt.Sprint(actual) != fmt.Sprint(tt.expected) {
+				t.Errorf("unexpected schedulers: %v, expected %v", actual, tt.expected)
+			}
+		})
+	}
+}
+
+// Rule #25: cross-origin requests should only be allowed for the configured origins
+func TestRestAPIServer_cors(t *testing.T) {
+	tests := []struct {
+		allowedOrigins []string
+		origin         string
+		expected       string
+	}{
+		{nil, "http://localhost:3000", ""},
+		{[]string{"http://localhost:3000"}, "http://localhost:3000", "http://localhost:3000"},
+		{[]string{"http://localhost:3000"}, "http://evil.com", ""},
+		{[]string{"https://*.example.com"}, "https://admin.example.com", "https://admin.example.com"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			router := newAuthRouter(t, tt.allowedOrigins...)
+
+			// preflight request is not authenticated
+			req, _ := http.NewRequest(http.MethodOptions, "/schedulers", http.NoBody)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			req.Header.Set("Access-Control-Request-Headers", "Authorization")
+			response := executeRequest(router, req)
+
+			if actual := response.Header().Get("Access-Control-Allow-Origin"); actual != tt.expected {
+				t.Errorf("unexpected allowed origin: %q, expected %q", actual, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	"github.com/gorilla/mux"
+)
+
+// payload of the bulk route, the filter fields are the same as the search parameters,
+// except the offset of the kafka message which is named message-offset
+type bulkRequest struct {
+	Action           bulk.Action       `json:"action"`
+	ScheduleID       string            `json:"schedule-id"`
+	EpochFrom        int64             `json:"epoch-from"`
+	EpochTo          int64             `json:"epoch-to"`
+	TargetTopic      string            `json:"target-topic,omitempty"`
+	TargetKey        string            `json:"target-key,omitempty"`
+	Value            string            `json:"value,omitempty"`
+	Fields           map[string]string `json:"fields,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	MessagePartition *int32            `json:"partition,omitempty"`
+	MessageOffset    *int64            `json:"message-offset,omitempty"`
+	Offset           int64             `json:"offset"`
+	DryRun           bool              `json:"dry-run"`
+}
+
+// params returns the filter of the request as search parameters, to be parsed as the search filter
+func (req bulkRequest) params() url.Values {
+	values := url.Values{}
+	set := func(param, value string) {
+		if value != "" {
+			values.Set(param, value)
+		}
+	}
+
+	set("schedule-id", req.ScheduleID)
+	if req.EpochFrom != 0 {
+		set("epoch-from", strconv.FormatInt(req.EpochFrom, BaseNumber))
+	}
+	if req.EpochTo != 0 {
+		set("epoch-to", strconv.FormatInt(req.EpochTo, BaseNumber))
+	}
+	set("target-topic", req.TargetTopic)
+	set("target-key", req.TargetKey)
+	set("value", req.Value)
+	for k, v := range req.Fields {
+		set(FieldParamPrefix+k, v)
+	}
+	for k, v := range req.Headers {
+		set(HeaderParamPrefix+k, v)
+	}
+	if req.MessagePartition != nil {
+		set("partition", strconv.FormatInt(int64(*req.MessagePartition), BaseNumber))
+	}
+	if req.MessageOffset != nil {
+		set("offset", strconv.FormatInt(*req.MessageOffset, BaseNumber))
+	}
+
+	return values
+}
+
+func startBulkJob(manager *bulk.Manager, m *mask.Masker, al *audit.Log) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		m, ok := responseMasker(w, r, m)
+		if !ok {
+			return
+		}
+
+		var req bulkRequest
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize)).Decode(&req)
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, fmt.Sprintf("invalid json body: %v", err))
+			return
+		}
+
+		filter, err := searchFilter(vars["name"], req.params())
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if param := maskedFilter(m, vars["name"], filter); param != "" {
+			respondWithErrorCode(w, http.StatusBadRequest, fmt.Sprintf("%v is masked, it requires the %v view", param, UnmaskedParam))
+			return
+		}
+
+		// the schedules are recorded after the start of the job
+		audited := recorder(al, r)
+		started := make(chan struct{})
+		defer close(started)
+
+		job, err := manager.Start(bulk.Request{
+			Action: req.Action,
+			Filter: filter,
+			Offset: req.Offset,
+			DryRun: req.DryRun,
+			Processed: func(jobID string, before producer.Schedule, after *producer.Schedule, err error) {
+				<-started
+				e := audit.Entry{
+					Action:     audit.CancelSchedule,
+					Scheduler:  vars["name"],
+					ScheduleID: before.ID,
+					Job:        jobID,
+					Before:     audit.Payload(before),
+				}
+				if after != nil {
+					e.Action = audit.UpdateSchedule
+					e.After = audit.Payload(after)
+				}
+				audited(e, err)
+			},
+		})
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		code := http.StatusAccepted
+		if job.DryRun {
+			code = http.StatusOK
+		} else {
+			audited(audit.Entry{
+				Action:    audit.StartBulkJob,
+				Scheduler: job.Scheduler,
+				Job:       job.ID,
+				After:     audit.Payload(req),
+			}, nil)
+		}
+
+		respondWithJSON(w, code, job)
+	}
+}
+
+func listBulkJobs(manager *bulk.Manager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := []bulk.Job{}
+		for _, job := range manager.List() {
+			if allowed(r, auth.Admin, job.Scheduler) {
+				result = append(result, job)
+			}
+		}
+		respondWithJSON(w, http.StatusOK, result)
+	}
+}
+
+// getAllowedBulkJob returns the job, the jobs of the schedulers on which the principal is not admin are not found
+func getAllowedBulkJob(manager *bulk.Manager, r *http.Request) (bulk.Job, error) {
+	job, err := manager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		return job, err
+	}
+	if !allowed(r, auth.Admin, job.Scheduler) {
+		return bulk.Job{}, bulk.ErrJobNotFound
+	}
+	return job, nil
+}
+
+func getBulkJob(manager *bulk.Manager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := getAllowedBulkJob(manager, r)
+		respondWithBulkJob(w, job, err)
+	}
+}
+
+func abortBulkJob(manager *bulk.Manager, al *audit.Log) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := getAllowedBulkJob(manager, r)
+		if err == nil {
+			before := job
+			job, err = manager.Abort(job.ID)
+			record(al, r, audit.Entry{
+				Action:    audit.AbortBulkJob,
+				Scheduler: before.Scheduler,
+				Job:       before.ID,
+				Before:    audit.Payload(before),
+				After:     audit.Payload(job),
+			}, err)
+		}
+		respondWithBulkJob(w, job, err)
+	}
+}
+
+func respondWithBulkJob(w http.ResponseWriter, job bulk.Job, err error) {
+	if errors.Is(err, bulk.ErrJobNotFound) {
+		respondWithJSON(w, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,268 @@
+package restapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
+	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+)
+
+const (
+	BulkEndpoint    = "/scheduler/%s/schedules/bulk"
+	BulkJobEndpoint = "/bulk/job/%s"
+)
+
+type bulkRequest struct {
+	Action           bulk.Action       `json:"action"`
+	ScheduleID       string            `json:"schedule-id,omitempty"`
+	EpochFrom        int64             `json:"epoch-from,omitempty"`
+	EpochTo          int64             `json:"epoch-to,omitempty"`
+	TargetKey        string            `json:"target-key,omitempty"`
+	Value            string            `json:"value,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	MessagePartition *int32            `json:"partition,omitempty"`
+	Offset           int64             `json:"offset,omitempty"`
+	DryRun           bool              `json:"dry-run,omitempty"`
+}
+
+func startBulkJob(ctx context.Context, t *testing.T, router http.Handler, schedulerName string, br bulkRequest, expectedCode int) bulk.Job {
+	url := fmt.Sprintf(BulkEndpoint, schedulerName)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(toJSON(t, br)))
+	response := executeRequest(router, req)
+	checkResponseCode(t, expectedCode, response.Code)
+
+	var job bulk.Job
+	err := json.Unmarshal(response.Body.Bytes(), &job)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return job
+}
+
+// waits for the job to be finished
+func waitBulkJob(ctx context.Context, t *testing.T, router http.Handler, id string) bulk.Job {
+	url := fmt.Sprintf(BulkJobEndpoint, id)
+	for {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+		response := executeRequest(router, req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var job bulk.Job
+		err := json.Unmarshal(response.Body.Bytes(), &job)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if job.Status != bulk.PendingStatus && job.Status != bulk.RunningStatus {
+			return job
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timeout waiting for job %v: %+v", id, job)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// Rule #16: bulk dry run should return the affected schedules without modifying them
+func TestRestAPIServer_bulk_dry_run(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	now := time.Now().Unix()
+	scheduler1 := schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(
+			newSchedule("scheduler-1", "schedule-1", now+10),
+			newSchedule("scheduler-1", "schedule-2", now+20),
+			newSchedule("scheduler-1", "schedule-3", now+30),
+		),
+	}
+	createSchedulerSchedules(schedulersSchedules(scheduler1), stores...)
+
+	job := startBulkJob(ctx, t, router, "scheduler-1", bulkRequest{
+		Action:    bulk.RescheduleAction,
+		EpochFrom: now + 15,
+		Offset:    60,
+		DryRun:    true,
+	}, http.StatusOK)
+
+	if job.Status != bulk.DoneStatus || job.Total != 2 || job.Processed != 0 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	expected := map[string]int64{"schedule-2": now + 80, "schedule-3": now + 90}
+	if len(job.Targets) != len(expected) {
+		t.Fatalf("unexpected targets: %+v", job.Targets)
+	}
+	for _, target := range job.Targets {
+		if expected[target.ID] != target.NewEpoch {
+			t.Errorf("unexpected target: %+v", target)
+		}
+	}
+
+	// nothing should have changed
+	versions, err := stores[0].Get("scheduler-1", "schedule-2")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(versions) != 1 {
+		t.Errorf("unexpected versions count: %v", len(versions))
+	}
+
+	// invalid requests
+	startBulkJob(ctx, t, router, "scheduler-1", bulkRequest{Action: "unknown"}, http.StatusBadRequest)
+	startBulkJob(ctx, t, router, "scheduler-1", bulkRequest{Action: bulk.RescheduleAction}, http.StatusBadRequest)
+}
+
+// Rule #17: bulk cancel should cancel all the schedules matching the filter
+func TestRestAPIServer_bulk_cancel(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	now := time.Now().Unix()
+	scheduler1 := schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(
+			newSchedule("scheduler-1", "schedule-1", now+10),
+			newSchedule("scheduler-1", "schedule-2", now+20),
+			newSchedule("scheduler-1", "schedule-3", now+30),
+		),
+	}
+	createSchedulerSchedules(schedulersSchedules(scheduler1), stores...)
+
+	job := startBulkJob(ctx, t, router, "scheduler-1", bulkRequest{
+		Action:  bulk.CancelAction,
+		EpochTo: now + 25,
+	}, http.StatusAccepted)
+
+	job = waitBulkJob(ctx, t, router, job.ID)
+	if job.Status != bulk.DoneStatus || job.Total != 2 || job.Processed != 2 || job.Failed != 0 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	list, err := stores[0].List("scheduler-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := []string{}
+	for sch := range list {
+		ids = append(ids, sch.ID())
+	}
+	if len(ids) != 1 || ids[0] != "schedule-3" {
+		t.Errorf("unexpected remaining schedules: %v", ids)
+	}
+
+	// the job should be listed
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/bulk/jobs", http.NoBody)
+	response := executeRequest(router, req)
+	checkResponseJSON(t, http.StatusOK, response, toJSON(t, []bulk.Job{job}))
+
+	// unknown job
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(BulkJobEndpoint, "unknown"), http.NoBody)
+	response = executeRequest(router, req)
+	checkResponseJSON(t, http.StatusNotFound, response, "")
+}
+
+// Rule #18: bulk reschedule should shift the epoch of all the schedules matching the filter
+func TestRestAPIServer_bulk_reschedule(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	now := time.Now().Unix()
+	scheduler1 := schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(
+			helper.NewKafkaSchedule("schedules", "schedule-1", "value-1", now+10, "target-topic", "target-key-1"),
+			helper.NewKafkaSchedule("schedules", "schedule-2", "value-2", now+20, "target-topic", "target-key-2"),
+		),
+	}
+	createSchedulerSchedules(schedulersSchedules(scheduler1), stores...)
+
+	job := startBulkJob(ctx, t, router, "scheduler-1", bulkRequest{
+		Action: bulk.RescheduleAction,
+		Offset: 3600,
+	}, http.StatusAccepted)
+
+	job = waitBulkJob(ctx, t, router, job.ID)
+	if job.Status != bulk.DoneStatus || job.Total != 2 || job.Processed != 2 || job.Failed != 0 {
+		t.Errorf("unexpected job: %+v", job)
+	}
+
+	versions, err := stores[0].Get("scheduler-1", "schedule-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("unexpected versions count: %v", len(versions))
+	}
+	// the content of the schedule should be kept
+	sch, err := producer.FromSchedule(versions[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sch.Epoch != now+3620 || sch.TargetKey != "target-key-2" || string(sch.Value) != "value-2" {
+		t.Errorf("unexpected new version: %v value=%s", sch, sch.Value)
+	}
+}
+
+// Rule #38: bulk should filter the schedules with the same filters as the search
+func TestRestAPIServer_bulk_filters(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	now := time.Now().Unix()
+	scheduler1 := schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(
+			helper.NewKafkaSchedule("schedules", "schedule-1", "value-1", now+10, "target-topic", "target-key-1"),
+			helper.NewKafkaSchedule("schedules", "schedule-2", "value-2", now+20, "target-topic", "target-key-2"),
+			helper.NewKafkaSchedule("schedules", "schedule-3", "value-3", now+30, "target-topic", "target-key-2"),
+		),
+	}
+	createSchedulerSchedules(schedulersSchedules(scheduler1), stores...)
+
+	partition := int32(-1)
+
+	tests := []struct {
+		request       bulkRequest
+		expectedCode  int
+		expectedTotal int
+	}{
+		{bulkRequest{TargetKey: "target-key-2"}, http.StatusOK, 2},
+		{bulkRequest{TargetKey: "target-key-2", EpochTo: now + 25}, http.StatusOK, 1},
+		{bulkRequest{Headers: map[string]string{kafka.TargetKey: "target-key-1"}}, http.StatusOK, 1},
+		{bulkRequest{TargetKey: "unknown"}, http.StatusOK, 0},
+		// invalid filter
+		{bulkRequest{MessagePartition: &partition}, http.StatusBadRequest, 0},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			tt.request.Action = bulk.CancelAction
+			tt.request.DryRun = true
+
+			job := startBulkJob(ctx, t, router, "scheduler-1", tt.request, tt.expectedCode)
+			if job.Total != tt.expectedTotal {
+				t.Errorf("unexpected total: %v", job.Total)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/broadcast"
+	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// interval of the comments sent to keep the connection alive
+	KeepAliveInterval = 15 * time.Second
+)
+
+type streamEvent struct {
+	Type      string            `json:"type"`
+	Scheduler string            `json:"scheduler"`
+	Schedule  schedule.Schedule `json:"schedule,omitempty"`
+}
+
+type eventFilter struct {
+	schedulerName string
+	scheduleID    string
+	types         map[string]bool
+}
+
+func newEventFilter(r *http.Request) eventFilter {
+	filter := eventFilter{
+		schedulerName: mux.Vars(r)["name"],
+		scheduleID:    r.URL.Query().Get("schedule-id"),
+		types:         map[string]bool{},
+	}
+
+	// comma separated list of event types: upsert, delete, reset
+	for _, t := range strings.Split(r.URL.Query().Get("type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.types[t] = true
+		}
+	}
+
+	return filter
+}
+
+func (f eventFilter) matches(evt store.Event) bool {
+	if len(f.types) > 0 && !f.types[evt.EventType.String()] {
+		return false
+	}
+
+	// a reset without scheduler name concerns all the schedulers
+	if evt.EventType == store.StoreResetType {
+		return evt.SchedulerName == "" || evt.SchedulerName == f.schedulerName
+	}
+
+	if evt.SchedulerName != f.schedulerName || evt.Schedule.Schedule == nil {
+		return false
+	}
+
+	return f.scheduleID == "" || strings.Contains(evt.ID(), f.scheduleID)
+}
+
+// streamEvents sends the events of the scheduler as server-sent events
+func streamEvents(b *broadcast.Broadcaster, m *mask.Masker) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			respondWithError(w, "streaming is not supported")
+			return
+		}
+
+		m, ok := responseMasker(w, r, m)
+		if !ok {
+			return
+		}
+
+		// the write timeout of the server applies to the other routes
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Debugf("cannot clear the write deadline of the events stream: %v", err)
+		}
+
+		filter := newEventFilter(r)
+
+		events, unsubscribe := b.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(KeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				_, err := fmt.Fprint(w, ": keep-alive\n\n")
+				if err != nil {
+					log.Errorf("cannot write keep alive: %v", err)
+					return
+				}
+				flusher.Flush()
+			case evt, ok := <-events:
+				if !ok {
+					return
+				}
+				if !filter.matches(evt) {
+					continue
+				}
+
+				sch := evt.Schedule.Schedule
+				// the schedule may already be wrapped with its scheduler name
+				for s, ok := sch.(store.Schedule); ok; s, ok = sch.(store.Schedule) {
+					sch = s.Schedule
+				}
+
+				data, err := json.Marshal(streamEvent{
+					Type:      evt.EventType.String(),
+					Scheduler: evt.SchedulerName,
+					Schedule:  m.Mask(evt.SchedulerName, sch),
+				})
+				if err != nil {
+					log.Errorf("cannot marshal event %+v: %v", evt, err)
+					continue
+				}
+
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.EventType, data)
+				if err != nil {
+					log.Errorf("cannot write event: %v", err)
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package restapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamEvent struct {
+	Type      string `json:"type"`
+	Scheduler string `json:"scheduler"`
+	Schedule  struct {
+		ID string `json:"id"`
+	} `json:"schedule"`
+}
+
+// reads the server-sent events of the stream until count events are received
+func readEvents(t *testing.T, resp *http.Response, count int) []streamEvent {
+	result := []streamEvent{}
+
+	scanner := bufio.NewScanner(resp.Body)
+	eventType := ""
+	for len(result) < count && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var evt streamEvent
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if evt.Type != eventType {
+				t.Errorf("unexpected event type: %v, expected %v", evt.Type, eventType)
+			}
+			result = append(result, evt)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return result
+}
+
+// Rule #22: events stream should send the changes of the scheduler's schedules matching the filters
+func TestRestAPIServer_streamEvents(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	tests := []struct {
+		query          string
+		expectedEvents []string
+	}{
+		{"", []string{"upsert:schedule-1", "upsert:video-1", "delete:schedule-1"}},
+		{"?schedule-id=video", []string{"upsert:video-1"}},
+		{"?type=delete", []string{"delete:schedule-1"}},
+		{"?type=upsert,delete&schedule-id=schedule", []string{"upsert:schedule-1", "delete:schedule-1"}},
+	}
+
+	responses := make([]*http.Response, len(tests))
+	for i, tt := range tests {
+		url := srv.URL + fmt.Sprintf("/scheduler/%s/events", "scheduler-1") + tt.query
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		checkResponseCode(t, http.StatusOK, resp.StatusCode)
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("unexpected content type: %v", ct)
+		}
+		responses[i] = resp
+	}
+
+	// the subscriptions are registered before the headers are sent
+	cold := stores[0]
+	cold.Add("scheduler-2", newSchedule("scheduler-2", "schedule-2", time.Now().Unix()))
+	cold.Add("scheduler-1", newSchedule("scheduler-1", "schedule-1", time.Now().Unix()))
+	cold.Add("scheduler-1", newSchedule("scheduler-1", "video-1", time.Now().Unix()))
+	cold.Delete("scheduler-1", newSchedule("scheduler-1", "schedule-1", time.Now().Unix()))
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			events := readEvents(t, responses[i], len(tt.expectedEvents))
+			actual := []string{}
+			for _, evt := range events {
+				if evt.Scheduler != "scheduler-1" {
+					t.Errorf("unexpected scheduler: %v", evt.Scheduler)
+				}
+				actual = append(actual, evt.Type+":"+evt.Schedule.ID)
+			}
+			if strings.Join(actual, ",") != strings.Join(tt.expectedEvents, ",") {
+				t.Errorf("unexpected events: %v, expected %v", actual, tt.expectedEvents)
+			}
+		})
+	}
+}
+
+// Rule #36: events stream should not be closed by the write timeout of the server
+func TestRestAPIServer_streamEvents_write_timeout(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/scheduler/scheduler-1/events", http.NoBody)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	checkResponseCode(t, http.StatusOK, resp.StatusCode)
+
+	// after the write timeout
+	time.Sleep(300 * time.Millisecond)
+	stores[0].Add("scheduler-1", newSchedule("scheduler-1", "schedule-1", time.Now().Unix()))
+
+	if events := readEvents(t, resp, 1); len(events) != 1 || events[0].Schedule.ID != "schedule-1" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
@@ -0,0 +1,89 @@
+package restapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+)
+
+// degradedResolver returns its schedulers with an error, like a resolver which cannot reach some instances
+type degradedResolver struct {
+	*slice.Slice
+	err    error
+	health map[string][]schedulers.InstanceHealth
+}
+
+func (r degradedResolver) List() ([]schedulers.Scheduler, error) {
+	schs, _ := r.Slice.List()
+	return schs, r.err
+}
+
+func (r degradedResolver) Health(name string) ([]schedulers.InstanceHealth, bool) {
+	instances, found := r.health[name]
+	return instances, found
+}
+
+// Rule #27: schedulers should be returned with the degraded header when the resolver returns partial results,
+// and the health of the instances should be exposed by scheduler
+func TestRestAPIServer_listSchedulers_degraded(t *testing.T) {
+	resolver := degradedResolver{
+		Slice: slice.NewResolver(),
+		err:   errors.New("resolver: partial results"),
+		health: map[string][]schedulers.InstanceHealth{
+			"scheduler-1": {
+				{Address: "10.0.0.1:8000", Status: schedulers.StatusUp, ConsecutiveFailures: 0, ResponseTime: 3},
+				{Address: "10.0.0.2:8000", Status: schedulers.StatusDown, LastError: "connection refused", ConsecutiveFailures: 4},
+			},
+		},
+	}
+
+	router, _, _ := newRouter(resolver)
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	// no results
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/schedulers", http.NoBody)
+	response := executeRequest(router, req)
+	checkResponseJSON(t, http.StatusInternalServerError, response, `{"error":"resolver: partial results"}`)
+
+	// partial results
+	createSchedulers(resolver.Slice, schedulersSlice(slice.Scheduler{SchedulerName: "scheduler-1"}))
+
+	for _, path := range []string{"/schedulers", "/stats"} {
+		req, _ = http.NewRequestWithContext(ctx, http.MethodGet, path, http.NoBody)
+		response = executeRequest(router, req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+		if v := response.Header().Get(restapi.DegradedHeader); v != "true" {
+			t.Errorf("%v: unexpected degraded header: %v", path, v)
+		}
+	}
+
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/schedulers/scheduler-1/instances", http.NoBody)
+	response = executeRequest(router, req)
+	checkResponseJSON(t, http.StatusOK, response, `[
+		{"address":"10.0.0.1:8000","status":"up","consecutive_failures":0,"response_time_ms":3},
+		{"address":"10.0.0.2:8000","status":"down","last_error":"connection refused","consecutive_failures":4,"response_time_ms":0}
+	]`)
+
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/schedulers/unknown/instances", http.NoBody)
+	response = executeRequest(router, req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	// complete results
+	resolver.err = nil
+	router, _, _ = newRouter(resolver)
+
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "/schedulers", http.NoBody)
+	response = executeRequest(router, req)
+	checkResponseJSON(t, http.StatusOK, response, `[{"name":"scheduler-1"}]`)
+	if v := response.Header().Get(restapi.DegradedHeader); v != "" {
+		t.Errorf("unexpected degraded header: %v", v)
+	}
+}
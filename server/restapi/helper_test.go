@@ -1,6 +1,7 @@
 package restapi_test
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,15 +9,19 @@ import (
 	"net/url"
 	"reflect"
 	stdsort "sort"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer/mutable"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
 	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
 	"github.com/etf1/kafka-message-scheduler-admin/server/sort"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/broadcast"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
 
 	"github.com/etf1/kafka-message-scheduler/schedule"
@@ -41,6 +46,7 @@ var (
 type searchResult struct {
 	Found     int                 `json:"found"`
 	Schedules []schedule.Schedule `json:"schedules"`
+	Next      string              `json:"next,omitempty"`
 }
 
 type schedulerSchedules struct {
@@ -78,6 +84,10 @@ type searchQuery struct {
 	epochTo       int64
 	sortField     string
 	sortOrder     string
+	targetTopic   string
+	targetKey     string
+	value         string
+	cursor        string
 }
 
 func (s searchQuery) toURLParams(base string) string {
@@ -110,6 +120,18 @@ func (s searchQuery) toURLParams(base string) string {
 	if s.max != 0 {
 		v.Set("max", fmt.Sprint(s.max))
 	}
+	if s.targetTopic != "" {
+		v.Set("target-topic", s.targetTopic)
+	}
+	if s.targetKey != "" {
+		v.Set("target-key", s.targetKey)
+	}
+	if s.value != "" {
+		v.Set("value", s.value)
+	}
+	if s.cursor != "" {
+		v.Set("cursor", s.cursor)
+	}
 	res := base
 	if encoded := v.Encode(); encoded != "" {
 		res += "?" + encoded
@@ -118,6 +140,11 @@ func (s searchQuery) toURLParams(base string) string {
 	return res
 }
 
+// same encoding as the cursor returned by the search routes
+func cursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
 func createSchedulerSchedules(schs []schedulerSchedules, stores ...*hmap.Hmap) {
 	for _, st := range stores {
 		st.Clear()
@@ -183,13 +210,27 @@ func newRouter(resolvers ...schedulers.Resolver) (http.Handler, []*hmap.Hmap, in
 		resolver = resolvers[0]
 	}
 
-	router := restapi.NewRouter(simple.DB{
+	coldDB := simple.DB{
 		Store: cold,
-	}, simple.DB{
-		Store: live,
-	}, simple.DB{
-		Store: history,
-	}, resolver)
+	}
+	prod := mutable.NewProducer(cold)
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB: coldDB,
+		LiveDB: simple.DB{
+			Store: live,
+		},
+		HistoryDB: simple.DB{
+			Store: history,
+		},
+		Resolver: resolver,
+		Producer: prod,
+		Bulk: bulk.NewManager(bulk.Config{
+			ColdDB:   coldDB,
+			Producer: prod,
+		}),
+		Events: broadcast.NewBroadcaster(cold),
+	})
 
 	return router, []*hmap.Hmap{cold, live, history}, simple.DefaultMax
 }
@@ -0,0 +1,68 @@
+package restapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+	"github.com/gorilla/mux"
+)
+
+// UnmaskedParam is the parameter requesting the schedules without masking, it requires the admin role on the scheduler
+const UnmaskedParam = "unmasked"
+
+// responseMasker returns the masker of the schedules of the response, nil when the unmasked view is requested,
+// it responds with an error when the principal of the request is not allowed to see the unmasked schedules
+func responseMasker(w http.ResponseWriter, r *http.Request, m *mask.Masker) (*mask.Masker, bool) {
+	unmasked, _ := strconv.ParseBool(r.URL.Query().Get(UnmaskedParam))
+	if !unmasked {
+		return m, true
+	}
+
+	if !allowed(r, auth.Admin, mux.Vars(r)["name"]) {
+		respondWithErrorCode(w, http.StatusForbidden, "forbidden")
+		return nil, false
+	}
+
+	return nil, true
+}
+
+// maskedFilter returns the parameter of the first filter on a masked value, empty when there is none,
+// the masked values would be matched by their raw value and disclosed by the search
+func maskedFilter(m *mask.Masker, schedulerName string, f db.Filter) string {
+	switch {
+	case f.Value != "" && m.MasksValue(schedulerName):
+		return "value"
+	case f.TargetTopic != "" && m.MasksHeader(schedulerName, kafka.TargetTopic):
+		return "target-topic"
+	case f.TargetKey != "" && m.MasksHeader(schedulerName, kafka.TargetKey):
+		return "target-key"
+	case len(f.Fields) != 0 && m.MasksValue(schedulerName):
+		// the fields are extracted from the value, they may contain its masked parts
+		for k := range f.Fields {
+			return FieldParamPrefix + k
+		}
+	}
+	for k := range f.Headers {
+		if m.MasksHeader(schedulerName, k) {
+			return HeaderParamPrefix + k
+		}
+	}
+	return ""
+}
+
+func maskSchedules(m *mask.Masker, schedulerName string, schs []store.Schedule) []store.Schedule {
+	if m == nil {
+		return schs
+	}
+
+	result := make([]store.Schedule, len(schs))
+	for i, s := range schs {
+		result[i] = m.Mask(schedulerName, s).(store.Schedule)
+	}
+	return result
+}
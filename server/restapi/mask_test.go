@@ -0,0 +1,219 @@
+package restapi_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer/mutable"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
+)
+
+// Rule #30: schedules should be returned masked, the unmasked view should require the admin role on the scheduler
+func TestRestAPIServer_mask(t *testing.T) {
+	dir := t.TempDir()
+
+	bindings, err := auth.LoadRoleBindings(writeFile(t, dir, "roles", `
+viewer:viewer@scheduler-1
+admin:admin@scheduler-1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := auth.LoadTokens(writeFile(t, dir, "tokens", `
+viewer:viewer-token
+admin:admin-token
+`), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	masker, err := mask.New(mask.Rule{Paths: []string{"email"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cold := hmap.NewStore()
+	cold.Add("scheduler-1", rest.Schedule{
+		ScheduleID:    "schedule-1",
+		ScheduleEpoch: 1000,
+		MessageValue:  []byte(`{"email":"john@example.com"}`),
+	})
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    simple.DB{Store: cold},
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Auth:      auth.Chain{tokens},
+		Masker:    masker,
+	})
+
+	masked := `{"id":"schedule-1","epoch":1000,"timestamp":0,"target-topic":"","target-key":"","topic":"","value":"eyJlbWFpbCI6IioqKioifQ=="}`
+	unmasked := `{"id":"schedule-1","epoch":1000,"timestamp":0,"target-topic":"","target-key":"","topic":"","value":"eyJlbWFpbCI6ImpvaG5AZXhhbXBsZS5jb20ifQ=="}`
+
+	tests := []struct {
+		token            string
+		url              string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"viewer-token", "/scheduler/scheduler-1/schedule/schedule-1", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + masked + `}]`},
+		{"viewer-token", "/scheduler/scheduler-1/schedules", http.StatusOK, `{"found":1,"schedules":[{"scheduler":"scheduler-1","schedule":` + masked + `}]}`},
+		{"viewer-token", "/scheduler/scheduler-1/schedule/schedule-1/versions", http.StatusOK, `[{"schedule":` + masked + `,"timestamp":0,"changes":[]}]`},
+		{"viewer-token", "/scheduler/scheduler-1/schedule/schedule-1?unmasked=true", http.StatusForbidden, `{"error":"forbidden"}`},
+		{"viewer-token", "/scheduler/scheduler-1/schedules?unmasked=true", http.StatusForbidden, `{"error":"forbidden"}`},
+		// admin
+		{"admin-token", "/scheduler/scheduler-1/schedule/schedule-1", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + masked + `}]`},
+		{"admin-token", "/scheduler/scheduler-1/schedule/schedule-1?unmasked=true", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + unmasked + `}]`},
+		{"admin-token", "/scheduler/scheduler-1/schedules?unmasked=true", http.StatusOK, `{"found":1,"schedules":[{"scheduler":"scheduler-1","schedule":` + unmasked + `}]}`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			response := executeRequest(router, req)
+			checkResponseJSON(t, tt.expectedCode, response, tt.expectedResponse)
+		})
+	}
+}
+
+// Rule #35: search should reject the filters on the masked values, except for the unmasked view
+func TestRestAPIServer_mask_filters(t *testing.T) {
+	dir := t.TempDir()
+
+	bindings, err := auth.LoadRoleBindings(writeFile(t, dir, "roles", `
+viewer:viewer@scheduler-1
+viewer:viewer@scheduler-2
+admin:admin@scheduler-1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := auth.LoadTokens(writeFile(t, dir, "tokens", `
+viewer:viewer-token
+admin:admin-token
+`), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	masker, err := mask.New(
+		mask.Rule{Schedulers: []string{"scheduler-1"}, Paths: []string{"email"}, Headers: []string{kafka.TargetKey, "customer"}},
+		mask.Rule{Schedulers: []string{"scheduler-2"}, Patterns: []string{`[0-9]{16}`}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    simple.DB{Store: hmap.NewStore()},
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Auth:      auth.Chain{tokens},
+		Masker:    masker,
+	})
+
+	tests := []struct {
+		token        string
+		url          string
+		expectedCode int
+	}{
+		{"viewer-token", "/scheduler/scheduler-1/schedules?value=john", http.StatusBadRequest},
+		{"viewer-token", "/scheduler/scheduler-1/schedules?target-key=key-1", http.StatusBadRequest},
+		{"viewer-token", "/scheduler/scheduler-1/schedules?header.customer=john", http.StatusBadRequest},
+		{"viewer-token", "/live/scheduler/scheduler-1/schedules?value=john", http.StatusBadRequest},
+		{"viewer-token", "/history/scheduler/scheduler-1/schedules?target-key=key-1", http.StatusBadRequest},
+		// the fields are extracted from the masked values
+		{"viewer-token", "/scheduler/scheduler-1/schedules?field.country=fr", http.StatusBadRequest},
+		{"viewer-token", "/scheduler/scheduler-2/schedules?field.card=1234", http.StatusBadRequest},
+		// not masked
+		{"viewer-token", "/scheduler/scheduler-1/schedules?target-topic=topic-1", http.StatusOK},
+		{"viewer-token", "/scheduler/scheduler-1/schedules?header.country=fr", http.StatusOK},
+		// the patterns mask the values, not the headers
+		{"viewer-token", "/scheduler/scheduler-2/schedules?target-key=key-1", http.StatusOK},
+		// admin
+		{"admin-token", "/scheduler/scheduler-1/schedules?value=john", http.StatusBadRequest},
+		{"admin-token", "/scheduler/scheduler-1/schedules?value=john&unmasked=true", http.StatusOK},
+		{"admin-token", "/scheduler/scheduler-1/schedules?target-key=key-1&header.customer=john&unmasked=true", http.StatusOK},
+		{"admin-token", "/scheduler/scheduler-1/schedules?field.country=fr", http.StatusBadRequest},
+		{"admin-token", "/scheduler/scheduler-1/schedules?field.country=fr&unmasked=true", http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			response := executeRequest(router, req)
+			checkResponseCode(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
+// Rule #39: bulk should reject the filters on the masked values, except for the unmasked view
+func TestRestAPIServer_mask_bulk_filters(t *testing.T) {
+	dir := t.TempDir()
+
+	bindings, err := auth.LoadRoleBindings(writeFile(t, dir, "roles", `
+admin:admin@scheduler-1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := auth.LoadTokens(writeFile(t, dir, "tokens", `
+admin:admin-token
+`), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	masker, err := mask.New(mask.Rule{Paths: []string{"email"}, Headers: []string{"customer"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cold := hmap.NewStore()
+	coldDB := simple.DB{Store: cold}
+	prod := mutable.NewProducer(cold)
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    coldDB,
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Producer:  prod,
+		Bulk:      bulk.NewManager(bulk.Config{ColdDB: coldDB, Producer: prod}),
+		Auth:      auth.Chain{tokens},
+		Masker:    masker,
+	})
+
+	tests := []struct {
+		url          string
+		body         string
+		expectedCode int
+	}{
+		{"/scheduler/scheduler-1/schedules/bulk", `{"action":"cancel","dry-run":true,"value":"john"}`, http.StatusBadRequest},
+		{"/scheduler/scheduler-1/schedules/bulk", `{"action":"cancel","dry-run":true,"headers":{"customer":"john"}}`, http.StatusBadRequest},
+		{"/scheduler/scheduler-1/schedules/bulk", `{"action":"cancel","dry-run":true,"fields":{"email":"john"}}`, http.StatusBadRequest},
+		// not masked
+		{"/scheduler/scheduler-1/schedules/bulk", `{"action":"cancel","dry-run":true,"headers":{"country":"fr"}}`, http.StatusOK},
+		// unmasked view
+		{"/scheduler/scheduler-1/schedules/bulk?unmasked=true", `{"action":"cancel","dry-run":true,"value":"john"}`, http.StatusOK},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+"admin-token")
+			response := executeRequest(router, req)
+			checkResponseCode(t, tt.expectedCode, response.Code)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package restapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+)
+
+// Rule #31: search should filter the schedules by the partition, the offset and the headers of the kafka message
+func TestRestAPIServer_searchSchedules_metadata(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	newMessageSchedule := func(id string, partition int32, offset int64, headers ...bbolt.Header) bbolt.Schedule {
+		return bbolt.Schedule{
+			ScheduleID:    id,
+			ScheduleEpoch: 1000,
+			Partition:     &partition,
+			Offset:        &offset,
+			Headers:       headers,
+			TimestampType: "create-time",
+		}
+	}
+	for _, st := range stores {
+		st.Clear()
+		st.Add("scheduler-1",
+			newMessageSchedule("schedule-1", 0, 10, bbolt.Header{Key: "trace-id", Value: "a"}),
+			newMessageSchedule("schedule-2", 1, 10, bbolt.Header{Key: "trace-id", Value: "b"}),
+			newMessageSchedule("schedule-3", 1, 11, bbolt.Header{Key: "trace-id", Value: "c d"}),
+		)
+	}
+
+	tests := []struct {
+		query        string
+		expectedCode int
+		expectedIDs  []string
+	}{
+		{"partition=1", http.StatusOK, []string{"schedule-2", "schedule-3"}},
+		{"partition=1&offset=10", http.StatusOK, []string{"schedule-2"}},
+		{"offset=10", http.StatusOK, []string{"schedule-1", "schedule-2"}},
+		{"header.trace-id=a", http.StatusOK, []string{"schedule-1"}},
+		{"header.trace-id=c", http.StatusOK, nil},
+		// the whole value is matched, not its words
+		{"header.trace-id=c%20d", http.StatusOK, []string{"schedule-3"}},
+		{"header.trace-id=c%20b", http.StatusOK, nil},
+		{"partition=-1", http.StatusBadRequest, nil},
+		{"offset=abc", http.StatusBadRequest, nil},
+	}
+
+	for i, tt := range tests {
+		for _, endpoint := range SchedulesEndpoints {
+			t.Run(fmt.Sprintf("case #%v %v", i+1, endpoint), func(t *testing.T) {
+				url := fmt.Sprintf(endpoint, "scheduler-1") + "?sort-by=id%20asc&" + tt.query
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+				response := executeRequest(router, req)
+				checkResponseCode(t, tt.expectedCode, response.Code)
+				if tt.expectedCode != http.StatusOK {
+					return
+				}
+
+				var result struct {
+					Schedules []struct {
+						Schedule bbolt.Schedule `json:"schedule"`
+					} `json:"schedules"`
+				}
+				if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				ids := make([]string, 0, len(result.Schedules))
+				for _, s := range result.Schedules {
+					ids = append(ids, s.Schedule.ScheduleID)
+					// the metadata of the message is returned
+					if s.Schedule.Partition == nil || s.Schedule.Offset == nil || s.Schedule.TimestampType != "create-time" {
+						t.Errorf("unexpected schedule: %+v", s.Schedule)
+					}
+				}
+				if fmt.Sprint(ids) != fmt.Sprint(tt.expectedIDs) {
+					t.Errorf("unexpected schedules: %v", ids)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package restapi
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+)
+
+// scheduleOutcome is a version of a schedule with the outcome of the schedule (status badge)
+type scheduleOutcome struct {
+	store.Schedule
+	outcome.Outcome
+}
+
+// statusParams returns the statuses of the status parameters, repeated or comma separated
+func statusParams(values url.Values) ([]outcome.Status, error) {
+	var result []outcome.Status
+	for _, v := range values[StatusParam] {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			status, err := outcome.ParseStatus(name)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, status)
+		}
+	}
+	return result, nil
+}
+
+// getScheduleWithOutcome responds with the versions of the schedule and its outcome,
+// the tombstones are returned when the schedule has been cancelled
+func getScheduleWithOutcome(w http.ResponseWriter, d db.DB, m *mask.Masker, tr *outcome.Tracker, schedulerName, scheduleID string) {
+	schs, o, err := tr.Get(d, schedulerName, scheduleID)
+	if err != nil {
+		respondWithError(w, err.Error())
+		return
+	}
+
+	if len(schs) == 0 {
+		respondWithJSON(w, http.StatusNotFound, nil)
+		return
+	}
+
+	result := make([]scheduleOutcome, len(schs))
+	for i, s := range maskSchedules(m, schedulerName, schs) {
+		result[i] = scheduleOutcome{Schedule: s, Outcome: o}
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,99 @@
+package restapi_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+)
+
+// Rule #32: the cold schedules should have a status computed from the history, filterable in the search
+func TestRestAPIServer_outcome(t *testing.T) {
+	cold := hmap.NewStore()
+	history := hmap.NewStore()
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     cold,
+		History:        history,
+		CancelledStore: hmap.NewStore(),
+		LateThreshold:  time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	future := time.Now().Add(time.Hour).Unix()
+
+	cold.Add("scheduler-1",
+		rest.Schedule{ScheduleID: "schedule-1", ScheduleEpoch: 1000},
+		rest.Schedule{ScheduleID: "schedule-2", ScheduleEpoch: 1000},
+		rest.Schedule{ScheduleID: "schedule-3", ScheduleEpoch: 1000},
+		rest.Schedule{ScheduleID: "schedule-4", ScheduleEpoch: future},
+	)
+	history.Add("scheduler-1",
+		rest.Schedule{ScheduleID: "schedule-1", ScheduleEpoch: 1000, ScheduleTimestamp: 1002},
+		rest.Schedule{ScheduleID: "schedule-2", ScheduleEpoch: 1000, ScheduleTimestamp: 1300},
+	)
+	cold.Delete("scheduler-1", rest.Schedule{ScheduleID: "schedule-4", ScheduleTimestamp: 2000})
+
+	// the cancellation is recorded asynchronously
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tombstones, _, err := tracker.Get(simple.DB{Store: cold}, "scheduler-1", "schedule-4")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(tombstones) != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cancellation not recorded after 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    simple.DB{Store: cold},
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: history},
+		Outcomes:  tracker,
+	})
+
+	schedule := func(id string, epoch, timestamp int64) string {
+		return fmt.Sprintf(`{"id":%q,"epoch":%v,"timestamp":%v,"target-topic":"","target-key":"","topic":"","value":null}`, id, epoch, timestamp)
+	}
+
+	tests := []struct {
+		url              string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{"/scheduler/scheduler-1/schedule/schedule-1", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-1", 1000, 0) + `,"status":"triggered","triggered-at-ms":1002000,"delay-ms":2000}]`},
+		{"/scheduler/scheduler-1/schedule/schedule-2", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-2", 1000, 0) + `,"status":"triggered-late","triggered-at-ms":1300000,"delay-ms":300000}]`},
+		{"/scheduler/scheduler-1/schedule/schedule-3", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-3", 1000, 0) + `,"status":"overdue"}]`},
+		{"/scheduler/scheduler-1/schedule/schedule-4", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-4", 0, 2000) + `,"status":"cancelled"}]`},
+		{"/scheduler/scheduler-1/schedule/schedule-5", http.StatusNotFound, ""},
+		{"/scheduler/scheduler-1/schedules?status=overdue", http.StatusOK, `{"found":1,"schedules":[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-3", 1000, 0) + `}]}`},
+		{"/scheduler/scheduler-1/schedules?status=triggered,triggered-late&sort-by=id+asc", http.StatusOK, `{"found":2,"schedules":[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-1", 1000, 0) + `},{"scheduler":"scheduler-1","schedule":` + schedule("schedule-2", 1000, 0) + `}]}`},
+		{"/scheduler/scheduler-1/schedules?status=cancelled", http.StatusOK, `{"found":1,"schedules":[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-4", 0, 2000) + `}]}`},
+		{"/scheduler/scheduler-1/schedules?status=pending", http.StatusOK, `{"found":0,"schedules":[]}`},
+		{"/scheduler/scheduler-1/schedules?status=unknown", http.StatusBadRequest, `{"error":"invalid status: unknown"}`},
+		// no status for the live and history schedules
+		{"/history/scheduler/scheduler-1/schedules?status=triggered", http.StatusBadRequest, `{"error":"status filter not supported"}`},
+		{"/history/scheduler/scheduler-1/schedule/schedule-1", http.StatusOK, `[{"scheduler":"scheduler-1","schedule":` + schedule("schedule-1", 1000, 1002) + `}]`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			response := executeRequest(router, req)
+			checkResponseJSON(t, tt.expectedCode, response, tt.expectedResponse)
+		})
+	}
+}
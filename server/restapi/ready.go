@@ -0,0 +1,56 @@
+package restapi
+
+import (
+	"net/http"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+)
+
+// Loader reports the loading progress of the schedulers of a DB consuming their topics
+type Loader interface {
+	Ready() bool
+	Progress(schedulerName string) (kafka.Progress, bool)
+}
+
+type readiness struct {
+	Ready bool `json:"ready"`
+	// by DB name
+	DBs map[string]bool `json:"dbs"`
+}
+
+// ready responds with the readiness of the DBs, with the service unavailable status until all of them are loaded
+func ready(loaders map[string]Loader) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := readiness{
+			Ready: true,
+			DBs:   make(map[string]bool, len(loaders)),
+		}
+		for name, l := range loaders {
+			result.DBs[name] = l.Ready()
+			result.Ready = result.Ready && result.DBs[name]
+		}
+
+		code := http.StatusOK
+		if !result.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		respondWithJSON(w, code, result)
+	}
+}
+
+// loadingProgress returns the loading progress of the scheduler by DB name and true when all the DBs are loaded,
+// nil when no DB reports its progress
+func loadingProgress(loaders map[string]Loader, schedulerName string) (map[string]kafka.Progress, *bool) {
+	if len(loaders) == 0 {
+		return nil, nil
+	}
+
+	result := make(map[string]kafka.Progress, len(loaders))
+	ready := true
+	for name, l := range loaders {
+		p, found := l.Progress(schedulerName)
+		result[name] = p
+		ready = ready && found && p.Ready
+	}
+	return result, &ready
+}
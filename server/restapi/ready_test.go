@@ -0,0 +1,117 @@
+package restapi_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+)
+
+// loader is a stub reporting the progress of the schedulers
+type loader struct {
+	mutex    sync.Mutex
+	progress map[string]kafka.Progress
+}
+
+func (l *loader) Ready() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, p := range l.progress {
+		if !p.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *loader) Progress(schedulerName string) (kafka.Progress, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	p, found := l.progress[schedulerName]
+	return p, found
+}
+
+func (l *loader) set(schedulerName string, p kafka.Progress) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.progress[schedulerName] = p
+}
+
+// Rule #34: the readiness should be exposed without authentication and flip once the DBs are loaded,
+// the loading progress of the schedulers should be returned by the stats
+func TestRestAPIServer_ready(t *testing.T) {
+	dir := t.TempDir()
+
+	bindings, err := auth.LoadRoleBindings(writeFile(t, dir, "roles", `
+viewer:viewer
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens, err := auth.LoadTokens(writeFile(t, dir, "tokens", `
+viewer:viewer-token
+`), bindings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resolver := slice.NewResolver()
+	resolver.Add(slice.Scheduler{SchedulerName: "scheduler-1"})
+
+	cold := &loader{progress: map[string]kafka.Progress{
+		"scheduler-1": {Ready: false, Consumed: 50, Total: 100},
+	}}
+	history := &loader{progress: map[string]kafka.Progress{
+		"scheduler-1": {Ready: true, Consumed: 10, Total: 10},
+	}}
+
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:    simple.DB{Store: hmap.NewStore()},
+		LiveDB:    simple.DB{Store: hmap.NewStore()},
+		HistoryDB: simple.DB{Store: hmap.NewStore()},
+		Resolver:  resolver,
+		Auth:      auth.Chain{tokens},
+		Loaders: map[string]restapi.Loader{
+			"cold":    cold,
+			"history": history,
+		},
+	})
+
+	tests := []struct {
+		before           func()
+		url              string
+		token            string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{func() {}, "/ready", "", http.StatusServiceUnavailable, `{"ready":false,"dbs":{"cold":false,"history":true}}`},
+		{func() {}, "/stats", "viewer-token", http.StatusOK, `[{"scheduler":"scheduler-1","total_live":0,"total_history":0,"total":0,"ready":false,"loading":{"cold":{"ready":false,"consumed":50,"total":100},"history":{"ready":true,"consumed":10,"total":10}}}]`},
+		{func() {}, "/stats", "", http.StatusUnauthorized, `{"error":"unauthorized"}`},
+		{func() {
+			cold.set("scheduler-1", kafka.Progress{Ready: true, Consumed: 100, Total: 100})
+		}, "/ready", "", http.StatusOK, `{"ready":true,"dbs":{"cold":true,"history":true}}`},
+		{func() {}, "/stats", "viewer-token", http.StatusOK, `[{"scheduler":"scheduler-1","total_live":0,"total_history":0,"total":0,"ready":true,"loading":{"cold":{"ready":true,"consumed":100,"total":100},"history":{"ready":true,"consumed":10,"total":10}}}]`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			tt.before()
+			req, _ := http.NewRequest(http.MethodGet, tt.url, http.NoBody)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			response := executeRequest(router, req)
+			checkResponseJSON(t, tt.expectedCode, response, tt.expectedResponse)
+		})
+	}
+}
@@ -1,15 +1,29 @@
 package restapi
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/etf1/kafka-message-scheduler-admin/server/alert"
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/luadecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
 	"github.com/etf1/kafka-message-scheduler-admin/server/sort"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/broadcast"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	log "github.com/sirupsen/logrus"
@@ -18,24 +32,126 @@ import (
 const (
 	BaseNumber = 10
 	BitSize    = 64
+	// DegradedHeader is set on the responses built from partial results of the resolver
+	DegradedHeader = "X-Degraded"
+	// FieldParamPrefix is the prefix of the query parameters filtering the fields extracted by the decoders
+	FieldParamPrefix = "field."
+	// HeaderParamPrefix is the prefix of the query parameters filtering the headers of the kafka messages
+	HeaderParamPrefix = "header."
+	// StatusParam is the query parameter filtering the outcome of the schedules, repeated or comma separated
+	StatusParam = "status"
+	// publicRoute is the name of the routes exposed without authentication
+	publicRoute = "public"
 )
 
-func NewRouter(coldDB, liveDB, historyDB db.DB, resv schedulers.Resolver) http.Handler {
-	return cors.AllowAll().Handler(initRouter(coldDB, liveDB, historyDB, resv))
+var (
+	errInvalidCursor    = errors.New("invalid cursor")
+	errInvalidPartition = errors.New("invalid partition")
+	errInvalidOffset    = errors.New("invalid offset")
+	errStatusFilter     = errors.New("status filter not supported")
+)
+
+// Config contains the dependencies of the router
+// ColdDB represents schedules stored in a persistent database
+// LiveDB represents schedules live in the schedulers' instances
+// HistoryDB represents schedules already triggered by the schedulers
+// Producer is optional, when not set the write routes are not exposed
+// Bulk is optional, when not set the bulk routes are not exposed
+// Events is optional, when not set the events stream is not exposed
+// Auth is optional, when not set the routes are not protected
+// AllowedOrigins is the CORS allow-list, when empty the cross-origin requests are not allowed
+// Audit is optional, when not set the actions are not audited and the audit routes are not exposed
+// Scripts is optional, when not set the script test route is not exposed
+// Masker is optional, when not set the schedules are returned unmasked
+// Outcomes is optional, when not set the cold schedules have no status and cannot be filtered by status
+// Alerts is optional, when not set the alerts routes are not exposed
+// Loaders are optional, by DB name, when not set the DBs are always ready
+type Config struct {
+	ColdDB         db.DB
+	LiveDB         db.DB
+	HistoryDB      db.DB
+	Resolver       schedulers.Resolver
+	Producer       producer.Producer
+	Bulk           *bulk.Manager
+	Events         *broadcast.Broadcaster
+	Auth           auth.Authenticator
+	AllowedOrigins []string
+	Audit          *audit.Log
+	Scripts        *luadecoder.Sandbox
+	Masker         *mask.Masker
+	Outcomes       *outcome.Tracker
+	Alerts         *alert.Checker
+	Loaders        map[string]Loader
+}
+
+func NewRouter(cfg Config) http.Handler {
+	router := initRouter(cfg)
+
+	if len(cfg.AllowedOrigins) == 0 {
+		return router
+	}
+
+	return cors.New(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{DegradedHeader},
+		AllowCredentials: true,
+	}).Handler(router)
 }
 
-// coldDB represents schedules stored in a persistent database
-// liveDB represents schedules live in the schedulers' instances
-func initRouter(coldDB, liveDB, historyDB db.DB, resv schedulers.Resolver) *mux.Router {
+func initRouter(cfg Config) *mux.Router {
+	coldDB, liveDB, historyDB, resv, m, tr := cfg.ColdDB, cfg.LiveDB, cfg.HistoryDB, cfg.Resolver, cfg.Masker, cfg.Outcomes
+
 	router := mux.NewRouter()
-	router.HandleFunc("/stats", stats(liveDB, coldDB, historyDB, resv)).Methods(http.MethodGet)
-	router.HandleFunc("/schedulers", listSchedulers(resv)).Methods(http.MethodGet)
-	router.HandleFunc("/scheduler/{name}/schedules", searchSchedules(coldDB)).Methods(http.MethodGet)
-	router.HandleFunc("/scheduler/{name}/schedule/{id}", getSchedule(coldDB)).Methods(http.MethodGet)
-	router.HandleFunc("/live/scheduler/{name}/schedules", searchSchedules(liveDB)).Methods(http.MethodGet)
-	router.HandleFunc("/live/scheduler/{name}/schedule/{id}", getSchedule(liveDB)).Methods(http.MethodGet)
-	router.HandleFunc("/history/scheduler/{name}/schedules", searchSchedules(historyDB)).Methods(http.MethodGet)
-	router.HandleFunc("/history/scheduler/{name}/schedule/{id}", getSchedule(historyDB)).Methods(http.MethodGet)
+	if cfg.Auth != nil {
+		router.Use(authenticate(cfg.Auth))
+	}
+	// every route requires a role on the scheduler of the route (or on any scheduler for the global routes)
+	requires := authorizer(cfg.Auth)
+
+	router.HandleFunc("/ready", ready(cfg.Loaders)).Methods(http.MethodGet).Name(publicRoute)
+	router.HandleFunc("/stats", requires(auth.Viewer, stats(liveDB, coldDB, historyDB, resv, cfg.Loaders))).Methods(http.MethodGet)
+	router.HandleFunc("/schedulers", requires(auth.Viewer, listSchedulers(resv))).Methods(http.MethodGet)
+	router.HandleFunc("/schedulers/{name}/instances", requires(auth.Viewer, listInstances(resv))).Methods(http.MethodGet)
+	router.HandleFunc("/scheduler/{name}/schedules", requires(auth.Viewer, searchSchedules(coldDB, m, tr))).Methods(http.MethodGet)
+	router.HandleFunc("/scheduler/{name}/schedule/{id}", requires(auth.Viewer, getSchedule(coldDB, m, tr))).Methods(http.MethodGet)
+	router.HandleFunc("/scheduler/{name}/schedule/{id}/versions", requires(auth.Viewer, getScheduleVersions(coldDB, m))).Methods(http.MethodGet)
+	router.HandleFunc("/live/scheduler/{name}/schedules", requires(auth.Viewer, searchSchedules(liveDB, m, nil))).Methods(http.MethodGet)
+	router.HandleFunc("/live/scheduler/{name}/schedule/{id}", requires(auth.Viewer, getSchedule(liveDB, m, nil))).Methods(http.MethodGet)
+	router.HandleFunc("/history/scheduler/{name}/schedules", requires(auth.Viewer, searchSchedules(historyDB, m, nil))).Methods(http.MethodGet)
+	router.HandleFunc("/history/scheduler/{name}/schedule/{id}", requires(auth.Viewer, getSchedule(historyDB, m, nil))).Methods(http.MethodGet)
+
+	if cfg.Producer != nil {
+		router.HandleFunc("/scheduler/{name}/schedule/{id}", requires(auth.Operator, createSchedule(coldDB, cfg.Producer, cfg.Audit))).Methods(http.MethodPost)
+		router.HandleFunc("/scheduler/{name}/schedule/{id}", requires(auth.Operator, updateSchedule(coldDB, cfg.Producer, cfg.Audit))).Methods(http.MethodPut)
+		router.HandleFunc("/scheduler/{name}/schedule/{id}", requires(auth.Operator, cancelSchedule(coldDB, cfg.Producer, cfg.Audit))).Methods(http.MethodDelete)
+	}
+
+	if cfg.Events != nil {
+		router.HandleFunc("/scheduler/{name}/events", requires(auth.Viewer, streamEvents(cfg.Events, m))).Methods(http.MethodGet)
+	}
+
+	if cfg.Bulk != nil {
+		router.HandleFunc("/scheduler/{name}/schedules/bulk", requires(auth.Admin, startBulkJob(cfg.Bulk, m, cfg.Audit))).Methods(http.MethodPost)
+		router.HandleFunc("/bulk/jobs", requires(auth.Admin, listBulkJobs(cfg.Bulk))).Methods(http.MethodGet)
+		router.HandleFunc("/bulk/job/{id}", requires(auth.Admin, getBulkJob(cfg.Bulk))).Methods(http.MethodGet)
+		router.HandleFunc("/bulk/job/{id}", requires(auth.Admin, abortBulkJob(cfg.Bulk, cfg.Audit))).Methods(http.MethodDelete)
+	}
+
+	if cfg.Scripts != nil {
+		router.HandleFunc("/scheduler/{name}/script/test", requires(auth.Admin, testScript(coldDB, cfg.Scripts))).Methods(http.MethodPost)
+	}
+
+	if cfg.Alerts != nil {
+		router.HandleFunc("/alerts/overdue", requires(auth.Viewer, overdueAlerts(cfg.Alerts))).Methods(http.MethodGet)
+	}
+
+	if cfg.Audit != nil {
+		router.HandleFunc("/audit", requires(auth.Admin, searchAudit(cfg.Audit, resv))).Methods(http.MethodGet)
+		router.HandleFunc("/audit/verify", requires(auth.Admin, verifyAudit(cfg.Audit))).Methods(http.MethodGet)
+	}
+
 	return router
 }
 
@@ -52,35 +168,58 @@ func initRouter(coldDB, liveDB, historyDB db.DB, resv schedulers.Resolver) *mux.
 // 	Schedule      ResponseSchedule `json:"schedule"`
 // }
 
-func searchSchedules(d db.DB) func(w http.ResponseWriter, r *http.Request) {
+func searchSchedules(d db.DB, m *mask.Masker, tr *outcome.Tracker) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		globalStart := time.Now()
 
 		vars := mux.Vars(r)
 
 		schedulerName := vars["name"]
-		scheduleID := r.URL.Query().Get("schedule-id")
-		epochFrom := r.URL.Query().Get("epoch-from")
-		epochTo := r.URL.Query().Get("epoch-to")
+		m, ok := responseMasker(w, r, m)
+		if !ok {
+			return
+		}
+
 		sortBy := r.URL.Query().Get("sort-by")
 		max := max(r.URL.Query().Get("max"))
 
+		offset, err := decodeCursor(r.URL.Query().Get("cursor"))
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filter, err := searchFilter(schedulerName, r.URL.Query())
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		statuses, err := statusParams(r.URL.Query())
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(statuses) != 0 && tr == nil {
+			respondWithErrorCode(w, http.StatusBadRequest, errStatusFilter.Error())
+			return
+		}
+
 		query := db.SearchQuery{
 			Limit: db.Limit{
-				Max: max,
-			},
-			Filter: db.Filter{
-				SchedulerName: schedulerName,
-				ScheduleID:    scheduleID,
-				EpochRange: db.EpochRange{
-					From: epoch(epochFrom),
-					To:   epoch(epochTo),
-				},
+				Max:    max,
+				Offset: offset,
 			},
+			Filter: filter,
 			SortBy: sort.ToSortBy(sortBy),
 		}
 
-		found, list, err := d.Search(query)
+		if param := maskedFilter(m, schedulerName, query.Filter); param != "" {
+			respondWithErrorCode(w, http.StatusBadRequest, fmt.Sprintf("%v is masked, it requires the %v view", param, UnmaskedParam))
+			return
+		}
+
+		found, list, err := tr.Search(d, query, statuses...)
 		if err != nil {
 			respondWithError(w, err.Error())
 			return
@@ -96,6 +235,7 @@ func searchSchedules(d db.DB) func(w http.ResponseWriter, r *http.Request) {
 
 		encoder := json.NewEncoder(w)
 		first := true
+		count := 0
 
 		for s := range list {
 			start := time.Now()
@@ -108,15 +248,29 @@ func searchSchedules(d db.DB) func(w http.ResponseWriter, r *http.Request) {
 			if first {
 				first = false
 			}
-			err = encoder.Encode(s)
+			err = encoder.Encode(m.Mask(schedulerName, s))
 			if err != nil {
 				log.Errorf("unable to encode json: %v", err)
 				return
 			}
+			count++
 			log.Warnf("searchSchedules.encode done elapsed=%v", time.Since(start))
 		}
 
-		_, err = w.Write([]byte("]}"))
+		_, err = w.Write([]byte("]"))
+		if err != nil {
+			log.Errorf("cannot write response end of list: %v", err)
+		}
+
+		// cursor of the next page, only when there are remaining schedules
+		if count > 0 && offset+count < found {
+			_, err = fmt.Fprintf(w, ", %q: %q", "next", encodeCursor(offset+count))
+			if err != nil {
+				log.Errorf("cannot write response next cursor: %v", err)
+			}
+		}
+
+		_, err = w.Write([]byte("}"))
 		if err != nil {
 			log.Errorf("cannot write response end: %v", err)
 		}
@@ -125,11 +279,10 @@ func searchSchedules(d db.DB) func(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func stats(liveDB, coldDB, historyDB db.DB, resv schedulers.Resolver) func(w http.ResponseWriter, r *http.Request) {
+func stats(liveDB, coldDB, historyDB db.DB, resv schedulers.Resolver, loaders map[string]Loader) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		schs, err := resv.List()
-		if err != nil {
-			respondWithError(w, err.Error())
+		schs, ok := resolveSchedulers(w, r, resv, auth.Viewer)
+		if !ok {
 			return
 		}
 
@@ -138,30 +291,33 @@ func stats(liveDB, coldDB, historyDB db.DB, resv schedulers.Resolver) func(w htt
 			TotalLive     int    `json:"total_live"`
 			History       int    `json:"total_history"`
 			Total         int    `json:"total"`
+			// loading progress by DB, the totals are partial until ready
+			Ready   *bool                     `json:"ready,omitempty"`
+			Loading map[string]kafka.Progress `json:"loading,omitempty"`
 		}
 
 		result := []stat{}
 		for _, sch := range schs {
-			q := db.SearchQuery{Filter: db.Filter{
-				SchedulerName: sch.Name(),
-			}}
-			totalLive, _, err := liveDB.Search(q)
+			totalLive, err := db.Count(liveDB, sch.Name())
 			if err != nil {
 				log.Errorf("stats on live DB failed: %v", err)
 			}
-			totalHistory, _, err := historyDB.Search(q)
+			totalHistory, err := db.Count(historyDB, sch.Name())
 			if err != nil {
 				log.Errorf("stats on history DB failed: %v", err)
 			}
-			total, _, err := coldDB.Search(q)
+			total, err := db.Count(coldDB, sch.Name())
 			if err != nil {
-				log.Errorf("stats on live DB failed: %v", err)
+				log.Errorf("stats on cold DB failed: %v", err)
 			}
+			loading, ready := loadingProgress(loaders, sch.Name())
 			result = append(result, stat{
 				SchedulerName: sch.Name(),
 				TotalLive:     totalLive,
 				History:       totalHistory,
 				Total:         total,
+				Ready:         ready,
+				Loading:       loading,
 			})
 		}
 
@@ -171,18 +327,60 @@ func stats(liveDB, coldDB, historyDB db.DB, resv schedulers.Resolver) func(w htt
 
 func listSchedulers(resv schedulers.Resolver) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		schs, err := resv.List()
-		if err != nil {
-			respondWithError(w, err.Error())
+		schs, ok := resolveSchedulers(w, r, resv, auth.Viewer)
+		if !ok {
 			return
 		}
 		respondWithJSON(w, http.StatusOK, schs)
 	}
 }
 
-func getSchedule(d db.DB) func(w http.ResponseWriter, r *http.Request) {
+// resolveSchedulers returns the schedulers on which the principal of the request has the role, when the resolver
+// returns partial results the degraded header is set, it responds with an error when there are no results
+func resolveSchedulers(w http.ResponseWriter, r *http.Request, resv schedulers.Resolver, role auth.Role) ([]schedulers.Scheduler, bool) {
+	schs, err := resv.List()
+	if err != nil {
+		if len(schs) == 0 {
+			respondWithError(w, err.Error())
+			return nil, false
+		}
+		log.Warnf("resolver returned partial results: %v", err)
+		w.Header().Set(DegradedHeader, "true")
+	}
+	return filterSchedulers(r, role, schs), true
+}
+
+func listInstances(resv schedulers.Resolver) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+
+		var instances []schedulers.InstanceHealth
+		found := false
+		if h, ok := resv.(schedulers.HealthReporter); ok {
+			instances, found = h.Health(name)
+		}
+		if !found {
+			respondWithJSON(w, http.StatusNotFound, nil)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, instances)
+	}
+}
+
+func getSchedule(d db.DB, m *mask.Masker, tr *outcome.Tracker) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
+		m, ok := responseMasker(w, r, m)
+		if !ok {
+			return
+		}
+
+		if tr != nil {
+			getScheduleWithOutcome(w, d, m, tr, vars["name"], vars["id"])
+			return
+		}
+
 		sch, err := d.Get(vars["name"], vars["id"])
 		if err != nil {
 			respondWithError(w, err.Error())
@@ -194,10 +392,58 @@ func getSchedule(d db.DB) func(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		respondWithJSON(w, http.StatusOK, sch)
+		respondWithJSON(w, http.StatusOK, maskSchedules(m, vars["name"], sch))
+	}
+}
+
+func getScheduleVersions(d db.DB, m *mask.Masker) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		m, ok := responseMasker(w, r, m)
+		if !ok {
+			return
+		}
+
+		schs, err := d.Get(vars["name"], vars["id"])
+		if err != nil {
+			respondWithError(w, err.Error())
+			return
+		}
+
+		if len(schs) == 0 {
+			respondWithJSON(w, http.StatusNotFound, nil)
+			return
+		}
+
+		// the changes between the versions are computed on the masked schedules
+		respondWithJSON(w, http.StatusOK, db.Versions(maskSchedules(m, vars["name"], schs)))
 	}
 }
 
+// searchFilter returns the filter of the search parameters of the scheduler
+func searchFilter(schedulerName string, values url.Values) (db.Filter, error) {
+	partition, messageOffset, err := messagePosition(values)
+	if err != nil {
+		return db.Filter{}, err
+	}
+
+	return db.Filter{
+		SchedulerName: schedulerName,
+		ScheduleID:    values.Get("schedule-id"),
+		EpochRange: db.EpochRange{
+			From: epoch(values.Get("epoch-from")),
+			To:   epoch(values.Get("epoch-to")),
+		},
+		TargetTopic:      values.Get("target-topic"),
+		TargetKey:        values.Get("target-key"),
+		Value:            values.Get("value"),
+		Fields:           prefixedParams(values, FieldParamPrefix),
+		MessagePartition: partition,
+		MessageOffset:    messageOffset,
+		Headers:          prefixedParams(values, HeaderParamPrefix),
+	}, nil
+}
+
 func epoch(s string) int64 {
 	if s != "" {
 		n, err := strconv.ParseInt(s, BaseNumber, BitSize)
@@ -209,6 +455,47 @@ func epoch(s string) int64 {
 	return 0
 }
 
+// prefixedParams returns the values of the query parameters <prefix><name>, by name
+func prefixedParams(values url.Values, prefix string) map[string]string {
+	var result map[string]string
+	for param := range values {
+		name := strings.TrimPrefix(param, prefix)
+		if name == param || name == "" {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[name] = values.Get(param)
+	}
+	return result
+}
+
+// messagePosition returns the partition and the offset of the kafka message of the query parameters, nil when not set
+func messagePosition(values url.Values) (*int32, *int64, error) {
+	var partition *int32
+	var offset *int64
+
+	if s := values.Get("partition"); s != "" {
+		n, err := strconv.ParseInt(s, BaseNumber, 32)
+		if err != nil || n < 0 {
+			return nil, nil, errInvalidPartition
+		}
+		p := int32(n)
+		partition = &p
+	}
+
+	if s := values.Get("offset"); s != "" {
+		n, err := strconv.ParseInt(s, BaseNumber, BitSize)
+		if err != nil || n < 0 {
+			return nil, nil, errInvalidOffset
+		}
+		offset = &n
+	}
+
+	return partition, offset, nil
+}
+
 func max(s string) int {
 	if s != "" {
 		n, err := strconv.Atoi(s)
@@ -220,8 +507,35 @@ func max(s string) int {
 	return 0
 }
 
+// the cursor is an opaque representation of the offset of the next page
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, errInvalidCursor
+	}
+
+	return offset, nil
+}
+
 func respondWithError(w http.ResponseWriter, message string) {
-	respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": message})
+	respondWithErrorCode(w, http.StatusInternalServerError, message)
+}
+
+func respondWithErrorCode(w http.ResponseWriter, code int, message string) {
+	respondWithJSON(w, code, map[string]string{"error": message})
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -0,0 +1,131 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/luadecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errInvalidScript     = errors.New("script is mandatory")
+	errInvalidScriptTest = errors.New("schedule-id or value is mandatory")
+)
+
+// payload of the script test route: the script is run with the stored schedule,
+// or with the value (base64 encoded) when there is no schedule id
+type scriptTestRequest struct {
+	Script     string `json:"script"`
+	ScheduleID string `json:"schedule-id"`
+	Value      []byte `json:"value"`
+}
+
+type scriptTestResponse struct {
+	Message  luadecoder.Message `json:"message"`
+	Result   *luadecoder.Result `json:"result,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Duration int64              `json:"duration_ms"`
+}
+
+func (s scriptTestRequest) validate() error {
+	if strings.TrimSpace(s.Script) == "" {
+		return errInvalidScript
+	}
+	if s.ScheduleID == "" && len(s.Value) == 0 {
+		return errInvalidScriptTest
+	}
+	return nil
+}
+
+// testScript runs a script against a stored schedule of the scheduler, nothing is stored,
+// the errors of the script are returned in the response with a 200 status
+func testScript(coldDB db.DB, sandbox *luadecoder.Sandbox) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schedulerName := mux.Vars(r)["name"]
+
+		var req scriptTestRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize)).Decode(&req); err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, fmt.Sprintf("invalid json body: %v", err))
+			return
+		}
+		if err := req.validate(); err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		msg := luadecoder.Message{
+			ID:        req.ScheduleID,
+			Scheduler: schedulerName,
+			Value:     req.Value,
+		}
+
+		if req.ScheduleID != "" {
+			schs, err := coldDB.Get(schedulerName, req.ScheduleID)
+			if err != nil {
+				respondWithError(w, err.Error())
+				return
+			}
+			if len(schs) == 0 {
+				respondWithJSON(w, http.StatusNotFound, nil)
+				return
+			}
+			msg = scriptMessage(schedulerName, latest(schs))
+			if len(req.Value) != 0 {
+				msg.Value = req.Value
+			}
+		}
+
+		script, err := sandbox.Compile("test", req.Script)
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		start := time.Now()
+		result, err := script.Run(msg)
+		resp := scriptTestResponse{
+			Message:  msg,
+			Duration: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Result = &result
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+// scriptMessage returns the message of a stored schedule, with the fields of the schedule
+func scriptMessage(schedulerName string, s store.Schedule) luadecoder.Message {
+	fields := db.GetFields(s)
+
+	return luadecoder.Message{
+		ID:          s.ID(),
+		Epoch:       s.Epoch(),
+		Scheduler:   schedulerName,
+		Topic:       fields.Topic,
+		TargetTopic: fields.TargetTopic,
+		TargetKey:   fields.TargetKey,
+		Value:       fields.RawValue,
+	}
+}
+
+// latest returns the most recent version of a schedule
+func latest(schs []store.Schedule) store.Schedule {
+	result := schs[0]
+	for _, s := range schs[1:] {
+		if s.Timestamp() >= result.Timestamp() {
+			result = s
+		}
+	}
+	return result
+}
@@ -0,0 +1,174 @@
+package restapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/luadecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+)
+
+const (
+	ScriptTestEndpoint = "/scheduler/%s/script/test"
+)
+
+type scriptTestRequest struct {
+	Script     string `json:"script"`
+	ScheduleID string `json:"schedule-id,omitempty"`
+	Value      []byte `json:"value,omitempty"`
+}
+
+// Rule #28: script test should run the script against a stored schedule, or a value, without storing anything
+func TestRestAPIServer_testScript(t *testing.T) {
+	cold := hmap.NewStore()
+	router := restapi.NewRouter(restapi.Config{
+		ColdDB:  simple.DB{Store: cold},
+		Scripts: &luadecoder.Sandbox{Timeout: 50 * time.Millisecond},
+	})
+
+	cold.Add("scheduler-1", rest.Schedule{
+		ScheduleID:         "schedule-1",
+		ScheduleEpoch:      1000,
+		MessageTopic:       "schedules",
+		MessageTargetTopic: "target-topic",
+		MessageTargetKey:   "target-key",
+		MessageValue:       []byte(`{"country":"fr"}`),
+	})
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	script := `function decode(msg)
+		local v = json.decode(msg.value)
+		return string.upper(msg.value), {country = v.country, topic = msg.target_topic}
+	end`
+	message := `{"id":"schedule-1","epoch":1000,"scheduler":"scheduler-1","topic":"schedules","target_topic":"target-topic","target_key":"target-key","value":"eyJjb3VudHJ5IjoiZnIifQ=="}`
+
+	tests := []struct {
+		body             scriptTestRequest
+		expectedCode     int
+		expectedResponse string
+	}{
+		{
+			scriptTestRequest{Script: script, ScheduleID: "schedule-1"}, http.StatusOK,
+			`{"message":` + message + `,"result":{"value":"eyJDT1VOVFJZIjoiRlIifQ==","fields":{"country":"fr","topic":"target-topic"}}}`,
+		},
+		// value of the request
+		{
+			scriptTestRequest{Script: `function decode(msg) return msg.value .. "!" end`, Value: []byte("value")}, http.StatusOK,
+			`{"message":{"id":"","epoch":0,"scheduler":"scheduler-1","topic":"","target_topic":"","target_key":"","value":"dmFsdWU="},"result":{"value":"dmFsdWUh"}}`,
+		},
+		// errors of the script
+		{
+			scriptTestRequest{Script: `function decode(msg) return 42 end`, ScheduleID: "schedule-1"}, http.StatusOK,
+			`{"message":` + message + `,"error":"script test: the value returned by decode should be a string or nil, not a number"}`,
+		},
+		{scriptTestRequest{Script: `x = 1`, ScheduleID: "schedule-1"}, http.StatusBadRequest, `{"error":"the script has no decode function: test"}`},
+		{scriptTestRequest{Script: `function decode(msg)`, ScheduleID: "schedule-1"}, http.StatusBadRequest, ""},
+		{scriptTestRequest{Script: script, ScheduleID: "schedule-2"}, http.StatusNotFound, ""},
+		{scriptTestRequest{ScheduleID: "schedule-1"}, http.StatusBadRequest, `{"error":"script is mandatory"}`},
+		{scriptTestRequest{Script: script}, http.StatusBadRequest, `{"error":"schedule-id or value is mandatory"}`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			url := fmt.Sprintf(ScriptTestEndpoint, "scheduler-1")
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(toJSON(t, tt.body)))
+			response := executeRequest(router, req)
+			checkResponseCode(t, tt.expectedCode, response.Code)
+			if tt.expectedResponse == "" {
+				return
+			}
+
+			// the duration is not compared
+			var body map[string]interface{}
+			if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			delete(body, "duration_ms")
+			if eq, _ := AreEqualJSON(string(toJSON(t, body)), tt.expectedResponse); !eq {
+				t.Errorf("unexpected body: %v, expected: %s", response.Body.String(), tt.expectedResponse)
+			}
+		})
+	}
+
+	// nothing is stored
+	schs, _ := cold.Get("scheduler-1", "schedule-1")
+	if v := string(schs[0].Schedule.(rest.Schedule).MessageValue); v != `{"country":"fr"}` {
+		t.Errorf("unexpected stored value: %v", v)
+	}
+}
+
+// Rule #29: search should filter the schedules by the fields extracted by the decoder
+func TestRestAPIServer_searchSchedules_fields(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	withFields := func(id string, fields map[string]string) decoder.WithFields {
+		return decoder.WithFields{Schedule: rest.Schedule{ScheduleID: id, ScheduleEpoch: 1000}, Fields: fields}
+	}
+	for _, st := range stores {
+		st.Clear()
+		st.Add("scheduler-1",
+			withFields("schedule-1", map[string]string{"country": "fr", "type": "a"}),
+			withFields("schedule-2", map[string]string{"country": "uk", "type": "a", "city": "new york"}),
+			rest.Schedule{ScheduleID: "schedule-3", ScheduleEpoch: 1000},
+		)
+	}
+
+	tests := []struct {
+		query       string
+		expectedIDs []string
+	}{
+		{"field.country=fr", []string{"schedule-1"}},
+		{"field.type=a", []string{"schedule-1", "schedule-2"}},
+		{"field.type=a&field.country=uk", []string{"schedule-2"}},
+		{"field.country=de", nil},
+		// the whole value is matched, not its words
+		{"field.city=new%20york", []string{"schedule-2"}},
+		{"field.city=new", nil},
+		{"", []string{"schedule-1", "schedule-2", "schedule-3"}},
+	}
+
+	for i, tt := range tests {
+		for _, endpoint := range SchedulesEndpoints {
+			t.Run(fmt.Sprintf("case #%v %v", i+1, endpoint), func(t *testing.T) {
+				url := fmt.Sprintf(endpoint, "scheduler-1") + "?" + tt.query
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+				response := executeRequest(router, req)
+				checkResponseCode(t, http.StatusOK, response.Code)
+
+				var result struct {
+					Found     int `json:"found"`
+					Schedules []struct {
+						Schedule struct {
+							ID string `json:"id"`
+						} `json:"schedule"`
+					} `json:"schedules"`
+				}
+				if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				ids := make([]string, 0, len(result.Schedules))
+				for _, s := range result.Schedules {
+					ids = append(ids, s.Schedule.ID)
+				}
+				if fmt.Sprint(ids) != fmt.Sprint(tt.expectedIDs) || result.Found != len(tt.expectedIDs) {
+					t.Errorf("unexpected schedules: %v (found %v)", ids, result.Found)
+				}
+			})
+		}
+	}
+}
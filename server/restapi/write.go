@@ -0,0 +1,185 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	MaxBodySize = 1 << 20
+)
+
+var (
+	errInvalidEpoch       = errors.New("epoch is mandatory")
+	errInvalidTargetTopic = errors.New("target-topic is mandatory")
+	errInvalidValue       = errors.New("value is mandatory, use DELETE to cancel a schedule")
+)
+
+// payload of the create (POST) and update (PUT) routes, value is base64 encoded
+// like in the schedules returned by the API
+type scheduleRequest struct {
+	Epoch       int64  `json:"epoch"`
+	TargetTopic string `json:"target-topic"`
+	TargetKey   string `json:"target-key"`
+	Value       []byte `json:"value"`
+}
+
+func (s scheduleRequest) validate() error {
+	if s.Epoch <= 0 {
+		return errInvalidEpoch
+	}
+	if strings.TrimSpace(s.TargetTopic) == "" {
+		return errInvalidTargetTopic
+	}
+	// an empty value is a tombstone for the scheduler
+	if len(s.Value) == 0 {
+		return errInvalidValue
+	}
+	return nil
+}
+
+func decodeScheduleRequest(w http.ResponseWriter, r *http.Request) (scheduleRequest, error) {
+	var req scheduleRequest
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize)).Decode(&req)
+	if err != nil {
+		return req, fmt.Errorf("invalid json body: %w", err)
+	}
+
+	return req, req.validate()
+}
+
+// currentSchedule returns the latest version of the schedule, nil when it doesn't exist
+func currentSchedule(d db.DB, schedulerName, scheduleID string) (*producer.Schedule, error) {
+	schs, err := d.Get(schedulerName, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if len(schs) == 0 {
+		return nil, nil
+	}
+
+	versions := db.Versions(schs)
+	sch, err := producer.FromSchedule(versions[len(versions)-1].Schedule)
+	if err != nil {
+		return nil, err
+	}
+	return &sch, nil
+}
+
+func respondWithProducerResult(w http.ResponseWriter, code int, result producer.Result, err error) {
+	if err != nil {
+		log.Errorf("cannot produce schedule message: %v", err)
+		if errors.Is(err, producer.ErrSchedulerNotFound) {
+			respondWithErrorCode(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondWithError(w, err.Error())
+		return
+	}
+
+	respondWithJSON(w, code, result)
+}
+
+func writeSchedule(coldDB db.DB, prod producer.Producer, al *audit.Log, create bool) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		schedulerName, scheduleID := vars["name"], vars["id"]
+
+		req, err := decodeScheduleRequest(w, r)
+		if err != nil {
+			respondWithErrorCode(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		current, err := currentSchedule(coldDB, schedulerName, scheduleID)
+		if err != nil {
+			respondWithError(w, err.Error())
+			return
+		}
+
+		if create && current != nil {
+			respondWithErrorCode(w, http.StatusConflict, fmt.Sprintf("schedule %v already exists", scheduleID))
+			return
+		}
+		if !create && current == nil {
+			respondWithJSON(w, http.StatusNotFound, nil)
+			return
+		}
+
+		sch := producer.Schedule{
+			ID:          scheduleID,
+			Epoch:       req.Epoch,
+			TargetTopic: req.TargetTopic,
+			TargetKey:   req.TargetKey,
+			Value:       req.Value,
+		}
+		result, err := prod.Produce(schedulerName, sch)
+
+		code := http.StatusOK
+		action := audit.UpdateSchedule
+		if create {
+			code = http.StatusCreated
+			action = audit.CreateSchedule
+		}
+
+		entry := audit.Entry{
+			Action:     action,
+			Scheduler:  schedulerName,
+			ScheduleID: scheduleID,
+			After:      audit.Payload(sch),
+		}
+		if current != nil {
+			entry.Before = audit.Payload(current)
+		}
+		record(al, r, entry, err)
+
+		respondWithProducerResult(w, code, result, err)
+	}
+}
+
+func createSchedule(coldDB db.DB, prod producer.Producer, al *audit.Log) func(w http.ResponseWriter, r *http.Request) {
+	return writeSchedule(coldDB, prod, al, true)
+}
+
+func updateSchedule(coldDB db.DB, prod producer.Producer, al *audit.Log) func(w http.ResponseWriter, r *http.Request) {
+	return writeSchedule(coldDB, prod, al, false)
+}
+
+func cancelSchedule(coldDB db.DB, prod producer.Producer, al *audit.Log) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		schedulerName, scheduleID := vars["name"], vars["id"]
+
+		current, err := currentSchedule(coldDB, schedulerName, scheduleID)
+		if err != nil {
+			respondWithError(w, err.Error())
+			return
+		}
+
+		if current == nil {
+			respondWithJSON(w, http.StatusNotFound, nil)
+			return
+		}
+
+		result, err := prod.Cancel(schedulerName, scheduleID)
+
+		record(al, r, audit.Entry{
+			Action:     audit.CancelSchedule,
+			Scheduler:  schedulerName,
+			ScheduleID: scheduleID,
+			Before:     audit.Payload(current),
+		}, err)
+
+		respondWithProducerResult(w, http.StatusOK, result, err)
+	}
+}
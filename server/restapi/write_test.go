@@ -0,0 +1,212 @@
+package restapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer"
+)
+
+const (
+	ScheduleEndpoint = "/scheduler/%s/schedule/%s"
+)
+
+type scheduleRequest struct {
+	Epoch       int64  `json:"epoch"`
+	TargetTopic string `json:"target-topic"`
+	TargetKey   string `json:"target-key"`
+	Value       []byte `json:"value"`
+}
+
+// Rule #13: create schedule should produce a schedule message, or fail when invalid or already existing
+func TestRestAPIServer_createSchedule(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	epoch := time.Now().Add(1 * time.Hour).Unix()
+	valid := scheduleRequest{epoch, "target-topic", "target-key", []byte("value")}
+
+	scheduler1 := schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(newSchedule("scheduler-1", "schedule-1", epoch)),
+	}
+
+	tests := []struct {
+		schedules        []schedulerSchedules
+		scheduleID       string
+		body             string
+		expectedCode     int
+		expectedResponse string
+	}{
+		{nil, "schedule-1", string(toJSON(t, valid)), http.StatusCreated, string(toJSON(t, producer.Result{Scheduler: "scheduler-1", Topic: "schedules"}))},
+		// already existing
+		{schedulersSchedules(scheduler1), "schedule-1", string(toJSON(t, valid)), http.StatusConflict, `{"error":"schedule schedule-1 already exists"}`},
+		// invalid payloads
+		{nil, "schedule-1", "{", http.StatusBadRequest, `{"error":"invalid json body: unexpected EOF"}`},
+		{nil, "schedule-1", string(toJSON(t, scheduleRequest{0, "target-topic", "target-key", []byte("value")})), http.StatusBadRequest, `{"error":"epoch is mandatory"}`},
+		{nil, "schedule-1", string(toJSON(t, scheduleRequest{epoch, "", "target-key", []byte("value")})), http.StatusBadRequest, `{"error":"target-topic is mandatory"}`},
+		{nil, "schedule-1", string(toJSON(t, scheduleRequest{epoch, "target-topic", "target-key", nil})), http.StatusBadRequest, `{"error":"value is mandatory, use DELETE to cancel a schedule"}`},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			createSchedulerSchedules(tt.schedules, stores...)
+
+			url := fmt.Sprintf(ScheduleEndpoint, "scheduler-1", tt.scheduleID)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(tt.body))
+			response := executeRequest(router, req)
+			checkResponseJSON(t, tt.expectedCode, response, tt.expectedResponse)
+		})
+	}
+}
+
+// Rule #14: update schedule should add a new version of an existing schedule
+func TestRestAPIServer_updateSchedule(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	epoch := time.Now().Add(1 * time.Hour).Unix()
+	scheduler1 := schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(newSchedule("scheduler-1", "schedule-1", epoch)),
+	}
+
+	url := fmt.Sprintf(ScheduleEndpoint, "scheduler-1", "schedule-1")
+	body := toJSON(t, scheduleRequest{epoch + 60, "target-topic", "target-key", []byte("value")})
+
+	// not found
+	createSchedulerSchedules(nil, stores...)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewBuffer(body))
+	response := executeRequest(router, req)
+	checkResponseJSON(t, http.StatusNotFound, response, "")
+
+	// found
+	createSchedulerSchedules(schedulersSchedules(scheduler1), stores...)
+	req, _ = http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewBuffer(body))
+	response = executeRequest(router, req)
+	checkResponseJSON(t, http.StatusOK, response, toJSON(t, producer.Result{Scheduler: "scheduler-1", Topic: "schedules"}))
+
+	versions, err := stores[0].Get("scheduler-1", "schedule-1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("unexpected versions count: %v", len(versions))
+	}
+	if v := versions[1].Epoch(); v != epoch+60 {
+		t.Errorf("unexpected epoch for the new version: %v", v)
+	}
+}
+
+// Rule #15: cancel schedule should send a tombstone for an existing schedule
+func TestRestAPIServer_cancelSchedule(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	scheduler1 := schedulerSchedules{
+		"scheduler-1",
+		schedulesSlice(newSchedule("scheduler-1", "schedule-1", time.Now().Unix())),
+	}
+
+	url := fmt.Sprintf(ScheduleEndpoint, "scheduler-1", "schedule-1")
+
+	// not found
+	createSchedulerSchedules(nil, stores...)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodDelete, url, http.NoBody)
+	response := executeRequest(router, req)
+	checkResponseJSON(t, http.StatusNotFound, response, "")
+
+	// found
+	createSchedulerSchedules(schedulersSchedules(scheduler1), stores...)
+	req, _ = http.NewRequestWithContext(ctx, http.MethodDelete, url, http.NoBody)
+	response = executeRequest(router, req)
+	checkResponseJSON(t, http.StatusOK, response, toJSON(t, producer.Result{Scheduler: "scheduler-1", Topic: "schedules"}))
+
+	// should not be found anymore
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	response = executeRequest(router, req)
+	checkResponseJSON(t, http.StatusNotFound, response, "")
+}
+
+// Rule #21: versions of a schedule should be returned from the oldest with the changes between them
+func TestRestAPIServer_getScheduleVersions(t *testing.T) {
+	router, stores, _ := newRouter()
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFunc()
+
+	createSchedulerSchedules(nil, stores...)
+
+	url := fmt.Sprintf(ScheduleEndpoint, "scheduler-1", "schedule-1")
+	epoch := time.Now().Add(1 * time.Hour).Unix()
+
+	// not found
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url+"/versions", http.NoBody)
+	response := executeRequest(router, req)
+	checkResponseJSON(t, http.StatusNotFound, response, "")
+
+	writes := []struct {
+		method string
+		body   scheduleRequest
+	}{
+		{http.MethodPost, scheduleRequest{epoch, "target-topic", "target-key", []byte("value")}},
+		{http.MethodPut, scheduleRequest{epoch + 60, "target-topic", "target-key", []byte("value")}},
+		{http.MethodPut, scheduleRequest{epoch + 60, "other-topic", "target-key", []byte("new value")}},
+	}
+	for _, w := range writes {
+		req, _ = http.NewRequestWithContext(ctx, w.method, url, bytes.NewBuffer(toJSON(t, w.body)))
+		response = executeRequest(router, req)
+		if response.Code != http.StatusCreated && response.Code != http.StatusOK {
+			t.Fatalf("unexpected response code for %v: %v", w.method, response.Code)
+		}
+	}
+
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, url+"/versions", http.NoBody)
+	response = executeRequest(router, req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var versions []struct {
+		Offset  *int64      `json:"offset"`
+		Topic   string      `json:"topic"`
+		Changes []db.Change `json:"changes"`
+	}
+	err := json.Unmarshal(response.Body.Bytes(), &versions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(versions) != 3 {
+		t.Fatalf("unexpected versions count: %v", len(versions))
+	}
+
+	expectedChanges := [][]string{
+		{},
+		{"epoch"},
+		{"target-topic", "value"},
+	}
+	for i, v := range versions {
+		if v.Offset == nil || *v.Offset != int64(i) || v.Topic != "schedules" {
+			t.Errorf("unexpected version #%v: %+v", i, v)
+		}
+		fields := []string{}
+		for _, c := range v.Changes {
+			fields = append(fields, c.Field)
+		}
+		if !reflect.DeepEqual(fields, expectedChanges[i]) {
+			t.Errorf("unexpected changes for version #%v: %+v", i, v.Changes)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/auth"
+	"github.com/etf1/kafka-message-scheduler-admin/server/config"
+)
+
+// NewAuthenticator returns the authenticators configured by the environment variables,
+// nil when none is configured (the authentication is disabled)
+func NewAuthenticator() (auth.Authenticator, error) {
+	bindings := auth.RoleBindings{}
+	if path := config.AuthRolesFile(); path != "" {
+		b, err := auth.LoadRoleBindings(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load roles file: %w", err)
+		}
+		bindings = b
+	}
+
+	chain := auth.Chain{}
+
+	if path := config.AuthTokensFile(); path != "" {
+		tokens, err := auth.LoadTokens(path, bindings)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load tokens file: %w", err)
+		}
+		chain = append(chain, tokens)
+	}
+
+	if path := config.AuthHtpasswdFile(); path != "" {
+		basic, err := auth.LoadHtpasswd(path, bindings)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load htpasswd file: %w", err)
+		}
+		chain = append(chain, basic)
+	}
+
+	if path := config.AuthJWKSFile(); path != "" {
+		jwt, err := auth.NewJWT(auth.JWTConfig{
+			JWKSPath:   path,
+			Issuer:     config.AuthJWTIssuer(),
+			Audience:   config.AuthJWTAudience(),
+			UserClaim:  config.AuthJWTUserClaim(),
+			RolesClaim: config.AuthJWTRolesClaim(),
+		}, bindings)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, jwt)
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/config"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/breaker"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/cache"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/chain"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/grpcdecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/httpdecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/luadecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemadecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/schemaregistry"
+	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
+)
+
+// NewDecoder returns the decoder of the kafka message bodies configured by the environment variables,
+// nil when no decoder is configured (the bodies are not decoded). Each decoder has a circuit breaker,
+// the decoded bodies are cached. The returned function closes the decoders (ie: the connection of the grpc decoder),
+// it is called once the stores using the decoder are closed.
+func NewDecoder() (decoder.Decoder, func() error, error) {
+	dec, closers, err := newDecoders()
+	closeAll := func() error {
+		var result error
+		for _, c := range closers {
+			if err := c.Close(); err != nil && result == nil {
+				result = err
+			}
+		}
+		return result
+	}
+	if err != nil {
+		helper.LogErr(closeAll())
+		return nil, nil, err
+	}
+	return dec, closeAll, nil
+}
+
+func newDecoders() (dec decoder.Decoder, closers []io.Closer, err error) {
+	defaultNames := config.Decoders()
+	byScheduler := config.DecodersByScheduler()
+	byTopic := config.DecodersByTopic()
+
+	if len(defaultNames) == 0 && len(byScheduler) == 0 && len(byTopic) == 0 {
+		return nil, nil, nil
+	}
+
+	sources := schemaregistry.Sources{}
+	if dir := config.SchemaRegistryDir(); dir != "" {
+		sources = append(sources, schemaregistry.Directory(dir))
+	}
+	if u := config.SchemaRegistryURL(); u != "" {
+		sources = append(sources, schemaregistry.NewRegistry(u, schemaregistry.DefaultTimeout))
+	}
+
+	// the decoders are shared by the chains, for sharing the cache of the schemas and the circuit breakers
+	decoders := map[string]decoder.Decoder{}
+	newChain := func(names []string) (chain.Chain, error) {
+		result := chain.Chain{}
+		for _, name := range names {
+			d, found := decoders[name]
+			if !found {
+				var err error
+				d, err = newDecoder(name, sources)
+				if err != nil {
+					return nil, err
+				}
+				if c, ok := d.(io.Closer); ok {
+					closers = append(closers, c)
+				}
+				d = breaker.New(name, d, config.DecoderBreakerThreshold(), config.DecoderBreakerCooldown())
+				decoders[name] = d
+			}
+			result = append(result, d)
+		}
+		return result, nil
+	}
+
+	router := chain.Router{
+		Schedulers: map[string]decoder.Decoder{},
+		Topics:     map[string]decoder.Decoder{},
+	}
+
+	if len(defaultNames) > 0 {
+		c, err := newChain(defaultNames)
+		if err != nil {
+			return nil, closers, err
+		}
+		router.Default = c
+	}
+
+	for name, names := range byScheduler {
+		c, err := newChain(names)
+		if err != nil {
+			return nil, closers, fmt.Errorf("decoders of scheduler %v: %w", name, err)
+		}
+		router.Schedulers[name] = c
+	}
+
+	for topic, names := range byTopic {
+		c, err := newChain(names)
+		if err != nil {
+			return nil, closers, fmt.Errorf("decoders of topic %v: %w", topic, err)
+		}
+		router.Topics[topic] = c
+	}
+
+	if size := config.DecoderCacheSize(); size > 0 {
+		return cache.New(router, size), closers, nil
+	}
+	return router, closers, nil
+}
+
+func newDecoder(name string, sources schemaregistry.Sources) (decoder.Decoder, error) {
+	switch name {
+	case "http", "grpc":
+		if !config.KafkaMessageBodyDecoderSet() {
+			return nil, fmt.Errorf("KAFKA_MESSAGE_BODY_DECODER is required by the %v decoder", name)
+		}
+		u := config.KafkaMessageBodyDecoder()
+		if grpcdecoder.IsURL(u) {
+			return grpcdecoder.New(u, config.KafkaMessageBodyDecoderTimeout())
+		}
+		return httpdecoder.Decoder{URL: u}, nil
+	case "avro", "protobuf", "jsonschema":
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("SCHEMA_REGISTRY_URL or SCHEMA_REGISTRY_DIR is required by the %v decoder", name)
+		}
+		switch name {
+		case "avro":
+			return schemadecoder.NewAvroDecoder(sources), nil
+		case "protobuf":
+			return schemadecoder.NewProtobufDecoder(sources), nil
+		default:
+			return schemadecoder.NewJSONSchemaDecoder(sources), nil
+		}
+	case "lua":
+		dir := config.LuaScriptsDir()
+		if dir == "" {
+			return nil, fmt.Errorf("LUA_SCRIPTS_DIR is required by the lua decoder")
+		}
+		return luadecoder.New(dir, luadecoder.Sandbox{Timeout: config.LuaScriptTimeout()})
+	default:
+		return nil, fmt.Errorf("unknown decoder: %v", name)
+	}
+}
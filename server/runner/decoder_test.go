@@ -0,0 +1,65 @@
+package runner_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/runner"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+)
+
+func setenv(t *testing.T, key, value string) {
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		os.Unsetenv(key)
+	})
+}
+
+// Rule #1: the scheduler of the schedule should be passed through the router, the chains and the breakers to the decoders
+func TestNewDecoder_scheduler(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"default.lua":     `function decode(msg) return "default:" .. msg.scheduler end`,
+		"scheduler-1.lua": `function decode(msg) return "scheduler-1:" .. msg.scheduler end`,
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	setenv(t, "DECODERS", "lua")
+	setenv(t, "LUA_SCRIPTS_DIR", dir)
+
+	dec, closeDecoder, err := runner.NewDecoder()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer closeDecoder()
+
+	tests := []struct {
+		schedulerName string
+		expectedValue string
+	}{
+		{"scheduler-1", "scheduler-1:scheduler-1"},
+		{"scheduler-2", "default:scheduler-2"},
+		// cached by scheduler
+		{"scheduler-1", "scheduler-1:scheduler-1"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result, err := decoder.Decode(dec, tt.schedulerName, rest.Schedule{ScheduleID: "schedule-1", MessageValue: []byte("value")})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v := string(result.(rest.Schedule).MessageValue); v != tt.expectedValue {
+				t.Errorf("unexpected value: %v", v)
+			}
+		})
+	}
+}
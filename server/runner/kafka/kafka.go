@@ -1,22 +1,34 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/etf1/kafka-message-scheduler-admin/server/alert"
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	auditkafka "github.com/etf1/kafka-message-scheduler-admin/server/audit/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
 	"github.com/etf1/kafka-message-scheduler-admin/server/config"
+	"github.com/etf1/kafka-message-scheduler-admin/server/db"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db/blevedb"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
-	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
-	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/httpdecoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/luadecoder"
 	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
-	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	kafkaproducer "github.com/etf1/kafka-message-scheduler-admin/server/producer/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
 	"github.com/etf1/kafka-message-scheduler-admin/server/runner"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/broadcast"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store/rest"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -65,37 +77,69 @@ func (r *Runner) Start() error {
 		log.Println(variable[0], "=>", variable[1])
 	}
 
-	var dec decoder.Decoder
+	dec, closeDecoder, err := runner.NewDecoder()
+	if err != nil {
+		return fmt.Errorf("cannot create decoder: %w", err)
+	}
+	// deferred first, the decoder is closed after the stores using it
+	defer func() {
+		helper.LogErr(closeDecoder())
+	}()
+	kafka.DecoderWorkers = config.DecoderWorkers()
 
-	if decoderURL := config.KafkaMessageBodyDecoder(); decoderURL != "" {
-		dec = httpdecoder.Decoder{
-			URL: decoderURL,
-		}
+	properties, err := runner.NewKafkaProperties()
+	if err != nil {
+		return err
 	}
 
+	// the pending events of the stores are discarded when the shutdown times out
+	ctx, abort := context.WithCancel(context.Background())
+	defer abort()
+
 	// cold DB
-	resolver := httpresolver.NewResolver(config.SchedulersAddr())
+	resolver, err := runner.NewResolver()
+	if err != nil {
+		return fmt.Errorf("cannot create schedulers resolver: %w", err)
+	}
+	defer resolver.Close()
+
+	// offsets consumed by the cold and history DBs, checked before the data files are created
+	offsets, err := openOffsets(dir+"schedules.offsets", []string{dir + "schedules.bleve"}, dir+"schedules.bbolt", dir+"cancelled.bbolt")
+	if err != nil {
+		return fmt.Errorf("cannot open offsets: %w", err)
+	}
+	defer offsets.Close()
+
+	historyOffsets, err := openOffsets(dir+"history.offsets", []string{dir + "history.bleve"}, dir+"history.bbolt")
+	if err != nil {
+		return fmt.Errorf("cannot open history offsets: %w", err)
+	}
+	defer historyOffsets.Close()
+
 	bboltStore, err := bbolt.NewStore(dir + "schedules.bbolt")
 	if err != nil {
 		return fmt.Errorf("cannot create bbolt store: %w", err)
 	}
 	defer bboltStore.Close()
 
-	watchableStore, err := NewWatchableStoreFromResolver(resolver, SchedulesTopics, dec)
+	watchableStore, err := NewWatchableStoreFromResolver(ctx, resolver, SchedulesTopics, properties, dec, offsets)
 	if err != nil {
 		return fmt.Errorf("cannot create watchable store: %w", err)
 	}
-	defer watchableStore.Close()
+	// the cold DB is closed after the events of the watchable store are forwarded to it
+	coldClosers := []func() error{watchableStore.Close}
+	defer func() {
+		closeInOrder(coldClosers)
+	}()
 
-	coldDB, err := blevedb.NewDB(blevedb.Config{
-		InternalStore: bboltStore,
-		SourceStore:   watchableStore,
-		Path:          dir + "schedules.bleve",
+	// events of the cold DB are shared between the indexer, the outcome tracker and the events stream,
+	// the watchers are registered before the source is watched so none of them misses the first events
+	events := broadcast.NewBroadcaster(watchableStore)
+	coldEvents, trackerEvents := events.Watcher(), events.Watcher()
+	coldClosers = append(coldClosers, func() error {
+		events.Wait()
+		return nil
 	})
-	if err != nil {
-		return fmt.Errorf("cannot create bleve db %v: %w", dir, err)
-	}
-	defer coldDB.Close()
 
 	// history DB
 	historyBboltStore, err := bbolt.NewStore(dir + "history.bbolt")
@@ -104,32 +148,207 @@ func (r *Runner) Start() error {
 	}
 	defer historyBboltStore.Close()
 
-	historyWatchableStore, err := NewWatchableStoreFromResolver(resolver, HistoryTopic, dec)
+	coldDB, err := blevedb.NewDB(blevedb.Config{
+		InternalStore: bboltStore,
+		SourceStore:   coldEvents,
+		Path:          dir + "schedules.bleve",
+		Name:          "cold",
+		Context:       ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create bleve db %v: %w", dir, err)
+	}
+	coldClosers = append(coldClosers, coldDB.Close)
+
+	historyWatchableStore, err := NewWatchableStoreFromResolver(ctx, resolver, HistoryTopic, properties, dec, historyOffsets)
 	if err != nil {
 		return fmt.Errorf("cannot create history watchable store: %w", err)
 	}
-	defer historyWatchableStore.Close()
+	historyClosers := []func() error{historyWatchableStore.Close}
+	defer func() {
+		closeInOrder(historyClosers)
+	}()
+
+	// events of the history DB are shared between the indexer and the outcome tracker
+	historyEvents := broadcast.NewBroadcaster(historyWatchableStore)
+	historyDBEvents, trackerHistoryEvents := historyEvents.Watcher(), historyEvents.Watcher()
+	historyClosers = append(historyClosers, func() error {
+		historyEvents.Wait()
+		return nil
+	})
 
 	historyDB, err := blevedb.NewDB(blevedb.Config{
 		InternalStore: historyBboltStore,
-		SourceStore:   historyWatchableStore,
+		SourceStore:   historyDBEvents,
 		Path:          dir + "history.bleve",
+		Name:          "history",
+		Context:       ctx,
 	})
 	if err != nil {
 		return fmt.Errorf("cannot create history bleve db: %w", err)
 	}
-	defer historyDB.Close()
+	historyClosers = append(historyClosers, historyDB.Close)
+
+	// outcome of the cold schedules, the tombstones of the cold schedules are recorded as cancellations
+	cancelledStore, err := bbolt.NewStore(dir + "cancelled.bbolt")
+	if err != nil {
+		return fmt.Errorf("cannot create cancelled bbolt store: %w", err)
+	}
+
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:       trackerEvents,
+		History:          historyBboltStore,
+		HistoryEvents:    trackerHistoryEvents,
+		CancelledStore:   cancelledStore,
+		LateThreshold:    config.ScheduleLateThreshold(),
+		Schedulers:       resolver,
+		HistoryRetention: config.HistoryRetention(),
+	})
+	if err != nil {
+		cancelledStore.Close()
+		return fmt.Errorf("cannot create outcome tracker: %w", err)
+	}
+	// the cancelled store is closed once the tracker has recorded the last events
+	coldClosers = append(coldClosers, tracker.Close, func() error {
+		cancelledStore.Close()
+		return nil
+	})
 
 	// live DB
 	liveDB := simple.DB{
 		Store: rest.NewStore(resolver, dec),
 	}
 
-	srv := runner.NewServer(coldDB, liveDB, historyDB, resolver)
+	schedulesCollector := metrics.NewSchedulesCollector(resolver, map[string]db.DB{
+		"live":    liveDB,
+		"cold":    coldDB,
+		"history": historyDB,
+	})
+	prometheus.MustRegister(schedulesCollector)
+	defer prometheus.Unregister(schedulesCollector)
+
+	// producer for the write routes
+	prod := kafkaproducer.NewProducer(resolver, properties)
+	defer prod.Close()
+
+	// reschedule is not possible with decoded values, the original message body is lost
+	bulkManager := bulk.NewManager(bulk.Config{
+		ColdDB:        coldDB,
+		Producer:      prod,
+		DecodedValues: dec != nil,
+	})
+	defer bulkManager.Close()
+
+	// audit log, optionally mirrored to a kafka topic
+	var mirror audit.Mirror
+	if topic := config.AuditKafkaTopic(); topic != "" {
+		kafkaMirror, err := auditkafka.NewMirror(config.AuditKafkaBootstrapServers(), topic, properties.Default)
+		if err != nil {
+			return fmt.Errorf("cannot create audit mirror: %w", err)
+		}
+		defer kafkaMirror.Close()
+		mirror = kafkaMirror
+	}
+
+	// stored in a bucket of the schedules store, closed before the store
+	auditLog, err := audit.New(bboltStore.Bolt(), mirror)
+	if err != nil {
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+	defer auditLog.Close()
+
+	overdueChecker := alert.NewChecker(alert.Config{
+		ColdDB:      coldDB,
+		Tracker:     tracker,
+		Resolver:    resolver,
+		Interval:    config.OverdueCheckInterval(),
+		GracePeriod: config.OverdueGracePeriod(),
+		WebhookURL:  config.OverdueWebhookURL(),
+		Ready:       historyWatchableStore.Ready,
+	})
+	overdueChecker.Start()
+	defer overdueChecker.Close()
+
+	authenticator, err := runner.NewAuthenticator()
+	if err != nil {
+		return fmt.Errorf("cannot create authenticator: %w", err)
+	}
+
+	masker, err := runner.NewMasker()
+	if err != nil {
+		return err
+	}
+
+	srv := runner.NewServer(restapi.Config{
+		ColdDB:         coldDB,
+		LiveDB:         liveDB,
+		HistoryDB:      historyDB,
+		Resolver:       resolver,
+		Producer:       prod,
+		Bulk:           bulkManager,
+		Events:         events,
+		Auth:           authenticator,
+		AllowedOrigins: config.CORSAllowedOrigins(),
+		Audit:          auditLog,
+		Scripts:        &luadecoder.Sandbox{Timeout: config.LuaScriptTimeout()},
+		Masker:         masker,
+		Outcomes:       tracker,
+		Alerts:         overdueChecker,
+		Loaders: map[string]restapi.Loader{
+			"cold":    watchableStore,
+			"history": historyWatchableStore,
+		},
+	})
 
 	helper.StartupHTTPServer(srv)
 	<-r.stopChan
 	helper.LogErr(helper.ShutdownHTTPServer(srv))
 
+	// the stores and DBs are closed by the deferred functions
+	time.AfterFunc(config.ShutdownTimeout(), func() {
+		log.Warnf("shutdown timeout, discarding the pending events")
+		abort()
+	})
+
 	return nil
 }
+
+// closeInOrder calls the close functions in order, the errors are logged
+func closeInOrder(closers []func() error) {
+	for _, c := range closers {
+		helper.LogErr(c())
+	}
+}
+
+// openOffsets opens the offset store of a DB, the offsets are removed when one of the data files of the DB is missing,
+// or when one of its indexes has an outdated mapping: the topics are consumed from the beginning to rebuild it
+func openOffsets(path string, indexPaths []string, dataPaths ...string) (*kafka.OffsetStore, error) {
+	invalid := ""
+	for _, p := range append(dataPaths, indexPaths...) {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			invalid = fmt.Sprintf("%v not found", p)
+			break
+		}
+	}
+	if invalid == "" {
+		for _, p := range indexPaths {
+			outdated, err := blevedb.Outdated(p)
+			if err != nil {
+				return nil, err
+			}
+			if outdated {
+				invalid = fmt.Sprintf("the mapping of %v is outdated", p)
+				break
+			}
+		}
+	}
+
+	if invalid != "" {
+		log.Warnf("%v, the offsets of %v are ignored", invalid, path)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return kafka.NewOffsetStore(path)
+}
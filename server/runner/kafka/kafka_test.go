@@ -254,3 +254,42 @@ loop:
 		}
 	}
 }
+
+// Rule #6: runner must restart on the data of a previous run
+func TestKafkaRunner_restart(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	dataDir := "./" + helper.GenRandString("db-")
+	defer func() {
+		os.RemoveAll(dataDir)
+	}()
+
+	for i := 0; i < 2; i++ {
+		exitchan := make(chan error, 1)
+
+		runner := kafka.NewRunner(dataDir)
+
+		// set a random port to avoid conflict
+		config.SetServerAddr(helper.NextServerAddr("localhost"))
+
+		go func() {
+			exitchan <- runner.Start()
+		}()
+
+		err := helper.WaitForHTTPServer(config.ServerAddr())
+		if err != nil {
+			t.Errorf("run #%v: unreachable host: %v", i+1, err)
+		}
+
+		err = runnertest.CheckSchedulesEndPoint("scheduler")
+		if err != nil {
+			t.Errorf("run #%v: unexpected error: %v", i+1, err)
+		}
+
+		// the runner does not wait for the close when it failed to start
+		go runner.Close()
+		if err := <-exitchan; err != nil {
+			t.Fatalf("run #%v: unexpected error: %v", i+1, err)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/etf1/kafka-message-scheduler-admin/server/config"
 	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
@@ -25,23 +28,48 @@ func HistoryTopic(s httpresolver.Scheduler) []string {
 
 type WatchableStoreFromResolver struct {
 	*kafka.WatchableStore
-	resolver httpresolver.Resolver
+	resolver schedulers.Resolver
 	schs     []schedulers.Scheduler
 	topics   func(s httpresolver.Scheduler) []string
-	// used by close
+	// client properties of the consumers of the buckets
+	properties kafka.ClientProperties
+	// 1 after the first update of the buckets
+	initialized int32
+	// used by close, the exit channel receives the error of the close of the watchable store
 	stopChan chan bool
-	exitChan chan bool
+	exitChan chan error
 }
 
-func (wr *WatchableStoreFromResolver) Close() {
+// Close stops the updates of the buckets and closes the watchable store
+func (wr *WatchableStoreFromResolver) Close() error {
 	log.Printf("calling WatchableStoreFromResolver close")
 	wr.stopChan <- true
-	<-wr.exitChan
+	return <-wr.exitChan
 }
 
+func (wr *WatchableStoreFromResolver) bucket(sch schedulers.Scheduler) (kafka.Bucket, error) {
+	s, ok := sch.(httpresolver.Scheduler)
+	if !ok {
+		return kafka.Bucket{}, fmt.Errorf("unable to cast: %T", sch)
+	}
+	return kafka.Bucket{
+		Name:             s.Name(),
+		BootstrapServers: s.BootstrapServers(),
+		Topics:           wr.topics(s),
+		Properties:       wr.properties.Get(s.Name()),
+	}, nil
+}
+
+// Ready returns true when the buckets of the resolved schedulers are loaded
+func (wr *WatchableStoreFromResolver) Ready() bool {
+	return atomic.LoadInt32(&wr.initialized) == 1 && wr.WatchableStore.Ready()
+}
+
+// updateBuckets adds the buckets of the schedulers returned by the resolver, and removes the buckets
+// of the schedulers which are not returned anymore (only when the resolver returns complete results)
 func (wr *WatchableStoreFromResolver) updateBuckets() error {
 	schs, err := wr.resolver.List()
-	if err != nil {
+	if err != nil && len(schs) == 0 {
 		return err
 	}
 
@@ -49,35 +77,68 @@ func (wr *WatchableStoreFromResolver) updateBuckets() error {
 
 	wr.schs = schs
 	buckets := make([]kafka.Bucket, 0)
+	names := make(map[string]bool)
 
 	for _, sch := range schs {
-		s, ok := sch.(httpresolver.Scheduler)
-		if !ok {
-			return fmt.Errorf("unable to cast: %T", sch)
+		bucket, err := wr.bucket(sch)
+		if err != nil {
+			return err
 		}
-		buckets = append(buckets, kafka.Bucket{
-			Name:             s.Name(),
-			BootstrapServers: s.BootstrapServers(),
-			Topics:           wr.topics(s),
-		})
+		buckets = append(buckets, bucket)
+		names[bucket.Name] = true
 	}
 
 	wr.WatchableStore.AddBuckets(buckets...)
+	atomic.StoreInt32(&wr.initialized, 1)
+
+	// partial results, some schedulers may be missing
+	if err != nil {
+		return err
+	}
+
+	removed := []string{}
+	for _, name := range wr.WatchableStore.Buckets() {
+		if !names[name] {
+			removed = append(removed, name)
+		}
+	}
+	wr.WatchableStore.RemoveBuckets(removed...)
+
+	return nil
+}
+
+// applyEvent updates the bucket of a scheduler changed in the resolver
+func (wr *WatchableStoreFromResolver) applyEvent(evt schedulers.Event) error {
+	log.Printf("received scheduler event from resolver: %v %v", evt.EventType, evt.Name())
+
+	if evt.EventType == schedulers.RemovedType {
+		wr.WatchableStore.RemoveBuckets(evt.Name())
+		return nil
+	}
+
+	bucket, err := wr.bucket(evt.Scheduler)
+	if err != nil {
+		return err
+	}
+	wr.WatchableStore.AddBuckets(bucket)
 
 	return nil
 }
 
 type TopicFunc func(s httpresolver.Scheduler) []string
 
-func NewWatchableStoreFromResolver(resolver httpresolver.Resolver, topics TopicFunc, d decoder.Decoder) (*WatchableStoreFromResolver, error) {
+// NewWatchableStoreFromResolver returns a watchable store of the topics of the resolved schedulers, the offsets are
+// committed to the offset store when not nil. The pending events are discarded when the context is done.
+func NewWatchableStoreFromResolver(ctx context.Context, resolver schedulers.Resolver, topics TopicFunc, properties kafka.ClientProperties, d decoder.Decoder, offsets *kafka.OffsetStore) (*WatchableStoreFromResolver, error) {
 	wr := &WatchableStoreFromResolver{
-		resolver: resolver,
-		stopChan: make(chan bool, 1),
-		exitChan: make(chan bool, 1),
-		topics:   topics,
+		resolver:   resolver,
+		stopChan:   make(chan bool, 1),
+		exitChan:   make(chan error, 1),
+		topics:     topics,
+		properties: properties,
 	}
 
-	ws, err := kafka.NewWatchableStore(d)
+	ws, err := kafka.NewResumableWatchableStore(ctx, d, offsets, config.OffsetsCommitInterval())
 	if err != nil {
 		return wr, err
 	}
@@ -85,11 +146,12 @@ func NewWatchableStoreFromResolver(resolver httpresolver.Resolver, topics TopicF
 	wr.WatchableStore = ws
 
 	go func() {
+		var closeErr error
 		defer func() {
 			if r := recover(); r != nil {
 				log.Errorf("recovering from panic in watchable store resolver: %v", r)
 			}
-			wr.exitChan <- true
+			wr.exitChan <- closeErr
 			log.Warnf("watchable store resolver exited.")
 		}()
 
@@ -97,6 +159,14 @@ func NewWatchableStoreFromResolver(resolver httpresolver.Resolver, topics TopicF
 		recheck := time.NewTimer(duration)
 		defer recheck.Stop()
 
+		// the resolvers which are watchers notify the changes without waiting for the recheck
+		var events <-chan schedulers.Event
+		if w, ok := wr.resolver.(schedulers.Watcher); ok {
+			var stop func()
+			events, stop = w.Watch()
+			defer stop()
+		}
+
 		// initial update of the buckets
 		err := wr.updateBuckets()
 		if err != nil {
@@ -115,8 +185,18 @@ func NewWatchableStoreFromResolver(resolver httpresolver.Resolver, topics TopicF
 				if err != nil {
 					log.Errorf("unable to update buckets: %v", err)
 				}
+			case evt, ok := <-events:
+				if !ok {
+					// resolver closed
+					events = nil
+					continue
+				}
+				err := wr.applyEvent(evt)
+				if err != nil {
+					log.Errorf("unable to update bucket: %v", err)
+				}
 			case <-wr.stopChan:
-				wr.WatchableStore.Close()
+				closeErr = wr.WatchableStore.Close()
 				break loop
 			}
 		}
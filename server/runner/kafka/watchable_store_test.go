@@ -2,6 +2,7 @@
 package kafka_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
 	"github.com/etf1/kafka-message-scheduler-admin/server/runner/kafka"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	storekafka "github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
 )
 
 // Rule #1: watch should stream all schedules by type (upsert or deleted) (from resolver)
@@ -28,7 +30,7 @@ func TestKafkaWatchableStoreFromResolver_Watch(t *testing.T) {
 
 	resolver := httpresolver.NewResolver(config.SchedulersAddr())
 
-	kstore, err := kafka.NewWatchableStoreFromResolver(resolver, kafka.DefaultTopics, nil)
+	kstore, err := kafka.NewWatchableStoreFromResolver(context.Background(), resolver, kafka.DefaultTopics, storekafka.ClientProperties{}, nil, nil)
 	if err != nil {
 		t.Errorf("failed to create kafka store: %v\n", err)
 	}
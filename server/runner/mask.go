@@ -0,0 +1,24 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/config"
+	"github.com/etf1/kafka-message-scheduler-admin/server/mask"
+)
+
+// NewMasker returns the masker of the schedules configured by the environment variables,
+// nil when no rules file is configured (the schedules are not masked)
+func NewMasker() (*mask.Masker, error) {
+	path := config.MaskingRulesFile()
+	if path == "" {
+		return nil, nil
+	}
+
+	m, err := mask.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load masking rules file: %w", err)
+	}
+
+	return m, nil
+}
@@ -6,13 +6,24 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/etf1/kafka-message-scheduler-admin/server/alert"
+	"github.com/etf1/kafka-message-scheduler-admin/server/audit"
+	"github.com/etf1/kafka-message-scheduler-admin/server/bulk"
+	"github.com/etf1/kafka-message-scheduler-admin/server/config"
 	"github.com/etf1/kafka-message-scheduler-admin/server/db/simple"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder/luadecoder"
 	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
+	"github.com/etf1/kafka-message-scheduler-admin/server/outcome"
+	"github.com/etf1/kafka-message-scheduler-admin/server/producer/mutable"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/slice"
+	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
 	"github.com/etf1/kafka-message-scheduler-admin/server/runner"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/broadcast"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
 	"github.com/etf1/kafka-message-scheduler/schedule"
 	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
@@ -111,12 +122,26 @@ func (r *Runner) Start() error {
 	coldStore := hmap.NewStore()
 	coldDB := simple.DB{Store: coldStore}
 
-	liveStore := hmap.NewStore()
-	liveDB := simple.DB{Store: liveStore}
+	// started now, so the generated schedules are not sent to the events stream
+	events := broadcast.NewBroadcaster(coldStore)
+	events.Start()
 
 	historyStore := hmap.NewStore()
 	historyDB := simple.DB{Store: historyStore}
 
+	tracker, err := outcome.NewTracker(outcome.Config{
+		ColdEvents:     events,
+		History:        historyStore,
+		CancelledStore: hmap.NewStore(),
+		LateThreshold:  config.ScheduleLateThreshold(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create outcome tracker: %w", err)
+	}
+
+	liveStore := hmap.NewStore()
+	liveDB := simple.DB{Store: liveStore}
+
 	resolver := slice.NewResolver()
 
 	sch1 := newScheduler("scheduler-1")
@@ -150,7 +175,64 @@ func (r *Runner) Start() error {
 	logErr(historyStore.Add(sch2.Name(), genRandVersions(schs[100:150])...))
 	logErr(historyStore.Add(sch3.Name(), genRandVersions(schs[200:250])...))
 
-	srv := runner.NewServer(coldDB, liveDB, historyDB, resolver)
+	prod := mutable.NewProducer(coldStore)
+
+	bulkManager := bulk.NewManager(bulk.Config{
+		ColdDB:   coldDB,
+		Producer: prod,
+	})
+	defer bulkManager.Close()
+
+	// the mini runner is in memory, so the audit log is removed on exit
+	auditDir, err := os.MkdirTemp("", "mini-audit-")
+	if err != nil {
+		return fmt.Errorf("cannot create audit directory: %w", err)
+	}
+	defer os.RemoveAll(auditDir)
+
+	auditLog, err := audit.Open(filepath.Join(auditDir, "audit.bbolt"), nil)
+	if err != nil {
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+	defer auditLog.Close()
+
+	overdueChecker := alert.NewChecker(alert.Config{
+		ColdDB:      coldDB,
+		Tracker:     tracker,
+		Resolver:    resolver,
+		Interval:    config.OverdueCheckInterval(),
+		GracePeriod: config.OverdueGracePeriod(),
+		WebhookURL:  config.OverdueWebhookURL(),
+	})
+	overdueChecker.Start()
+	defer overdueChecker.Close()
+
+	authenticator, err := runner.NewAuthenticator()
+	if err != nil {
+		return fmt.Errorf("cannot create authenticator: %w", err)
+	}
+
+	masker, err := runner.NewMasker()
+	if err != nil {
+		return err
+	}
+
+	srv := runner.NewServer(restapi.Config{
+		ColdDB:         coldDB,
+		LiveDB:         liveDB,
+		HistoryDB:      historyDB,
+		Resolver:       resolver,
+		Producer:       prod,
+		Bulk:           bulkManager,
+		Events:         events,
+		Auth:           authenticator,
+		AllowedOrigins: config.CORSAllowedOrigins(),
+		Audit:          auditLog,
+		Scripts:        &luadecoder.Sandbox{Timeout: config.LuaScriptTimeout()},
+		Masker:         masker,
+		Outcomes:       tracker,
+		Alerts:         overdueChecker,
+	})
 
 	helper.StartupHTTPServer(srv)
 	<-r.stopChan
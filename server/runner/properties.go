@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/config"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+)
+
+// NewKafkaProperties returns the client properties of the kafka consumers configured by the environment variables,
+// the properties of a scheduler override the default ones
+func NewKafkaProperties() (kafka.ClientProperties, error) {
+	result := kafka.ClientProperties{
+		Buckets: make(map[string]kafka.Properties),
+	}
+
+	if path := config.KafkaPropertiesFile(); path != "" {
+		p, err := kafka.LoadProperties(path)
+		if err != nil {
+			return result, fmt.Errorf("cannot load kafka properties: %w", err)
+		}
+		result.Default = p
+	}
+
+	for name, paths := range config.KafkaPropertiesFilesByScheduler() {
+		props := kafka.Properties{}
+		for _, path := range paths {
+			p, err := kafka.LoadProperties(path)
+			if err != nil {
+				return result, fmt.Errorf("cannot load kafka properties of %v: %w", name, err)
+			}
+			props = props.Merge(p)
+		}
+		result.Buckets[name] = props
+	}
+
+	return result, nil
+}
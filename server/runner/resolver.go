@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/config"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/fileresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/k8sresolver"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/multi"
+)
+
+// NewResolver returns the resolvers of the schedulers configured by the environment variables,
+// the schedulers of the first resolver have priority on the following ones
+func NewResolver() (multi.Resolver, error) {
+	result := multi.Resolver{}
+
+	for _, name := range config.SchedulersResolvers() {
+		switch name {
+		case "dns":
+			result = append(result, httpresolver.NewResolver(config.SchedulersAddr()))
+		case "file":
+			path := config.SchedulersFile()
+			if path == "" {
+				return nil, fmt.Errorf("SCHEDULERS_FILE is required by the file resolver")
+			}
+			r, err := fileresolver.NewResolver(path, fileresolver.DefaultReloadInterval)
+			if err != nil {
+				result.Close()
+				return nil, fmt.Errorf("cannot create file resolver: %w", err)
+			}
+			result = append(result, r)
+		case "kubernetes":
+			r, err := k8sresolver.NewResolver(k8sresolver.Config{
+				Namespace:     config.SchedulersK8SNamespace(),
+				LabelSelector: config.SchedulersK8SLabelSelector(),
+				PortName:      config.SchedulersK8SPortName(),
+				Endpoints:     config.SchedulersK8SEndpoints(),
+			})
+			if err != nil {
+				result.Close()
+				return nil, fmt.Errorf("cannot create kubernetes resolver: %w", err)
+			}
+			result = append(result, r)
+		default:
+			result.Close()
+			return nil, fmt.Errorf("unknown schedulers resolver: %v", name)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no schedulers resolver configured")
+	}
+
+	return result, nil
+}
@@ -6,8 +6,6 @@ import (
 	"time"
 
 	"github.com/etf1/kafka-message-scheduler-admin/server/config"
-	"github.com/etf1/kafka-message-scheduler-admin/server/db"
-	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
 	"github.com/etf1/kafka-message-scheduler-admin/server/restapi"
 	"github.com/gorilla/mux"
 )
@@ -26,22 +24,24 @@ func (fs *spaFileSystem) Open(name string) (http.File, error) {
 }
 
 // TODO: accept a http.Server instance as parameter of the runner, if none then use a default server
-func NewServer(coldDB, liveDB, historyDB db.DB, resolver schedulers.Resolver) *http.Server {
+func NewServer(cfg restapi.Config) *http.Server {
 	var router http.Handler
 
 	if config.APIServerOnly() {
-		router = restapi.NewRouter(coldDB, liveDB, historyDB, resolver)
+		router = restapi.NewRouter(cfg)
 	} else {
 		r := mux.NewRouter().StrictSlash(true)
-		r.PathPrefix("/api").Handler(http.StripPrefix("/api", restapi.NewRouter(coldDB, liveDB, historyDB, resolver)))
+		r.PathPrefix("/api").Handler(http.StripPrefix("/api", restapi.NewRouter(cfg)))
 		r.PathPrefix("/").Handler(http.StripPrefix("/", http.FileServer(&spaFileSystem{http.Dir(config.StaticFilesDir())})))
 		router = r
 	}
 
+	// the write deadline is cleared by the events stream which is a long lived response
 	return &http.Server{
 		Handler:      router,
 		Addr:         config.ServerAddr(),
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
+		IdleTimeout:  60 * time.Second,
 	}
 }
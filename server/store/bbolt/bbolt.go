@@ -6,8 +6,11 @@ import (
 	"strconv"
 	"time"
 
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler/schedule"
+	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
 	log "github.com/sirupsen/logrus"
 	bolt "go.etcd.io/bbolt"
 )
@@ -31,6 +34,98 @@ type Schedule struct {
 	TargetTopic       string `json:"target-topic"`
 	TargetKey         string `json:"target-key"`
 	Value             []byte `json:"value"`
+	// partition and offset of the kafka message, nil when the schedule doesn't come from kafka
+	Partition *int32 `json:"partition,omitempty"`
+	Offset    *int64 `json:"offset,omitempty"`
+	// error of the decoder when the value cannot be decoded, the value is the raw value
+	DecodeError string `json:"decode-error,omitempty"`
+	// fields extracted from the value by the decoder
+	Fields map[string]string `json:"fields,omitempty"`
+	// headers of the kafka message
+	Headers []Header `json:"headers,omitempty"`
+	// timestamp of the kafka message in milliseconds and its type: create-time or log-append-time
+	TimestampMs   int64  `json:"timestamp-ms,omitempty"`
+	TimestampType string `json:"timestamp-type,omitempty"`
+}
+
+// Header is a header of a kafka message, the value is converted to a string
+type Header struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// toSchedule converts a kafka schedule to a bbolt schedule, in order to keep the metadata of the message
+// (partition, offset, headers, timestamp) and the error or the fields of the decoder, other types of schedule are returned as is
+func toSchedule(sch schedule.Schedule) schedule.Schedule {
+	var ks kafka.Schedule
+
+	s, decodeErr, fields := decoder.Unwrap(sch)
+
+	switch s := s.(type) {
+	case kafka.Schedule:
+		ks = s
+	case *kafka.Schedule:
+		if s == nil {
+			return sch
+		}
+		ks = *s
+	default:
+		return sch
+	}
+
+	if ks.Message == nil {
+		return sch
+	}
+
+	result := Schedule{
+		ScheduleID:        ks.ID(),
+		ScheduleEpoch:     ks.Epoch(),
+		ScheduleTimestamp: ks.Timestamp(),
+		Topic:             ks.Topic(),
+		TargetTopic:       ks.TargetTopic(),
+		TargetKey:         ks.TargetKey(),
+		Value:             ks.Value,
+		DecodeError:       decodeErr,
+		Fields:            fields,
+		Headers:           MessageHeaders(ks.Message),
+		TimestampType:     MessageTimestampType(ks.Message),
+	}
+	if !ks.Message.Timestamp.IsZero() {
+		result.TimestampMs = ks.Message.Timestamp.UnixNano() / int64(time.Millisecond)
+	}
+
+	// negative offsets are the special offsets of the kafka client (not consumed message)
+	if ks.TopicPartition.Offset >= 0 {
+		partition := ks.TopicPartition.Partition
+		offset := int64(ks.TopicPartition.Offset)
+		result.Partition, result.Offset = &partition, &offset
+	}
+
+	return result
+}
+
+// MessageHeaders returns the headers of a kafka message, nil when the message has no header
+func MessageHeaders(msg *confluent.Message) []Header {
+	if len(msg.Headers) == 0 {
+		return nil
+	}
+	result := make([]Header, len(msg.Headers))
+	for i, h := range msg.Headers {
+		result[i] = Header{Key: h.Key, Value: string(h.Value)}
+	}
+	return result
+}
+
+// MessageTimestampType returns the type of the timestamp of a kafka message, empty when not available
+func MessageTimestampType(msg *confluent.Message) string {
+	switch msg.TimestampType {
+	case confluent.TimestampCreateTime:
+		return "create-time"
+	case confluent.TimestampLogAppendTime:
+		return "log-append-time"
+	default:
+		return ""
+	}
 }
 
 func NewSchedule(id, epoch interface{}, timestamp ...time.Time) Schedule {
@@ -178,6 +273,7 @@ func (d DB) Add(schedulerName string, ss ...schedule.Schedule) error {
 			return fmt.Errorf("cannot create bucket %s: %s", schedulerName, err)
 		}
 		for _, s := range ss {
+			s = toSchedule(s)
 			v := b.Get([]byte(s.ID()))
 			if v == nil {
 				buf, err := json.Marshal([]schedule.Schedule{s})
@@ -192,6 +288,10 @@ func (d DB) Add(schedulerName string, ss ...schedule.Schedule) error {
 				}
 				continue
 			}
+			if containsMessage(s, v) {
+				log.Debugf("bbolt store skipped %s: message already stored", s.ID())
+				continue
+			}
 
 			buf, err := addToSlice(s, v)
 			if err != nil {
@@ -214,6 +314,11 @@ func (d DB) Close() {
 	d.db.Close()
 }
 
+// Bolt returns the underlying database, to store other buckets next to the schedulers ones (ie: the audit log)
+func (d DB) Bolt() *bolt.DB {
+	return d.db
+}
+
 func (d DB) Delete(schedulerName string, ss ...schedule.Schedule) error {
 	return d.db.Batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(schedulerName))
@@ -249,6 +354,7 @@ func (d DB) addToBucket(tx *bolt.Tx, bucketName string, sch schedule.Schedule) e
 	if err != nil {
 		return fmt.Errorf("cannot create bucket %s: %s", bucketName, err)
 	}
+	sch = toSchedule(sch)
 	v := b.Get([]byte(sch.ID()))
 	if v == nil {
 		buf, err2 := json.Marshal([]schedule.Schedule{sch})
@@ -263,6 +369,12 @@ func (d DB) addToBucket(tx *bolt.Tx, bucketName string, sch schedule.Schedule) e
 		return nil
 	}
 
+	// the messages consumed again when resuming from the committed offsets are already stored
+	if containsMessage(sch, v) {
+		log.Debugf("bbolt store skipped %s: message already stored", sch.ID())
+		return nil
+	}
+
 	buf, err := addToSlice(sch, v)
 	if err != nil {
 		return fmt.Errorf("cannot get bytes for %v: %v", string(v), err)
@@ -281,8 +393,11 @@ func (d DB) Batch(events chan store.Event) chan error {
 
 	var batch []store.Event
 
+	// the events are acknowledged once their transaction is committed
 	processBatch := func() error {
-		return d.db.Batch(func(tx *bolt.Tx) error {
+		var stored []store.Event
+		err := d.db.Batch(func(tx *bolt.Tx) error {
+			stored = nil
 			for _, evt := range batch {
 				var err error
 				switch evt.EventType {
@@ -295,11 +410,21 @@ func (d DB) Batch(events chan store.Event) chan error {
 				}
 				if err != nil {
 					errChan <- err
+					continue
 				}
+				stored = append(stored, evt)
 			}
-			batch = nil
 			return nil
 		})
+		if err != nil {
+			return err
+		}
+
+		batch = nil
+		for _, evt := range stored {
+			evt.Acknowledge()
+		}
+		return nil
 	}
 
 	go func() {
@@ -318,10 +443,11 @@ func (d DB) Batch(events chan store.Event) chan error {
 			select {
 			case evt, ok := <-events:
 				if !ok {
+					// last batch, the error is returned as the input cannot be processed again
 					err := processBatch()
 					if err != nil {
 						log.Errorf("cannot process batch: %v", err)
-						break
+						errChan <- err
 					}
 					break loop
 				}
@@ -352,6 +478,27 @@ func (d DB) Batch(events chan store.Event) chan error {
 	return errChan
 }
 
+// containsMessage returns true when one of the versions comes from the same kafka message as the schedule
+func containsMessage(s schedule.Schedule, v []byte) bool {
+	bs, ok := s.(Schedule)
+	if !ok || bs.Partition == nil || bs.Offset == nil {
+		return false
+	}
+
+	var versions []Schedule
+	if err := json.Unmarshal(v, &versions); err != nil {
+		return false
+	}
+
+	for _, version := range versions {
+		if version.Partition != nil && version.Offset != nil && version.Topic == bs.Topic &&
+			*version.Partition == *bs.Partition && *version.Offset == *bs.Offset {
+			return true
+		}
+	}
+	return false
+}
+
 func addToSlice(s schedule.Schedule, v []byte) ([]byte, error) {
 	var arr []interface{}
 
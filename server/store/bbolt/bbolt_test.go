@@ -2,12 +2,17 @@ package bbolt_test
 
 import (
 	"os"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
 	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store/bbolt"
+	kafka_schedule "github.com/etf1/kafka-message-scheduler/schedule/kafka"
 	simple_schedule "github.com/etf1/kafka-message-scheduler/schedule/simple"
 )
 
@@ -293,3 +298,143 @@ loop:
 		t.Errorf("unexpected result: %v", len(lst))
 	}
 }
+
+func TestBboltStore_kafka_schedule_offset(t *testing.T) {
+	file := helper.GenRandString("db-")
+	defer func() {
+		err := os.Remove(file)
+		if err != nil {
+			t.Errorf("unable to delete db file %v: %v", file, err)
+		}
+	}()
+
+	db, err := bbolt.NewStore(file)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	sch := helper.NewKafkaSchedule("schedules", "schedule-1", "value", time.Now().Unix(), "target-topic", "target-key")
+	sch.TopicPartition.Partition = 2
+	sch.TopicPartition.Offset = 42
+	sch.Message.Timestamp = time.Unix(1623456789, 123*int64(time.Millisecond))
+	sch.Message.TimestampType = confluent.TimestampLogAppendTime
+
+	err = db.Add("scheduler-1", sch)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	lst, err := db.Get("scheduler-1", "schedule-1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(lst) != 1 {
+		t.Fatalf("unexpected result length: %v", len(lst))
+	}
+
+	bsch, ok := lst[0].Schedule.(bbolt.Schedule)
+	if !ok {
+		t.Fatalf("unexpected schedule type: %T", lst[0].Schedule)
+	}
+	if bsch.Partition == nil || *bsch.Partition != 2 || bsch.Offset == nil || *bsch.Offset != 42 {
+		t.Errorf("unexpected partition/offset: %v %v", bsch.Partition, bsch.Offset)
+	}
+	if bsch.TargetTopic != "target-topic" || bsch.TargetKey != "target-key" || string(bsch.Value) != "value" {
+		t.Errorf("unexpected schedule: %+v", bsch)
+	}
+	if bsch.TimestampMs != 1623456789123 || bsch.TimestampType != "log-append-time" {
+		t.Errorf("unexpected timestamp: %v %v", bsch.TimestampMs, bsch.TimestampType)
+	}
+	expectedHeaders := []bbolt.Header{
+		{Key: "scheduler-epoch", Value: strconv.FormatInt(sch.Epoch(), 10)},
+		{Key: "scheduler-target-topic", Value: "target-topic"},
+		{Key: "scheduler-target-key", Value: "target-key"},
+	}
+	if !reflect.DeepEqual(bsch.Headers, expectedHeaders) {
+		t.Errorf("unexpected headers: %v", bsch.Headers)
+	}
+}
+
+func TestBboltStore_decode_error(t *testing.T) {
+	file := helper.GenRandString("db-")
+	defer func() {
+		err := os.Remove(file)
+		if err != nil {
+			t.Errorf("unable to delete db file %v: %v", file, err)
+		}
+	}()
+
+	db, err := bbolt.NewStore(file)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	sch := helper.NewKafkaSchedule("schedules", "schedule-1", "raw", time.Now().Unix(), "target-topic", "target-key")
+
+	err = db.Add("scheduler-1", decoder.Failed{Schedule: sch, Err: "invalid message"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	lst, err := db.Get("scheduler-1", "schedule-1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(lst) != 1 {
+		t.Fatalf("unexpected result length: %v", len(lst))
+	}
+
+	bsch, ok := lst[0].Schedule.(bbolt.Schedule)
+	if !ok {
+		t.Fatalf("unexpected schedule type: %T", lst[0].Schedule)
+	}
+	if bsch.DecodeError != "invalid message" || string(bsch.Value) != "raw" || bsch.TargetKey != "target-key" {
+		t.Errorf("unexpected schedule: %+v", bsch)
+	}
+}
+
+func TestBboltStore_kafka_message_replayed(t *testing.T) {
+	file := helper.GenRandString("db-")
+	defer func() {
+		err := os.Remove(file)
+		if err != nil {
+			t.Errorf("unable to delete db file %v: %v", file, err)
+		}
+	}()
+
+	db, err := bbolt.NewStore(file)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	message := func(offset confluent.Offset, value string) kafka_schedule.Schedule {
+		sch := helper.NewKafkaSchedule("schedules", "schedule-1", value, time.Now().Unix(), "target-topic", "target-key")
+		sch.TopicPartition.Partition = 0
+		sch.TopicPartition.Offset = offset
+		return sch
+	}
+
+	// the messages 10 and 11 are consumed again after a restart
+	for _, sch := range []kafka_schedule.Schedule{message(10, "v1"), message(11, "v2"), message(10, "v1"), message(11, "v2"), message(12, "v3")} {
+		err = db.Add("scheduler-1", sch)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	lst, err := db.Get("scheduler-1", "schedule-1")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	values := []string{}
+	for _, s := range lst {
+		values = append(values, string(s.Schedule.(bbolt.Schedule).Value))
+	}
+	if !reflect.DeepEqual(values, []string{"v3", "v2", "v1"}) {
+		t.Errorf("unexpected versions: %v", values)
+	}
+}
@@ -0,0 +1,171 @@
+package broadcast
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ChanSize = 1000
+)
+
+// Broadcaster fans out the events of a watchable store to multiple consumers,
+// the source is watched when the first consumer registers (or when started).
+// Watchers (Watch) receive all the events, so they have to consume their channel, and acknowledge them: the
+// acknowledgement of an event (Event.Ack) is called once all the watchers acknowledged it. Subscribers (Subscribe)
+// may miss events when they are too slow (used for the http clients), they don't acknowledge the events.
+type Broadcaster struct {
+	source      store.Watchable
+	once        *sync.Once
+	mutex       *sync.RWMutex
+	watchers    []chan store.Event
+	subscribers map[chan store.Event]bool
+	closed      bool
+	// 1 when the source is watched, done is closed when its events are forwarded
+	started *int32
+	done    chan bool
+}
+
+func NewBroadcaster(source store.Watchable) *Broadcaster {
+	return &Broadcaster{
+		source:      source,
+		once:        &sync.Once{},
+		mutex:       &sync.RWMutex{},
+		subscribers: make(map[chan store.Event]bool),
+		started:     new(int32),
+		done:        make(chan bool),
+	}
+}
+
+// Start watches the source store, it is called by the first consumer, so it only
+// needs to be called to discard the events sent before any consumer registers
+func (b *Broadcaster) Start() {
+	b.once.Do(func() {
+		watchChan, err := b.source.Watch()
+		if err != nil {
+			log.Errorf("cannot watch source store: %v", err)
+			return
+		}
+		atomic.StoreInt32(b.started, 1)
+		go b.broadcast(watchChan)
+	})
+}
+
+// Wait waits for the events of the source store to be forwarded, once the source is closed,
+// it returns immediately when the source is not watched
+func (b *Broadcaster) Wait() {
+	if atomic.LoadInt32(b.started) == 1 {
+		<-b.done
+	}
+}
+
+func (b *Broadcaster) broadcast(watchChan chan store.Event) {
+	defer log.Printf("broadcaster closed")
+	defer close(b.done)
+
+	for evt := range watchChan {
+		b.mutex.RLock()
+		ack := evt.Ack
+		evt.Ack = acknowledgement(ack, len(b.watchers))
+		for _, w := range b.watchers {
+			w <- evt
+		}
+		evt.Ack = nil
+		for s := range b.subscribers {
+			select {
+			case s <- evt:
+			default:
+				log.Warnf("subscriber too slow, dropping event: %+v", evt)
+			}
+		}
+		b.mutex.RUnlock()
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	for _, w := range b.watchers {
+		close(w)
+	}
+	for s := range b.subscribers {
+		close(s)
+		delete(b.subscribers, s)
+	}
+}
+
+// acknowledgement returns the acknowledgement of an event by each of the watchers,
+// the event is acknowledged once all of them acknowledged it
+func acknowledgement(ack func(), watchers int) func() {
+	if ack == nil || watchers <= 1 {
+		return ack
+	}
+	remaining := int32(watchers)
+	return func() {
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			ack()
+		}
+	}
+}
+
+// Watch returns a channel receiving all the events of the source store
+func (b *Broadcaster) Watch() (chan store.Event, error) {
+	return b.Watcher().Watch()
+}
+
+// Watcher registers a watcher without watching the source store, the source is watched when the
+// returned watchable is watched. It allows several watchers to receive the events from the first one.
+func (b *Broadcaster) Watcher() store.Watchable {
+	watchChan := make(chan store.Event, ChanSize)
+
+	b.mutex.Lock()
+	if b.closed {
+		close(watchChan)
+	} else {
+		b.watchers = append(b.watchers, watchChan)
+	}
+	b.mutex.Unlock()
+
+	return watcher{b, watchChan}
+}
+
+type watcher struct {
+	b         *Broadcaster
+	watchChan chan store.Event
+}
+
+func (w watcher) Watch() (chan store.Event, error) {
+	w.b.Start()
+	return w.watchChan, nil
+}
+
+// Subscribe returns a channel receiving the events of the source store, events are dropped
+// when the channel is full. The returned function has to be called to unsubscribe.
+func (b *Broadcaster) Subscribe() (chan store.Event, func()) {
+	subChan := make(chan store.Event, ChanSize)
+
+	b.mutex.Lock()
+	if b.closed {
+		close(subChan)
+	} else {
+		b.subscribers[subChan] = true
+	}
+	b.mutex.Unlock()
+
+	b.Start()
+
+	unsubscribe := func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		if b.subscribers[subChan] {
+			delete(b.subscribers, subChan)
+			close(subChan)
+		}
+	}
+
+	return subChan, unsubscribe
+}
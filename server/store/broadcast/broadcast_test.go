@@ -0,0 +1,166 @@
+package broadcast_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/broadcast"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
+	"github.com/etf1/kafka-message-scheduler/schedule/simple"
+)
+
+func receive(t *testing.T, events chan store.Event) store.Event {
+	select {
+	case evt := <-events:
+		return evt
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for event")
+	}
+	return store.Event{}
+}
+
+// Rule #1: watchers and subscribers should all receive the events of the source store
+func TestBroadcaster(t *testing.T) {
+	source := hmap.NewStore()
+	b := broadcast.NewBroadcaster(source)
+
+	w1, err := b.Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w2, err := b.Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s1, unsubscribe := b.Subscribe()
+
+	source.Add("scheduler-1", simple.NewSchedule("schedule-1", time.Now().Unix()))
+
+	for i, events := range []chan store.Event{w1, w2, s1} {
+		evt := receive(t, events)
+		if evt.EventType != store.UpsertType || evt.ID() != "schedule-1" || evt.SchedulerName != "scheduler-1" {
+			t.Errorf("consumer #%v: unexpected event: %+v", i+1, evt)
+		}
+	}
+
+	unsubscribe()
+	if _, ok := <-s1; ok {
+		t.Errorf("subscriber channel should be closed")
+	}
+	// unsubscribe can be called multiple times
+	unsubscribe()
+
+	source.Delete("scheduler-1", simple.NewSchedule("schedule-1", time.Now().Unix()))
+
+	for i, events := range []chan store.Event{w1, w2} {
+		evt := receive(t, events)
+		if evt.EventType != store.DeletedType || evt.ID() != "schedule-1" {
+			t.Errorf("watcher #%v: unexpected event: %+v", i+1, evt)
+		}
+	}
+}
+
+// Rule #2: the watchers registered before the source is watched should all receive the first events
+func TestBroadcaster_Watcher(t *testing.T) {
+	source := hmap.NewStore()
+	b := broadcast.NewBroadcaster(source)
+
+	watcher1, watcher2 := b.Watcher(), b.Watcher()
+
+	source.Add("scheduler-1", simple.NewSchedule("schedule-1", time.Now().Unix()))
+
+	w1, err := watcher1.Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// events are broadcasted as soon as the first watcher is watched
+	time.Sleep(100 * time.Millisecond)
+	w2, err := watcher2.Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, events := range []chan store.Event{w1, w2} {
+		evt := receive(t, events)
+		if evt.EventType != store.UpsertType || evt.ID() != "schedule-1" {
+			t.Errorf("watcher #%v: unexpected event: %+v", i+1, evt)
+		}
+	}
+}
+
+// source is a watchable store closed by the test
+type source chan store.Event
+
+func (s source) Watch() (chan store.Event, error) {
+	return s, nil
+}
+
+// Rule #3: wait should return once the events of the closed source are forwarded to the watchers
+func TestBroadcaster_Wait(t *testing.T) {
+	// not watched
+	broadcast.NewBroadcaster(source(make(chan store.Event))).Wait()
+
+	src := source(make(chan store.Event, 10))
+	b := broadcast.NewBroadcaster(src)
+	w, err := b.Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		src <- store.Event{EventType: store.UpsertType}
+	}
+	close(src)
+
+	waited := make(chan bool)
+	go func() {
+		b.Wait()
+		close(waited)
+	}()
+
+	count := 0
+	for range w {
+		count++
+	}
+	if count != 10 {
+		t.Errorf("unexpected number of events: %v", count)
+	}
+
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for the broadcaster")
+	}
+}
+
+// Rule #4: the events should be acknowledged once all the watchers acknowledged them, the subscribers don't acknowledge them
+func TestBroadcaster_acknowledgement(t *testing.T) {
+	src := source(make(chan store.Event, 1))
+	b := broadcast.NewBroadcaster(src)
+	w1, _ := b.Watch()
+	w2, _ := b.Watch()
+	s1, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	acked := make(chan bool, 1)
+	src <- store.Event{EventType: store.UpsertType, Ack: func() { acked <- true }}
+
+	if evt := receive(t, s1); evt.Ack != nil {
+		t.Errorf("subscriber should not acknowledge the event")
+	}
+
+	receive(t, w1).Acknowledge()
+	select {
+	case <-acked:
+		t.Errorf("event acknowledged by a single watcher")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	receive(t, w2).Acknowledge()
+	select {
+	case <-acked:
+	case <-time.After(5 * time.Second):
+		t.Errorf("event not acknowledged")
+	}
+}
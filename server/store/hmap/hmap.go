@@ -155,12 +155,16 @@ func (h Hmap) Batch(events chan store.Event) chan error {
 				err := h.Add(evt.SchedulerName, evt.Schedule.Schedule)
 				if err != nil {
 					result <- err
+					continue
 				}
+				evt.Acknowledge()
 			case store.DeletedType:
 				err := h.Delete(evt.SchedulerName, evt.Schedule.Schedule)
 				if err != nil {
 					result <- err
+					continue
 				}
+				evt.Acknowledge()
 			case store.StoreResetType:
 				h.Clear()
 			}
@@ -0,0 +1,436 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	OffsetsFileMode = 0666
+	// DefaultCommitInterval is the default period between two commits of the offsets
+	DefaultCommitInterval = 5 * time.Second
+	// timeout of the query of the watermarks of a partition, when resuming a consumer
+	WatermarksTimeoutMs = 5000
+	// period between two checks of the acknowledgements, when closing the store
+	AckPollInterval = 100 * time.Millisecond
+)
+
+var offsetsBucket = []byte("offsets")
+
+// Partition is a partition of a topic
+type Partition struct {
+	Topic     string
+	Partition int32
+}
+
+// Offsets are the next offsets to consume by partition
+type Offsets map[Partition]int64
+
+type partitionOffset struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Offset    int64  `json:"offset"`
+}
+
+// offsetsRecord contains the offsets of a bucket, with the config of its consumer: the offsets are
+// not valid anymore when the bootstrap servers or the topics of the bucket change
+type offsetsRecord struct {
+	BootstrapServers string            `json:"bootstrap-servers"`
+	Topics           []string          `json:"topics"`
+	Offsets          []partitionOffset `json:"offsets"`
+}
+
+// OffsetStore persists the offsets of the buckets consumed by a watchable store, in a bbolt file
+type OffsetStore struct {
+	db *bolt.DB
+}
+
+func NewOffsetStore(path string) (*OffsetStore, error) {
+	db, err := bolt.Open(path, OffsetsFileMode, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &OffsetStore{db: db}, nil
+}
+
+// Get returns the offsets of the bucket, nil when no offsets were committed with the current config of the bucket
+func (s *OffsetStore) Get(bucket Bucket) (Offsets, error) {
+	var record *offsetsRecord
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(offsetsBucket)
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(bucket.Name))
+		if v == nil {
+			return nil
+		}
+		record = &offsetsRecord{}
+		return json.Unmarshal(v, record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot get offsets of %v: %w", bucket.Name, err)
+	}
+
+	if record == nil || record.BootstrapServers != bucket.BootstrapServers || !reflect.DeepEqual(record.Topics, bucket.Topics) {
+		return nil, nil
+	}
+
+	result := make(Offsets, len(record.Offsets))
+	for _, o := range record.Offsets {
+		result[Partition{o.Topic, o.Partition}] = o.Offset
+	}
+	return result, nil
+}
+
+// Put replaces the offsets of the bucket
+func (s *OffsetStore) Put(bucket Bucket, offsets Offsets) error {
+	record := offsetsRecord{
+		BootstrapServers: bucket.BootstrapServers,
+		Topics:           bucket.Topics,
+		Offsets:          make([]partitionOffset, 0, len(offsets)),
+	}
+	for p, o := range offsets {
+		record.Offsets = append(record.Offsets, partitionOffset{p.Topic, p.Partition, o})
+	}
+
+	buf, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(offsetsBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(bucket.Name), buf)
+	})
+}
+
+// Delete removes the offsets of the bucket, its consumer will start from the beginning of the topics
+func (s *OffsetStore) Delete(name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(offsetsBucket)
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+func (s *OffsetStore) Close() {
+	if err := s.db.Close(); err != nil {
+		log.Errorf("cannot close offset store: %v", err)
+	}
+}
+
+// partitionOffsets contains the offsets of the messages of a partition not sent to the watcher yet,
+// and the offset following the last message received
+type partitionOffsets struct {
+	pending map[int64]int
+	next    int64
+}
+
+// committable returns the offset from which the partition can be consumed again without missing an event
+func (po *partitionOffsets) committable() int64 {
+	result := po.next
+	for o := range po.pending {
+		if o < result {
+			result = o
+		}
+	}
+	return result
+}
+
+// tracked contains the offsets of the messages of a consumer
+type tracked struct {
+	bucket     Bucket
+	partitions map[Partition]*partitionOffsets
+}
+
+// committer tracks the offsets of the messages of the consumers until their events are acknowledged by the watcher,
+// once persisted, and periodically commits the acknowledged offsets to the offset store
+type committer struct {
+	offsets  *OffsetStore
+	interval time.Duration
+	mutex    *sync.Mutex
+	// by bucket name, only the current consumer of a bucket is tracked
+	buckets  map[string]*tracked
+	stopChan chan bool
+	exitChan chan bool
+}
+
+func newCommitter(offsets *OffsetStore, interval time.Duration) *committer {
+	if interval <= 0 {
+		interval = DefaultCommitInterval
+	}
+	return &committer{
+		offsets:  offsets,
+		interval: interval,
+		mutex:    &sync.Mutex{},
+		buckets:  make(map[string]*tracked),
+		stopChan: make(chan bool),
+		exitChan: make(chan bool),
+	}
+}
+
+func (c *committer) start() {
+	go func() {
+		defer func() {
+			c.exitChan <- true
+		}()
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.commit(c.snapshot())
+			case <-c.stopChan:
+				c.commit(c.snapshot())
+				return
+			}
+		}
+	}()
+}
+
+func (c *committer) close() {
+	c.stopChan <- true
+	<-c.exitChan
+}
+
+// track starts the tracking of the consumer of the bucket, it replaces the previous consumer of the bucket
+func (c *committer) track(bucket Bucket) *tracked {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &tracked{
+		bucket:     bucket,
+		partitions: make(map[Partition]*partitionOffsets),
+	}
+	c.buckets[bucket.Name] = t
+	return t
+}
+
+// untrack stops the tracking of the consumer of the bucket and removes its offsets,
+// the schedules of the bucket are purged by a reset event
+func (c *committer) untrack(name string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.buckets, name)
+	if err := c.offsets.Delete(name); err != nil {
+		log.Errorf("cannot delete offsets of %v: %v", name, err)
+	}
+}
+
+// rebuild forgets the offsets of the consumer which consumes its topics again from the beginning
+func (c *committer) rebuild(t *tracked) {
+	if c == nil || t == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.buckets[t.bucket.Name] != t {
+		return
+	}
+	t.partitions = make(map[Partition]*partitionOffsets)
+	if err := c.offsets.Delete(t.bucket.Name); err != nil {
+		log.Errorf("cannot delete offsets of %v: %v", t.bucket.Name, err)
+	}
+}
+
+// received records a message of the consumer, its offset is pending until acknowledged
+func (c *committer) received(t *tracked, msg *confluent.Message) {
+	if c == nil || t == nil || msg == nil || msg.TopicPartition.Topic == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.buckets[t.bucket.Name] != t {
+		return
+	}
+	p := Partition{*msg.TopicPartition.Topic, msg.TopicPartition.Partition}
+	po, found := t.partitions[p]
+	if !found {
+		po = &partitionOffsets{pending: make(map[int64]int)}
+		t.partitions[p] = po
+	}
+	offset := int64(msg.TopicPartition.Offset)
+	po.pending[offset]++
+	po.next = offset + 1
+}
+
+// ack returns the acknowledgement of the event of the message, called by the watcher once the event is persisted,
+// nil when the message is not tracked
+func (c *committer) ack(t *tracked, msg *confluent.Message) func() {
+	if c == nil || t == nil || msg == nil || msg.TopicPartition.Topic == nil {
+		return nil
+	}
+
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			c.acknowledged(t, msg)
+		})
+	}
+}
+
+// acknowledged records that the event of the message was persisted by the watcher
+func (c *committer) acknowledged(t *tracked, msg *confluent.Message) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.buckets[t.bucket.Name] != t {
+		return
+	}
+	po, found := t.partitions[Partition{*msg.TopicPartition.Topic, msg.TopicPartition.Partition}]
+	if !found {
+		return
+	}
+	offset := int64(msg.TopicPartition.Offset)
+	po.pending[offset]--
+	if po.pending[offset] <= 0 {
+		delete(po.pending, offset)
+	}
+}
+
+// snapshot returns the committable offsets of the tracked consumers
+func (c *committer) snapshot() map[string]Offsets {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := make(map[string]Offsets, len(c.buckets))
+	for name, t := range c.buckets {
+		offsets := make(Offsets, len(t.partitions))
+		for p, po := range t.partitions {
+			offsets[p] = po.committable()
+		}
+		result[name] = offsets
+	}
+	return result
+}
+
+// commit persists the acknowledged offsets of the snapshot
+func (c *committer) commit(snapshot map[string]Offsets) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for name, offsets := range snapshot {
+		// the bucket may have been untracked since the snapshot
+		t, found := c.buckets[name]
+		if !found || len(offsets) == 0 {
+			continue
+		}
+		if err := c.offsets.Put(t.bucket, offsets); err != nil {
+			log.Errorf("cannot commit offsets of %v: %v", name, err)
+		}
+	}
+}
+
+// pending returns the number of messages of the tracked consumers not acknowledged yet
+func (c *committer) pending() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := 0
+	for _, t := range c.buckets {
+		for _, po := range t.partitions {
+			for _, count := range po.pending {
+				result += count
+			}
+		}
+	}
+	return result
+}
+
+// wait waits for the events sent to the watcher to be acknowledged, until the context is done
+func (c *committer) wait(ctx context.Context) {
+	ticker := time.NewTicker(AckPollInterval)
+	defer ticker.Stop()
+
+	for c.pending() != 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.Warnf("%v events not acknowledged, their offsets are not committed", c.pending())
+			return
+		}
+	}
+}
+
+// resume returns the partitions assigned to the consumer of the tracked bucket, starting from the committed offsets.
+// When the committed offsets are missing or out of range, the partitions are consumed from the beginning and
+// a reset event is sent first, to purge the schedules of the bucket.
+func (c *committer) resume(t *tracked, kc *confluent.Consumer, partitions []confluent.TopicPartition, send func(evt event) bool) []confluent.TopicPartition {
+	offsets, err := c.offsets.Get(t.bucket)
+	if err != nil {
+		log.Errorf("cannot get offsets of %v: %v", t.bucket.Name, err)
+	}
+
+	result := make([]confluent.TopicPartition, len(partitions))
+	rebuild := offsets == nil
+
+	for i, tp := range partitions {
+		result[i] = tp
+		result[i].Offset = confluent.OffsetBeginning
+		if rebuild || tp.Topic == nil {
+			continue
+		}
+
+		offset, found := offsets[Partition{*tp.Topic, tp.Partition}]
+		if !found {
+			// nothing consumed yet in the partition
+			continue
+		}
+
+		low, high, err := kc.QueryWatermarkOffsets(*tp.Topic, tp.Partition, WatermarksTimeoutMs)
+		if err != nil || offset < low || offset > high {
+			log.Warnf("offset %v of %v[%v] out of range [%v, %v] (%v), rebuilding %v", offset, *tp.Topic, tp.Partition, low, high, err, t.bucket.Name)
+			rebuild = true
+			continue
+		}
+		result[i].Offset = confluent.Offset(offset)
+	}
+
+	if rebuild {
+		for i := range result {
+			result[i].Offset = confluent.OffsetBeginning
+		}
+		c.rebuild(t)
+		send(event{
+			storeResetType,
+			t.bucket.Name,
+			nil,
+			nil,
+			nil,
+		})
+	}
+
+	log.Printf("resuming %v from %v", t.bucket.Name, result)
+	return result
+}
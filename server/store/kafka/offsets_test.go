@@ -0,0 +1,60 @@
+package kafka_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+)
+
+// Rule #1: the offsets should be returned only for the config of the bucket they were committed with
+func TestOffsetStore(t *testing.T) {
+	offsets, err := kafka.NewOffsetStore(filepath.Join(t.TempDir(), "schedules.offsets"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer offsets.Close()
+
+	bucket := kafka.Bucket{Name: "scheduler-1", BootstrapServers: "localhost:9092", Topics: []string{"schedules"}}
+	committed := kafka.Offsets{
+		{Topic: "schedules", Partition: 0}: 10,
+		{Topic: "schedules", Partition: 1}: 42,
+	}
+
+	err = offsets.Put(bucket, committed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		bucket   kafka.Bucket
+		expected kafka.Offsets
+	}{
+		{bucket, committed},
+		{kafka.Bucket{Name: "scheduler-1", BootstrapServers: "other:9092", Topics: []string{"schedules"}}, nil},
+		{kafka.Bucket{Name: "scheduler-1", BootstrapServers: "localhost:9092", Topics: []string{"schedules", "other"}}, nil},
+		{kafka.Bucket{Name: "scheduler-2", BootstrapServers: "localhost:9092", Topics: []string{"schedules"}}, nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result, err := offsets.Get(tt.bucket)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("unexpected offsets: %v", result)
+			}
+		})
+	}
+
+	err = offsets.Delete(bucket.Name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result, err := offsets.Get(bucket); err != nil || result != nil {
+		t.Errorf("unexpected offsets after delete: %v %v", result, err)
+	}
+}
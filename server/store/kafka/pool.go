@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// pool runs the tasks on a fixed number of workers, the tasks with the same key are run
+// by the same worker, in the order of submission
+type pool struct {
+	workers []chan func()
+	wg      *sync.WaitGroup
+}
+
+func newPool(size int) pool {
+	if size <= 0 {
+		size = 1
+	}
+
+	p := pool{
+		workers: make([]chan func(), size),
+		wg:      &sync.WaitGroup{},
+	}
+
+	p.wg.Add(size)
+	for i := range p.workers {
+		tasks := make(chan func(), ChanSize/size+1)
+		p.workers[i] = tasks
+		go func() {
+			defer p.wg.Done()
+			for task := range tasks {
+				task()
+			}
+		}()
+	}
+
+	return p
+}
+
+// submit runs the task after the tasks previously submitted with the same key
+func (p pool) submit(key string, task func()) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	p.workers[h.Sum32()%uint32(len(p.workers))] <- task
+}
+
+// barrier runs the task after all the tasks previously submitted,
+// the tasks submitted afterwards are run after it
+func (p pool) barrier(task func()) {
+	arrived := &sync.WaitGroup{}
+	arrived.Add(len(p.workers))
+	release := make(chan bool)
+
+	for _, w := range p.workers {
+		w <- func() {
+			arrived.Done()
+			<-release
+		}
+	}
+
+	arrived.Wait()
+	task()
+	close(release)
+}
+
+// close waits for the submitted tasks
+func (p pool) close() {
+	for _, w := range p.workers {
+		close(w)
+	}
+	p.wg.Wait()
+}
@@ -0,0 +1,246 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ProgressRetryInterval is the period between two attempts to capture the high watermarks of a bucket
+	ProgressRetryInterval = 5 * time.Second
+)
+
+// Progress is the loading progress of a bucket: the offsets consumed up to the high watermarks of its partitions,
+// captured when its consumer started. The bucket is ready when each partition reached its initial high watermark
+// and the events of its messages were sent to the watcher.
+type Progress struct {
+	Ready    bool  `json:"ready"`
+	Consumed int64 `json:"consumed"`
+	Total    int64 `json:"total"`
+}
+
+type partitionProgress struct {
+	low      int64
+	high     int64
+	position int64
+	// the end of the partition was reached, the offsets of a compacted topic may have gaps up to the high watermark
+	eof bool
+}
+
+func (pp *partitionProgress) loaded() bool {
+	return pp.eof || pp.position >= pp.high
+}
+
+// progress tracks the loading of the bucket of a consumer
+type progress struct {
+	mutex      *sync.Mutex
+	partitions map[Partition]*partitionProgress
+	// the high watermarks were captured
+	captured bool
+	// offsets of the ends of the partitions reached before the capture, by partition
+	ends map[Partition]int64
+	// all the partitions reached their high watermarks
+	loaded bool
+	// the events of the messages up to the high watermarks were sent to the watcher
+	ready bool
+}
+
+func newProgress() *progress {
+	return &progress{
+		mutex:      &sync.Mutex{},
+		partitions: make(map[Partition]*partitionProgress),
+		ends:       make(map[Partition]int64),
+	}
+}
+
+// capture queries the high watermarks of the partitions of the topics, the current positions of the consumer
+// are kept when messages were consumed before. It returns true when the bucket is loaded.
+func (p *progress) capture(kc *confluent.Consumer, topics []string) (bool, error) {
+	partitions := make(map[Partition]*partitionProgress)
+
+	for _, topic := range topics {
+		topic := topic
+		md, err := kc.GetMetadata(&topic, false, WatermarksTimeoutMs)
+		if err != nil {
+			return false, err
+		}
+		tm, found := md.Topics[topic]
+		if !found || tm.Error.Code() == confluent.ErrUnknownTopicOrPart {
+			// nothing to load
+			continue
+		}
+		if tm.Error.Code() != confluent.ErrNoError {
+			return false, fmt.Errorf("cannot get metadata of %v: %v", topic, tm.Error)
+		}
+
+		for _, pm := range tm.Partitions {
+			low, high, err := kc.QueryWatermarkOffsets(topic, pm.ID, WatermarksTimeoutMs)
+			if err != nil {
+				return false, err
+			}
+			partitions[Partition{topic, pm.ID}] = &partitionProgress{low: low, high: high, position: low}
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for key, pp := range p.partitions {
+		if current, found := partitions[key]; found && pp.position > current.position {
+			current.position = pp.position
+		}
+	}
+	// an end reached before the capture is the end of the partition when it is not before the high watermark
+	for key, end := range p.ends {
+		if current, found := partitions[key]; found && end >= current.high {
+			current.eof = true
+		}
+	}
+	p.partitions = partitions
+	p.captured = true
+
+	return p.update(), nil
+}
+
+// consumed records the offset of a message, it returns true when the bucket becomes loaded
+func (p *progress) consumed(msg *confluent.Message) bool {
+	if p == nil || msg.TopicPartition.Topic == nil {
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := Partition{*msg.TopicPartition.Topic, msg.TopicPartition.Partition}
+	pp, found := p.partitions[key]
+	if !found {
+		// not captured yet
+		pp = &partitionProgress{}
+		p.partitions[key] = pp
+	}
+	pp.position = int64(msg.TopicPartition.Offset) + 1
+
+	return p.update()
+}
+
+// reachedEnd records the end of a partition, it returns true when the bucket becomes loaded
+func (p *progress) reachedEnd(tp confluent.TopicPartition) bool {
+	if p == nil || tp.Topic == nil {
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := Partition{*tp.Topic, tp.Partition}
+	if !p.captured {
+		// the end may be before the high watermark, it is compared to it by the capture
+		p.ends[key] = int64(tp.Offset)
+		return false
+	}
+	pp, found := p.partitions[key]
+	if !found {
+		return false
+	}
+	pp.eof = true
+
+	return p.update()
+}
+
+// update returns true when the bucket becomes loaded
+func (p *progress) update() bool {
+	if p.loaded || !p.captured {
+		return false
+	}
+	for _, pp := range p.partitions {
+		if !pp.loaded() {
+			return false
+		}
+	}
+	p.loaded = true
+	return true
+}
+
+func (p *progress) setReady() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.ready = true
+}
+
+func (p *progress) get() Progress {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	result := Progress{Ready: p.ready}
+	if !p.captured {
+		return result
+	}
+	for _, pp := range p.partitions {
+		result.Total += pp.high - pp.low
+		switch {
+		case pp.loaded():
+			result.Consumed += pp.high - pp.low
+		case pp.position > pp.low:
+			result.Consumed += pp.position - pp.low
+		}
+	}
+	return result
+}
+
+// loading contains the progress of the buckets of a watchable store, by bucket name
+type loading struct {
+	mutex   *sync.RWMutex
+	buckets map[string]*progress
+}
+
+func newLoading() *loading {
+	return &loading{
+		mutex:   &sync.RWMutex{},
+		buckets: make(map[string]*progress),
+	}
+}
+
+func (l *loading) add(name string) *progress {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	p := newProgress()
+	l.buckets[name] = p
+	return p
+}
+
+func (l *loading) remove(name string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.buckets, name)
+}
+
+func (l *loading) ready() bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	for name, p := range l.buckets {
+		if !p.get().Ready {
+			log.Debugf("bucket %v not ready", name)
+			return false
+		}
+	}
+	return true
+}
+
+func (l *loading) progress(name string) (Progress, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	p, found := l.buckets[name]
+	if !found {
+		return Progress{}, false
+	}
+	return p.get(), true
+}
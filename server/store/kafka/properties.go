@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	log "github.com/sirupsen/logrus"
+)
+
+// SecretFileSuffix is the suffix of the properties whose value is the path of a file containing the actual value,
+// for example sasl.password.file=/run/secrets/kafka-password sets sasl.password
+const SecretFileSuffix = ".file"
+
+// properties required by the consumers and the producers, they cannot be overridden
+var reservedProperties = map[string]bool{
+	"bootstrap.servers":    true,
+	"group.id":             true,
+	"enable.auto.commit":   true,
+	"enable.partition.eof": true,
+	"auto.offset.reset":    true,
+}
+
+// Properties are the kafka client properties of a consumer or a producer (security.protocol, sasl.mechanisms, ssl.ca.location, client.id ...)
+type Properties map[string]string
+
+// LoadProperties reads a properties file, with a property per line and the format key=value,
+// the lines starting with # or ! are comments. The secrets are read from their files.
+func LoadProperties(path string) (Properties, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open properties file %v: %w", path, err)
+	}
+	defer f.Close()
+
+	result := Properties{}
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "!") {
+			continue
+		}
+
+		i := strings.Index(text, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%v:%v: invalid property, expected key=value", path, line)
+		}
+		key, value := strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+
+		if strings.HasSuffix(key, SecretFileSuffix) {
+			key = strings.TrimSuffix(key, SecretFileSuffix)
+			secret, err := os.ReadFile(value)
+			if err != nil {
+				return nil, fmt.Errorf("%v:%v: cannot read %v: %w", path, line, key, err)
+			}
+			value = strings.TrimSpace(string(secret))
+		}
+
+		result[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read properties file %v: %w", path, err)
+	}
+
+	return result, nil
+}
+
+// Merge returns the properties overridden by the others
+func (p Properties) Merge(others ...Properties) Properties {
+	result := Properties{}
+	for k, v := range p {
+		result[k] = v
+	}
+	for _, o := range others {
+		for k, v := range o {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Keys returns the sorted names of the properties, to log them without their values
+func (p Properties) Keys() []string {
+	result := make([]string, 0, len(p))
+	for k := range p {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// String returns the names of the properties without their values, which may be secrets
+func (p Properties) String() string {
+	keys := p.Keys()
+	for i, k := range keys {
+		keys[i] = k + ":***"
+	}
+	return "map[" + strings.Join(keys, " ") + "]"
+}
+
+// GoString is the redacted format of %#v
+func (p Properties) GoString() string {
+	return "kafka.Properties" + p.String()
+}
+
+// Apply sets the properties in the config map of a client, except the reserved ones
+func (p Properties) Apply(cm *confluent.ConfigMap) error {
+	for _, k := range p.Keys() {
+		if reservedProperties[k] {
+			log.Warnf("kafka property %v is set by the client, ignored", k)
+			continue
+		}
+		if err := cm.SetKey(k, p[k]); err != nil {
+			return fmt.Errorf("cannot set kafka property %v: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// ClientProperties are the default properties of the consumers and the producers, overridden by the properties of the buckets
+// (the schedulers)
+type ClientProperties struct {
+	Default Properties
+	// by bucket name
+	Buckets map[string]Properties
+}
+
+// Get returns the properties of the clients of the bucket
+func (cp ClientProperties) Get(name string) Properties {
+	return cp.Default.Merge(cp.Buckets[name])
+}
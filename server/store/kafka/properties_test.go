@@ -0,0 +1,142 @@
+package kafka_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/etf1/kafka-message-scheduler-admin/server/store/kafka"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+// Rule #1: the properties should be loaded from the file, with the secrets read from their files
+func TestLoadProperties(t *testing.T) {
+	dir := t.TempDir()
+	password := writeFile(t, dir, "password", "s3cr3t\n")
+
+	tests := []struct {
+		content     string
+		expected    kafka.Properties
+		expectedErr bool
+	}{
+		{`
+# security
+security.protocol=SASL_SSL
+sasl.mechanisms = SCRAM-SHA-512
+sasl.username=admin
+! secret
+sasl.password.file=` + password + `
+ssl.ca.location=/etc/kafka/ca.pem
+client.id=kafka-message-scheduler-admin
+`, kafka.Properties{
+			"security.protocol": "SASL_SSL",
+			"sasl.mechanisms":   "SCRAM-SHA-512",
+			"sasl.username":     "admin",
+			"sasl.password":     "s3cr3t",
+			"ssl.ca.location":   "/etc/kafka/ca.pem",
+			"client.id":         "kafka-message-scheduler-admin",
+		}, false},
+		{"", kafka.Properties{}, false},
+		{"security.protocol", nil, true},
+		{"sasl.password.file=" + filepath.Join(dir, "missing"), nil, true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			path := writeFile(t, dir, fmt.Sprintf("kafka-%v.properties", i+1), tt.content)
+
+			result, err := kafka.LoadProperties(path)
+			if tt.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("unexpected properties: %v", result)
+			}
+		})
+	}
+}
+
+// Rule #2: the properties of a bucket should override the default ones
+func TestClientProperties_Get(t *testing.T) {
+	cp := kafka.ClientProperties{
+		Default: kafka.Properties{"security.protocol": "SSL", "client.id": "admin"},
+		Buckets: map[string]kafka.Properties{
+			"scheduler-1": {"security.protocol": "SASL_SSL", "sasl.mechanisms": "PLAIN"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		expected kafka.Properties
+	}{
+		{"scheduler-1", kafka.Properties{"security.protocol": "SASL_SSL", "sasl.mechanisms": "PLAIN", "client.id": "admin"}},
+		{"scheduler-2", kafka.Properties{"security.protocol": "SSL", "client.id": "admin"}},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			if result := cp.Get(tt.name); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("unexpected properties: %v", result)
+			}
+		})
+	}
+}
+
+// Rule #3: the values of the properties should not be printed with the bucket
+func TestProperties_String(t *testing.T) {
+	bucket := kafka.Bucket{
+		Name:             "scheduler-1",
+		BootstrapServers: "localhost:9092",
+		Topics:           []string{"schedules"},
+		Properties:       kafka.Properties{"sasl.username": "admin", "sasl.password": "s3cr3t"},
+	}
+
+	for i, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			result := fmt.Sprintf(format, bucket)
+			if strings.Contains(result, "s3cr3t") || strings.Contains(result, "admin") {
+				t.Errorf("unexpected secret in %v", result)
+			}
+			if !strings.Contains(result, "sasl.password") {
+				t.Errorf("unexpected format: %v", result)
+			}
+		})
+	}
+}
+
+// Rule #4: the properties should be applied to the config map of a client, except the reserved ones
+func TestProperties_Apply(t *testing.T) {
+	cm := &confluent.ConfigMap{
+		"bootstrap.servers": "localhost:9092",
+	}
+	props := kafka.Properties{
+		"security.protocol": "SASL_SSL",
+		"sasl.mechanisms":   "PLAIN",
+		"bootstrap.servers": "other:9092",
+		"group.id":          "group",
+	}
+
+	if err := props.Apply(cm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &confluent.ConfigMap{
+		"bootstrap.servers": "localhost:9092",
+		"security.protocol": "SASL_SSL",
+		"sasl.mechanisms":   "PLAIN",
+	}
+	if !reflect.DeepEqual(cm, expected) {
+		t.Errorf("unexpected config map: %v", cm)
+	}
+}
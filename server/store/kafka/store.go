@@ -1,10 +1,16 @@
 package kafka
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	confluent "github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
+	"github.com/etf1/kafka-message-scheduler-admin/server/metrics"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store/hmap"
 	"github.com/etf1/kafka-message-scheduler/schedule"
@@ -15,6 +21,8 @@ import (
 var (
 	PolltimeoutMs = 100
 	ChanSize      = 10000
+	// DecoderWorkers is the number of goroutines decoding the messages of a watchable store
+	DecoderWorkers = 8
 )
 
 type eventType int
@@ -22,12 +30,18 @@ type eventType int
 const (
 	messageType eventType = iota
 	storeResetType
+	// the messages of the bucket up to the initial high watermarks were consumed
+	loadedType
 )
 
 type event struct {
 	evtType eventType
 	name    string
 	*confluent.Message
+	// offsets of the consumer of the message, nil when the offsets are not persisted
+	tracked *tracked
+	// progress of the consumer of a loaded event
+	progress *progress
 }
 
 type consumer struct {
@@ -35,19 +49,37 @@ type consumer struct {
 	consumer         *confluent.Consumer
 	bootstrapServers string
 	topics           []string
-	stopChan         chan bool
-	exitChan         chan bool
+	// the messages are sent to the events channel, unless the context of the store is done
+	events chan event
+	ctx    context.Context
+	// closed to stop the consumer, the message polled before is sent
+	stopChan chan bool
+	// receives the error of the close of the kafka consumer, when its goroutine exits
+	done chan error
+	// committer and offsets of the consumer, nil when the offsets are not persisted
+	committer *committer
+	tracked   *tracked
+	// loading progress of the consumer, nil when not tracked
+	progress *progress
 }
 
-func newConsumer(name, bootstrapServers string, topics []string) (consumer, error) {
-	log.Printf("new consumer topics=%v bootstrapServers=%v", topics, bootstrapServers)
-	kafkaConsumer, err := confluent.NewConsumer(&confluent.ConfigMap{
-		"bootstrap.servers":  bootstrapServers,
-		"group.id":           helper.GenRandString("kafka-store-"),
-		"session.timeout.ms": 6000,
-		"enable.auto.commit": false,
-		"auto.offset.reset":  "earliest",
-	})
+func newConsumer(name, bootstrapServers string, topics []string, properties Properties) (consumer, error) {
+	log.Printf("new consumer topics=%v bootstrapServers=%v properties=%v", topics, bootstrapServers, properties.Keys())
+	cm := &confluent.ConfigMap{
+		"bootstrap.servers":    bootstrapServers,
+		"group.id":             helper.GenRandString("kafka-store-"),
+		"session.timeout.ms":   6000,
+		"enable.auto.commit":   false,
+		"auto.offset.reset":    "earliest",
+		"enable.partition.eof": true,
+	}
+	// the properties override the defaults, except the ones required by the store
+	err := properties.Apply(cm)
+	if err != nil {
+		return consumer{}, err
+	}
+
+	kafkaConsumer, err := confluent.NewConsumer(cm)
 	if err != nil {
 		return consumer{}, err
 	}
@@ -57,12 +89,27 @@ func newConsumer(name, bootstrapServers string, topics []string) (consumer, erro
 		kafkaConsumer,
 		bootstrapServers,
 		topics,
-		make(chan bool, 1),
-		make(chan bool, 1),
+		nil,
+		nil,
+		make(chan bool),
+		make(chan error, 1),
+		nil,
+		nil,
+		nil,
 	}, nil
 }
 
-func (c consumer) processMessage(events chan event) {
+// send sends the event to the processor, it returns false when the store is aborted
+func (c consumer) send(evt event) bool {
+	select {
+	case c.events <- evt:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+func (c consumer) processMessage() {
 	e := c.consumer.Poll(PolltimeoutMs)
 
 	if e == nil {
@@ -70,10 +117,20 @@ func (c consumer) processMessage(events chan event) {
 	}
 	switch evt := e.(type) {
 	case *confluent.Message:
-		events <- event{
+		c.updateMetrics(evt)
+		sent := c.send(event{
 			messageType,
 			c.name,
 			evt,
+			c.tracked,
+			nil,
+		})
+		if sent && c.progress.consumed(evt) {
+			c.loaded()
+		}
+	case confluent.PartitionEOF:
+		if c.progress.reachedEnd(confluent.TopicPartition(evt)) {
+			c.loaded()
 		}
 	case confluent.Error:
 		log.Errorf("received kakfa error: %v", evt)
@@ -82,101 +139,217 @@ func (c consumer) processMessage(events chan event) {
 	}
 }
 
-func (c consumer) start(events chan event) error {
-	err := c.consumer.SubscribeTopics(c.topics, nil)
+func (c consumer) updateMetrics(msg *confluent.Message) {
+	if msg.TopicPartition.Topic == nil {
+		return
+	}
+	topic := *msg.TopicPartition.Topic
+	partition := msg.TopicPartition.Partition
+
+	metrics.ConsumedMessages.WithLabelValues(c.name, topic).Inc()
+
+	// cached high watermark, updated on each fetch response
+	_, high, err := c.consumer.GetWatermarkOffsets(topic, partition)
+	if err != nil || high < 0 {
+		return
+	}
+	lag := high - int64(msg.TopicPartition.Offset) - 1
+	if lag < 0 {
+		lag = 0
+	}
+	metrics.ConsumerLag.WithLabelValues(c.name, topic, strconv.Itoa(int(partition))).Set(float64(lag))
+}
+
+// loaded sends the loaded event of the bucket, after its messages up to the initial high watermarks
+func (c consumer) loaded() {
+	log.Printf("consumer %v loaded", c.name)
+	c.send(event{
+		loadedType,
+		c.name,
+		nil,
+		nil,
+		c.progress,
+	})
+}
+
+// captureProgress captures the high watermarks of the topics, it returns false when it should be retried
+func (c consumer) captureProgress() bool {
+	loaded, err := c.progress.capture(c.consumer, c.topics)
+	if err != nil {
+		log.Errorf("cannot capture high watermarks of %v: %v", c.name, err)
+		return false
+	}
+	if loaded {
+		c.loaded()
+	}
+	return true
+}
+
+// rebalance assigns the partitions from the committed offsets of the consumer
+func (c consumer) rebalance() confluent.RebalanceCb {
+	return func(kc *confluent.Consumer, e confluent.Event) error {
+		switch evt := e.(type) {
+		case confluent.AssignedPartitions:
+			return kc.Assign(c.committer.resume(c.tracked, kc, evt.Partitions, c.send))
+		case confluent.RevokedPartitions:
+			return kc.Unassign()
+		}
+		return nil
+	}
+}
+
+// start subscribes to the topics and consumes them until the consumer is closed or the context is done,
+// the messages are sent to the events channel
+func (c *consumer) start(ctx context.Context, events chan event) error {
+	c.ctx = ctx
+	c.events = events
+
+	var rebalanceCb confluent.RebalanceCb
+	if c.tracked != nil {
+		rebalanceCb = c.rebalance()
+	}
+
+	err := c.consumer.SubscribeTopics(c.topics, rebalanceCb)
 	if err != nil {
+		helper.LogErr(c.consumer.Close())
 		return err
 	}
 
-	go func() {
-		defer func() {
-			c.consumer.Close()
-			c.exitChan <- true
-			log.Printf("consumer closed: %+v", c)
-		}()
+	go c.run()
 
-		for {
-			select {
-			case <-c.stopChan:
-				log.Printf("closing consumer")
-				return
-			default:
-				c.processMessage(events)
-			}
-		}
+	return nil
+}
+
+func (c consumer) run() {
+	defer func() {
+		c.done <- c.consumer.Close()
+		close(c.done)
+		log.Printf("consumer closed: %v", c.name)
 	}()
 
-	return nil
+	captured := c.progress == nil || c.captureProgress()
+	lastCapture := time.Now()
+
+	for {
+		select {
+		case <-c.stopChan:
+			log.Printf("closing consumer %v", c.name)
+			return
+		case <-c.ctx.Done():
+			log.Printf("aborting consumer %v", c.name)
+			return
+		default:
+			c.processMessage()
+			if !captured && time.Since(lastCapture) > ProgressRetryInterval {
+				captured = c.captureProgress()
+				lastCapture = time.Now()
+			}
+		}
+	}
 }
 
-func (c consumer) close() {
-	c.stopChan <- true
-	<-c.exitChan
+// close stops the consumer and waits for its goroutine, its messages are sent before it returns
+func (c consumer) close() error {
+	close(c.stopChan)
+	return <-c.done
 }
 
+// processor applies the action to the events of the consumers and forwards them to the watcher,
+// it stops when its input is closed, once the events are forwarded
 type processor struct {
 	processChan   chan event
 	processedChan chan event
 	action        func(evt event) error
-	stopChan      chan bool
-	exitChan      chan bool
+	// the events are discarded when the context is done
+	ctx  context.Context
+	done chan bool
+	// 1 when the processed events are delivered to a watcher
+	watched  *int32
+	watchers *sync.WaitGroup
 }
 
-func newProcessor(action func(evt event) error) processor {
+func newProcessor(ctx context.Context, action func(evt event) error) processor {
 	return processor{
 		processChan:   make(chan event, ChanSize),
 		processedChan: make(chan event, ChanSize),
 		action:        action,
-		stopChan:      make(chan bool),
-		exitChan:      make(chan bool),
+		ctx:           ctx,
+		done:          make(chan bool),
+		watched:       new(int32),
+		watchers:      &sync.WaitGroup{},
 	}
 }
 
+// close waits for the events sent before to be processed and delivered, the consumers have to be closed first.
+// The events are discarded when nothing watches them.
 func (p processor) close() {
-	p.stopChan <- true
-	<-p.exitChan
+	close(p.processChan)
+	if atomic.LoadInt32(p.watched) == 0 {
+		for range p.processedChan {
+		}
+	}
+	<-p.done
+	p.watchers.Wait()
 	log.Printf("after processor close")
 }
 
 func (p processor) start() {
 	go func() {
 		defer func() {
-			p.exitChan <- true
+			close(p.processedChan)
+			close(p.done)
 			log.Printf("processor closed")
 		}()
 
-		for {
-			select {
-			case <-p.stopChan:
-				log.Printf("processor stopChan called")
-				close(p.processChan)
-			case msg, ok := <-p.processChan:
-				if !ok {
-					close(p.processedChan)
-					return
+		for msg := range p.processChan {
+			if p.action != nil {
+				err := p.action(msg)
+				if err != nil {
+					log.Errorf("cannot process event %v : %v", msg, err)
+					continue
 				}
-				if p.action != nil {
-					err := p.action(msg)
-					if err != nil {
-						log.Errorf("cannot process event %v : %v", msg, err)
-						break
-					}
-				}
-				p.processedChan <- msg
+			}
+			select {
+			case p.processedChan <- msg:
+			case <-p.ctx.Done():
+				// aborted, the remaining events are discarded
 			}
 		}
 	}()
 }
 
+// watch runs the delivery of the processed events in a goroutine, close waits for it
+func (p processor) watch(deliver func(events chan event)) {
+	atomic.StoreInt32(p.watched, 1)
+	p.watchers.Add(1)
+	go func() {
+		defer p.watchers.Done()
+		deliver(p.processedChan)
+	}()
+}
+
+// deliver sends the event to the watcher, unless the store is aborted
+func (p processor) deliver(resultChan chan store.Event, evt store.Event) bool {
+	select {
+	case resultChan <- evt:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
 type Bucket struct {
 	Name             string
 	BootstrapServers string
 	Topics           []string
+	// kafka client properties of the consumer (security, client id, fetch sizes ...), read when the consumer starts
+	Properties Properties
 }
 
 type Store struct {
 	consumers map[string]consumer
 	data      store.MutableStore
+	cancel    context.CancelFunc
 	processor
 }
 
@@ -189,25 +362,27 @@ func NewStore(buckets []Bucket) (Store, error) {
 		})
 	}
 
-	p := newProcessor(action)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := newProcessor(ctx, action)
 	p.start()
 
 	s := Store{
 		consumers: make(map[string]consumer),
 		data:      ms,
+		cancel:    cancel,
 		processor: p,
 	}
 
 	for _, bucket := range buckets {
-		c, err := newConsumer(bucket.Name, bucket.BootstrapServers, bucket.Topics)
+		c, err := newConsumer(bucket.Name, bucket.BootstrapServers, bucket.Topics, bucket.Properties)
 		if err != nil {
-			log.Errorf("cannot create kafka consumer for %+v: %v", bucket, err)
+			log.Errorf("cannot create kafka consumer for %v: %v", bucket.Name, err)
 			continue
 		}
 
-		err = c.start(p.processChan)
+		err = c.start(ctx, p.processChan)
 		if err != nil {
-			log.Errorf("cannot start kafka consumer for %+v: %v", bucket, err)
+			log.Errorf("cannot start kafka consumer for %v: %v", bucket.Name, err)
 			continue
 		}
 
@@ -217,18 +392,30 @@ func NewStore(buckets []Bucket) (Store, error) {
 	return s, nil
 }
 
-func (s Store) Close() {
+// Close stops the consumers, and waits for their messages to be stored and sent to the watcher
+func (s Store) Close() error {
 	defer log.Printf("kafka store closed")
 
 	log.Printf("closing kafka store ...")
-	for _, c := range s.consumers {
-		c.close()
-	}
+	err := closeConsumers(s.consumers)
+	s.processor.close()
+	s.cancel()
 
-	// wait for consumer Poll timeout, otherwise we will get "panic: send on closed channel"
-	time.Sleep(1 * time.Second)
+	return err
+}
 
-	s.processor.close()
+// closeConsumers closes the consumers, it returns the first error
+func closeConsumers(consumers map[string]consumer) error {
+	var result error
+	for name, c := range consumers {
+		if err := c.close(); err != nil {
+			log.Errorf("cannot close consumer %v: %v", name, err)
+			if result == nil {
+				result = fmt.Errorf("cannot close consumer %v: %w", name, err)
+			}
+		}
+	}
+	return result
 }
 
 func (s Store) Get(schedulerName, scheduleID string) ([]store.Schedule, error) {
@@ -243,17 +430,20 @@ func (s Store) Add(schedulerName string, ss ...schedule.Schedule) error {
 	return s.data.Add(schedulerName, ss...)
 }
 
+// Watch returns the events of the consumed messages, the channel is closed when the store is closed
 func (s Store) Watch() (chan store.Event, error) {
 	resultChan := make(chan store.Event, ChanSize)
 
-	go func() {
-		for e := range s.processedChan {
+	s.processor.watch(func(events chan event) {
+		defer close(resultChan)
+
+		for e := range events {
 			eventType := store.UpsertType
 			if len(e.Value) == 0 {
 				eventType = store.DeletedType
 			}
 
-			resultChan <- store.Event{
+			s.deliver(resultChan, store.Event{
 				EventType: eventType,
 				Schedule: store.Schedule{
 					SchedulerName: e.name,
@@ -261,9 +451,9 @@ func (s Store) Watch() (chan store.Event, error) {
 						Message: e.Message,
 					},
 				},
-			}
+			})
 		}
-	}()
+	})
 
 	return resultChan, nil
 }
@@ -30,8 +30,8 @@ func TestKafkaStore_Get(t *testing.T) {
 	}
 
 	kstore, err := kafka.NewStore([]kafka.Bucket{
-		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}},
-		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}},
+		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil},
+		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}, nil},
 	})
 	if err != nil {
 		t.Errorf("failed to create kafka store: %v\n", err)
@@ -99,7 +99,7 @@ func TestKafkaStore_Get_many(t *testing.T) {
 	}
 
 	kstore, err := kafka.NewStore([]kafka.Bucket{
-		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}},
+		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil},
 	})
 	if err != nil {
 		t.Errorf("failed to create kafka store: %v\n", err)
@@ -153,8 +153,8 @@ func TestKafkaStore_List(t *testing.T) {
 	}
 
 	kstore, err := kafka.NewStore([]kafka.Bucket{
-		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}},
-		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}},
+		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil},
+		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}, nil},
 	})
 	if err != nil {
 		t.Errorf("failed to create kafka store: %v\n", err)
@@ -227,8 +227,8 @@ func TestKafkaStore_Watch(t *testing.T) {
 	}
 
 	kstore, err := kafka.NewStore([]kafka.Bucket{
-		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}},
-		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}},
+		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil},
+		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}, nil},
 	})
 	if err != nil {
 		t.Errorf("failed to create kafka store: %v\n", err)
@@ -1,11 +1,12 @@
 package kafka
 
 import (
-	"fmt"
+	"context"
 	"reflect"
 	"time"
 
 	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
+	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler/schedule"
 	"github.com/etf1/kafka-message-scheduler/schedule/kafka"
@@ -15,29 +16,46 @@ import (
 type WatchableStore struct {
 	consumers map[string]consumer
 	dec       decoder.Decoder
+	// nil when the offsets are not persisted
+	committer *committer
+	loading   *loading
+	// the pending events are discarded when the context is done
+	ctx    context.Context
+	cancel context.CancelFunc
 	processor
 }
 
 func NewWatchableStore(dec decoder.Decoder, buckets ...Bucket) (*WatchableStore, error) {
-	p := newProcessor(nil)
+	return NewResumableWatchableStore(context.Background(), dec, nil, 0, buckets...)
+}
+
+// NewResumableWatchableStore returns a watchable store committing the offsets of its consumers to the offset store
+// every interval, the consumers resume from them. Only the offsets of the events acknowledged by the watcher (Event.Ack)
+// are committed, the watcher acknowledges an event once persisted. The buckets without valid offsets are consumed from
+// the beginning after a reset event. When the context is done, the consumers stop and the pending events are discarded.
+func NewResumableWatchableStore(ctx context.Context, dec decoder.Decoder, offsets *OffsetStore, interval time.Duration, buckets ...Bucket) (*WatchableStore, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	p := newProcessor(ctx, nil)
 	p.start()
 
 	ws := WatchableStore{
 		consumers: make(map[string]consumer),
 		processor: p,
 		dec:       dec,
+		loading:   newLoading(),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
-	for _, bucket := range buckets {
-		c, err := newConsumer(bucket.Name, bucket.BootstrapServers, bucket.Topics)
-		if err != nil {
-			log.Errorf("cannot create kafka consumer for %+v: %v", bucket, err)
-			continue
-		}
+	if offsets != nil {
+		ws.committer = newCommitter(offsets, interval)
+		ws.committer.start()
+	}
 
-		err = c.start(p.processChan)
+	for _, bucket := range buckets {
+		c, err := ws.startConsumer(bucket)
 		if err != nil {
-			log.Errorf("cannot start kafka consumer for %+v: %v", bucket, err)
+			log.Errorf("cannot start kafka consumer for %v: %v", bucket.Name, err)
 			continue
 		}
 
@@ -47,18 +65,43 @@ func NewWatchableStore(dec decoder.Decoder, buckets ...Bucket) (*WatchableStore,
 	return &ws, nil
 }
 
-func (ws WatchableStore) Close() {
+// Close stops the consumers and waits for the events of their messages to be sent to the watcher,
+// then the watch channel is closed and the offsets are committed once the events are acknowledged
+// (or the context is done)
+func (ws *WatchableStore) Close() error {
 	defer log.Warnf("watchable kafka store closed")
 
 	log.Warnf("closing watchable kafka store ...")
-	for _, c := range ws.consumers {
-		c.close()
+	err := closeConsumers(ws.consumers)
+	ws.processor.close()
+
+	if ws.committer != nil {
+		ws.committer.wait(ws.ctx)
+		ws.committer.close()
 	}
+	ws.cancel()
+
+	return err
+}
 
-	// wait for consumer Poll timeout, otherwise we will get "panic: send on closed channel"
-	time.Sleep(1 * time.Second)
+// startConsumer creates and starts the consumer of the bucket
+func (ws *WatchableStore) startConsumer(bucket Bucket) (consumer, error) {
+	c, err := newConsumer(bucket.Name, bucket.BootstrapServers, bucket.Topics, bucket.Properties)
+	if err != nil {
+		return consumer{}, err
+	}
+	c.committer = ws.committer
+	c.tracked = ws.committer.track(bucket)
+	c.progress = ws.loading.add(bucket.Name)
+
+	err = c.start(ws.ctx, ws.processChan)
+	if err != nil {
+		ws.committer.untrack(bucket.Name)
+		ws.loading.remove(bucket.Name)
+		return consumer{}, err
+	}
 
-	ws.processor.close()
+	return c, nil
 }
 
 func (ws *WatchableStore) AddBuckets(buckets ...Bucket) {
@@ -67,75 +110,146 @@ func (ws *WatchableStore) AddBuckets(buckets ...Bucket) {
 
 		// consumer config changed
 		if found && (c.bootstrapServers != bucket.BootstrapServers || !reflect.DeepEqual(c.topics, bucket.Topics)) {
-			// so sending reset event
-			ws.processedChan <- event{
-				storeResetType,
-				bucket.Name,
-				nil,
-			}
-			// closing current consumer
-			c.close()
+			// closing current consumer and sending reset event
+			ws.reset(c)
 		} else if found {
 			// nothing changed
 			continue
 		}
-		fmt.Printf("setting new consumer %v\n", bucket)
+		log.Printf("setting new consumer %v with the properties %v", bucket.Name, bucket.Properties.Keys())
 		// starting new consumer
-		c, err := newConsumer(bucket.Name, bucket.BootstrapServers, bucket.Topics)
+		c, err := ws.startConsumer(bucket)
 		if err != nil {
-			log.Errorf("cannot create kafka consumer for %+v: %v", bucket, err)
+			log.Errorf("cannot start kafka consumer for %v: %v", bucket.Name, err)
 			continue
 		}
 
-		err = c.start(ws.processChan)
-		if err != nil {
-			log.Errorf("cannot start kafka consumer for %+v: %v", bucket, err)
+		ws.consumers[bucket.Name] = c
+	}
+}
+
+// RemoveBuckets stops the consumers of the buckets and sends a reset event for each of them,
+// so the schedules of the removed buckets are purged
+func (ws *WatchableStore) RemoveBuckets(names ...string) {
+	for _, name := range names {
+		c, found := ws.consumers[name]
+		if !found {
 			continue
 		}
 
-		ws.consumers[bucket.Name] = c
+		log.Printf("removing consumer %v", name)
+		ws.reset(c)
+		delete(ws.consumers, name)
 	}
 }
 
-func (ws WatchableStore) Watch() (chan store.Event, error) {
+// reset closes the consumer, removes its offsets and sends a reset event for its bucket, the event is sent after
+// the last messages of the consumer, through the processor
+func (ws *WatchableStore) reset(c consumer) {
+	helper.LogErr(c.close())
+	ws.committer.untrack(c.name)
+	ws.loading.remove(c.name)
+	select {
+	case ws.processChan <- event{
+		storeResetType,
+		c.name,
+		nil,
+		nil,
+		nil,
+	}:
+	case <-ws.ctx.Done():
+	}
+}
+
+// Buckets returns the names of the buckets with a consumer
+func (ws *WatchableStore) Buckets() []string {
+	result := make([]string, 0, len(ws.consumers))
+	for name := range ws.consumers {
+		result = append(result, name)
+	}
+	return result
+}
+
+// Ready returns true when the messages of each bucket up to the high watermarks captured when its consumer
+// started were sent to the watcher
+func (ws WatchableStore) Ready() bool {
+	return ws.loading.ready()
+}
+
+// Progress returns the loading progress of the bucket, false when the bucket has no consumer
+func (ws WatchableStore) Progress(name string) (Progress, bool) {
+	return ws.loading.progress(name)
+}
+
+// Watch returns the events of the messages consumed by the store, the messages are decoded by a pool
+// of workers, the events of a schedule are in the order of its messages. The channel is closed when the store
+// is closed, after the events of the last messages.
+func (ws *WatchableStore) Watch() (chan store.Event, error) {
 	resultChan := make(chan store.Event, ChanSize)
 
-	go func() {
-		for e := range ws.processedChan {
+	ws.processor.watch(func(events chan event) {
+		workers := newPool(DecoderWorkers)
+		defer close(resultChan)
+		defer workers.close()
+
+		for e := range events {
+			e := e
 			switch e.evtType {
 			case messageType:
-				evtType := store.UpsertType
-				if len(e.Value) == 0 {
-					evtType = store.DeletedType
-				}
-
-				var sch schedule.Schedule = &kafka.Schedule{
-					Message: e.Message,
-				}
-
-				if ws.dec != nil {
-					sdec, err := ws.dec.Decode(sch)
-					if err != nil {
-						log.Warnf("cannot decode kafka schedule %v: %v", sch.ID(), err)
-					} else {
-						sch = sdec
-					}
-				}
-
-				resultChan <- store.Event{
-					EventType: evtType,
-					Schedule: store.Schedule{
-						SchedulerName: e.name,
-						Schedule:      sch,
-					},
-				}
+				ws.committer.received(e.tracked, e.Message)
+				workers.submit(e.name+"|"+string(e.Key), func() {
+					evt := ws.toEvent(e)
+					// the offset is committed once the watcher persisted the event
+					evt.Ack = ws.committer.ack(e.tracked, e.Message)
+					ws.deliver(resultChan, evt)
+				})
 			case storeResetType:
-				resultChan <- store.Event{
-					EventType: store.StoreResetType,
-				}
+				// the schedules of the bucket are purged after its last messages
+				workers.barrier(func() {
+					ws.deliver(resultChan, store.Event{
+						EventType: store.StoreResetType,
+						Schedule: store.Schedule{
+							SchedulerName: e.name,
+						},
+					})
+				})
+			case loadedType:
+				// the bucket is ready after the events of its messages
+				workers.barrier(e.progress.setReady)
 			}
 		}
-	}()
+	})
 
 	return resultChan, nil
 }
+
+// toEvent returns the event of the message, the schedule is decoded when the store has a decoder,
+// a schedule which cannot be decoded keeps its raw value and the error of the decoder
+func (ws WatchableStore) toEvent(e event) store.Event {
+	evtType := store.UpsertType
+	if len(e.Value) == 0 {
+		evtType = store.DeletedType
+	}
+
+	var sch schedule.Schedule = &kafka.Schedule{
+		Message: e.Message,
+	}
+
+	if ws.dec != nil && evtType == store.UpsertType {
+		sdec, err := decoder.Decode(ws.dec, e.name, sch)
+		if err != nil {
+			log.Warnf("cannot decode kafka schedule %v: %v", sch.ID(), err)
+			sch = decoder.Failed{Schedule: sch, Err: err.Error()}
+		} else {
+			sch = sdec
+		}
+	}
+
+	return store.Event{
+		EventType: evtType,
+		Schedule: store.Schedule{
+			SchedulerName: e.name,
+			Schedule:      sch,
+		},
+	}
+}
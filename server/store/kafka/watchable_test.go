@@ -2,7 +2,9 @@
 package kafka_test
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -24,8 +26,8 @@ func TestKafkaWatchableStore_Watch(t *testing.T) {
 	}
 
 	buckets := []kafka.Bucket{
-		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}},
-		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}},
+		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil},
+		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}, nil},
 	}
 	kstore, err := kafka.NewWatchableStore(nil, buckets...)
 	if err != nil {
@@ -116,10 +118,10 @@ func TestKafkaWatchableStore_AddBuckets(t *testing.T) {
 	}
 
 	// buckets to add
-	bucket1 := kafka.Bucket{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}}
-	bucket2 := kafka.Bucket{"scheduler-2", "unknown:9092", []string{topics[1]}}
+	bucket1 := kafka.Bucket{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil}
+	bucket2 := kafka.Bucket{"scheduler-2", "unknown:9092", []string{topics[1]}, nil}
 	// fix the bucket config
-	bucket2Fix := kafka.Bucket{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}}
+	bucket2Fix := kafka.Bucket{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}, nil}
 
 	kstore, err := kafka.NewWatchableStore(nil)
 	if err != nil {
@@ -221,8 +223,8 @@ func TestKafkaWatchableStore_Decoder(t *testing.T) {
 	}
 
 	buckets := []kafka.Bucket{
-		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}},
-		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}},
+		{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil},
+		{"scheduler-2", helper.GetDefaultBootstrapServers(), []string{topics[1]}, nil},
 	}
 
 	dec := &helper.KafkaMessageSimpleDecoder{}
@@ -304,3 +306,292 @@ loop:
 		t.Errorf("unexpected decoder count: %v", dec.Called)
 	}
 }
+
+// Rule #4: the store should be ready once the messages up to the initial high watermarks were sent to the watcher
+func TestKafkaWatchableStore_Ready(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	now := time.Now()
+
+	topics, err := helper.CreateTopics(1, []int{2}, "schedules")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	msgs := make([]*confluent.Message, 10)
+	for i := 0; i < 10; i++ {
+		msgs[i] = helper.Message(topics[0], fmt.Sprintf("schedule-%v", i), "value", now.Add(1*time.Hour).Unix())
+	}
+	helper.ProduceMessages(msgs)
+
+	err = helper.AssertMessagesinTopic(topics[0], msgs)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	kstore, err := kafka.NewWatchableStore(nil, kafka.Bucket{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil})
+	if err != nil {
+		t.Errorf("failed to create kafka store: %v\n", err)
+	}
+	defer kstore.Close()
+
+	if kstore.Ready() {
+		t.Errorf("unexpected ready store before watch")
+	}
+
+	lst, err := kstore.Watch()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	total := 0
+	for total < 10 {
+		select {
+		case <-lst:
+			total++
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout, total=%v", total)
+		}
+	}
+
+	time.Sleep(1 * time.Second)
+
+	if !kstore.Ready() {
+		t.Errorf("unexpected store not ready")
+	}
+	p, found := kstore.Progress("scheduler-1")
+	if !found || !p.Ready || p.Consumed != 10 || p.Total != 10 {
+		t.Errorf("unexpected progress: %+v %v", p, found)
+	}
+}
+
+// Rule #5: the store should resume from the committed offsets, after a reset event the first time
+func TestKafkaWatchableStore_resume(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	now := time.Now()
+
+	topics, err := helper.CreateTopics(1, []int{1}, "schedules")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	offsets, err := kafka.NewOffsetStore(filepath.Join(t.TempDir(), "schedules.offsets"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer offsets.Close()
+
+	bucket := kafka.Bucket{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil}
+
+	produce := func(from, to int) {
+		msgs := []*confluent.Message{}
+		for i := from; i < to; i++ {
+			msgs = append(msgs, helper.Message(topics[0], fmt.Sprintf("schedule-%v", i), "value", now.Add(1*time.Hour).Unix()))
+		}
+		helper.ProduceMessages(msgs)
+	}
+
+	consume := func() (upsert, reset int) {
+		kstore, err := kafka.NewResumableWatchableStore(context.Background(), nil, offsets, 100*time.Millisecond, bucket)
+		if err != nil {
+			t.Fatalf("failed to create kafka store: %v\n", err)
+		}
+		defer kstore.Close()
+
+		lst, err := kstore.Watch()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for {
+			select {
+			case evt := <-lst:
+				evt.Acknowledge()
+				if evt.EventType == store.UpsertType {
+					upsert++
+				}
+				if evt.EventType == store.StoreResetType {
+					reset++
+				}
+			case <-time.After(5 * time.Second):
+				return upsert, reset
+			}
+		}
+	}
+
+	produce(0, 10)
+	if upsert, reset := consume(); upsert != 10 || reset != 1 {
+		t.Errorf("unexpected first consumption: upsert=%v reset=%v", upsert, reset)
+	}
+
+	produce(10, 15)
+	if upsert, reset := consume(); upsert != 5 || reset != 0 {
+		t.Errorf("unexpected resumed consumption: upsert=%v reset=%v", upsert, reset)
+	}
+}
+
+// Rule #6: closing the store while consuming should deliver the events of the consumed messages,
+// and the messages not delivered should be consumed again after a restart
+func TestKafkaWatchableStore_Close(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	now := time.Now()
+	count := 5000
+
+	topics, err := helper.CreateTopics(1, []int{3}, "schedules")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	offsets, err := kafka.NewOffsetStore(filepath.Join(t.TempDir(), "schedules.offsets"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer offsets.Close()
+
+	bucket := kafka.Bucket{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil}
+
+	msgs := []*confluent.Message{}
+	for i := 0; i < count; i++ {
+		msgs = append(msgs, helper.Message(topics[0], fmt.Sprintf("schedule-%v", i), "value", now.Add(1*time.Hour).Unix()))
+	}
+	helper.ProduceMessages(msgs)
+
+	received := make(map[string]bool)
+
+	// the store is closed after the first events, the channel is closed once the events are delivered
+	kstore, err := kafka.NewResumableWatchableStore(context.Background(), nil, offsets, 100*time.Millisecond, bucket)
+	if err != nil {
+		t.Fatalf("failed to create kafka store: %v\n", err)
+	}
+	lst, err := kstore.Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for len(received) < count/10 {
+		select {
+		case evt := <-lst:
+			evt.Acknowledge()
+			if evt.EventType == store.UpsertType {
+				received[evt.ID()] = true
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout waiting for the first events")
+		}
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- kstore.Close()
+	}()
+	for evt := range lst {
+		if evt.EventType == store.UpsertType {
+			received[evt.ID()] = true
+		}
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the restarted store resumes from the committed offsets
+	kstore, err = kafka.NewResumableWatchableStore(context.Background(), nil, offsets, 100*time.Millisecond, bucket)
+	if err != nil {
+		t.Fatalf("failed to create kafka store: %v\n", err)
+	}
+	defer kstore.Close()
+
+	lst, err = kstore.Watch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for len(received) < count {
+		select {
+		case evt := <-lst:
+			evt.Acknowledge()
+			if evt.EventType == store.UpsertType {
+				received[evt.ID()] = true
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("unexpected number of schedules received: %v", len(received))
+		}
+	}
+}
+
+// Rule #7: the offsets of the events not acknowledged by the watcher should not be committed
+func TestKafkaWatchableStore_acknowledge(t *testing.T) {
+	helper.VerifyIfSkipIntegrationTests(t)
+
+	now := time.Now()
+
+	topics, err := helper.CreateTopics(1, []int{1}, "schedules")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	offsets, err := kafka.NewOffsetStore(filepath.Join(t.TempDir(), "schedules.offsets"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer offsets.Close()
+
+	bucket := kafka.Bucket{"scheduler-1", helper.GetDefaultBootstrapServers(), []string{topics[0]}, nil}
+
+	msgs := []*confluent.Message{}
+	for i := 0; i < 10; i++ {
+		msgs = append(msgs, helper.Message(topics[0], fmt.Sprintf("schedule-%v", i), "value", now.Add(1*time.Hour).Unix()))
+	}
+	helper.ProduceMessages(msgs)
+
+	consume := func(ack bool) (upsert int) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		kstore, err := kafka.NewResumableWatchableStore(ctx, nil, offsets, 100*time.Millisecond, bucket)
+		if err != nil {
+			t.Fatalf("failed to create kafka store: %v\n", err)
+		}
+		defer kstore.Close()
+		if !ack {
+			// the close does not wait for the acknowledgements once the context is done
+			defer cancel()
+		}
+
+		lst, err := kstore.Watch()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for {
+			select {
+			case evt := <-lst:
+				if ack {
+					evt.Acknowledge()
+				}
+				if evt.EventType == store.UpsertType {
+					upsert++
+				}
+			case <-time.After(5 * time.Second):
+				return upsert
+			}
+		}
+	}
+
+	tests := []struct {
+		ack      bool
+		expected int
+	}{
+		{false, 10},
+		// consumed again, the events were not acknowledged
+		{true, 10},
+		{true, 0},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case #%v", i+1), func(t *testing.T) {
+			if upsert := consume(tt.ack); upsert != tt.expected {
+				t.Errorf("unexpected number of upserts: %v", upsert)
+			}
+		})
+	}
+}
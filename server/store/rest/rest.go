@@ -12,6 +12,7 @@ import (
 
 	"github.com/etf1/kafka-message-scheduler-admin/server/decoder"
 	"github.com/etf1/kafka-message-scheduler-admin/server/helper"
+	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers"
 	"github.com/etf1/kafka-message-scheduler-admin/server/resolver/schedulers/httpresolver"
 	"github.com/etf1/kafka-message-scheduler-admin/server/store"
 	"github.com/etf1/kafka-message-scheduler/schedule"
@@ -45,11 +46,11 @@ func (s Schedule) String() string {
 }
 
 type HTTPRetriever struct {
-	httpresolver.Resolver
+	schedulers.Resolver
 	dec decoder.Decoder
 }
 
-func NewStore(r httpresolver.Resolver, dec decoder.Decoder) *HTTPRetriever {
+func NewStore(r schedulers.Resolver, dec decoder.Decoder) *HTTPRetriever {
 	return &HTTPRetriever{
 		Resolver: r,
 		dec:      dec,
@@ -141,9 +142,10 @@ func (h HTTPRetriever) getSchedules(schedulerName string, filter func(s schedule
 						var sch schedule.Schedule = s
 
 						if h.dec != nil {
-							sdec, err := h.dec.Decode(s)
+							sdec, err := decoder.Decode(h.dec, schedulerName, s)
 							if err != nil {
-								log.Warnf("cannot decode rest schedule %v: %v", err, s.ID())
+								log.Warnf("cannot decode rest schedule %v: %v", s.ID(), err)
+								sch = decoder.Failed{Schedule: s, Err: err.Error()}
 							} else {
 								sch = sdec
 							}
@@ -24,15 +24,38 @@ const (
 	StoreResetType
 )
 
+func (e EventType) String() string {
+	switch e {
+	case UpsertType:
+		return "upsert"
+	case DeletedType:
+		return "delete"
+	case StoreResetType:
+		return "reset"
+	default:
+		return "unknown"
+	}
+}
+
 type Event struct {
 	EventType
 	Schedule
+	// Ack acknowledges the event once persisted by the watcher, nil when the source does not need it
+	Ack func() `json:"-"`
+}
+
+// Acknowledge calls the acknowledgement of the event, if any
+func (e Event) Acknowledge() {
+	if e.Ack != nil {
+		e.Ack()
+	}
 }
 
 type Watchable interface {
 	Watch() (chan Event, error)
 }
 
+// Batchable stores the events by batches, the events are acknowledged once persisted
 type Batchable interface {
 	Batch(events chan Event) chan error
 }